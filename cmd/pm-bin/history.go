@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"pm/internal/cache"
+	"pm/internal/config"
+)
+
+// record adds an invocation to the history; ref resolves the project
+// again from any directory, projects are the ones a selection ran in. The
+// wrapper reports the script's status later from the same process,
+// pm-bin's parent.
+func record(project, ref string, args []string, projects ...string) {
+	cwd, _ := os.Getwd()
+	_ = config.RecordHistory(config.HistoryEntry{
+		Project:  project,
+		Ref:      ref,
+		Args:     args,
+		Projects: projects,
+		Cwd:      cwd,
+		Shell:    os.Getppid(),
+	})
+}
+
+// again implements `pm again [N]` and `pm !!`: the arguments of the Nth
+// latest invocation, announced on stderr.
+func again(args []string) []string {
+	n := 1
+	if len(args) > 0 {
+		var err error
+		if n, err = strconv.Atoi(args[0]); err != nil {
+			fail("pm again [N]")
+		}
+	}
+	h, err := config.LoadHistory()
+	if err != nil {
+		fail(err.Error())
+	}
+	e, err := h.Back(n)
+	if err != nil {
+		fail(err.Error())
+	}
+	fmt.Fprintf(os.Stderr, "# pm: again: %s\n", e)
+	return e.Command()
+}
+
+// showHistory implements `pm history [PROJECT] [-n N] [--json]`: the
+// latest invocations, oldest first, numbered for `pm again N`.
+func showHistory(args []string) {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	n := fs.Int("n", 20, "number of entries")
+	asJSON := fs.Bool("json", false, "JSON output")
+	refs := parseInterspersed(fs, args)
+	h, err := config.LoadHistory()
+	if err != nil {
+		fail(err.Error())
+	}
+	type numbered struct {
+		N int `json:"n"`
+		config.HistoryEntry
+	}
+	var out []numbered
+	for i, e := range slices.Backward(h) {
+		if len(out) == *n {
+			break
+		}
+		if len(refs) == 0 || e.Project == refs[0] {
+			out = append(out, numbered{len(h) - i, e})
+		}
+	}
+	slices.Reverse(out)
+	if *asJSON {
+		b, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			fail(err.Error())
+		}
+		fmt.Println(string(b))
+		return
+	}
+	for _, e := range out {
+		fmt.Printf("%4d  %s  %-6s  %s\n", e.N, e.Time.Local().Format("2006-01-02 15:04"), status(e.Status), e)
+	}
+}
+
+func status(s *int) string {
+	switch {
+	case s == nil:
+		return "-"
+	case *s == 0:
+		return "ok"
+	}
+	return "exit " + strconv.Itoa(*s)
+}
+
+// showRecent implements `pm recent [PROJECT] [-n N] [--json]`: distinct
+// invocations, latest first.
+func showRecent(args []string) {
+	fs := flag.NewFlagSet("recent", flag.ExitOnError)
+	n := fs.Int("n", 10, "number of entries")
+	asJSON := fs.Bool("json", false, "JSON output")
+	refs := parseInterspersed(fs, args)
+	h, err := config.LoadHistory()
+	if err != nil {
+		fail(err.Error())
+	}
+	project := ""
+	if len(refs) > 0 {
+		project = refs[0]
+	}
+	recent := h.Recent(project, *n)
+	if *asJSON {
+		b, err := json.MarshalIndent(recent, "", "  ")
+		if err != nil {
+			fail(err.Error())
+		}
+		fmt.Println(string(b))
+		return
+	}
+	for _, e := range recent {
+		fmt.Println(e)
+	}
+}
+
+// reportStatus implements `pm __status CODE`, which the wrapper calls with
+// the exit status of the script it ran.
+func reportStatus(args []string) {
+	if len(args) != 1 {
+		return
+	}
+	if code, err := strconv.Atoi(strings.TrimSpace(args[0])); err == nil {
+		_ = config.SetHistoryStatus(os.Getppid(), code)
+		// the script exported how many steps with stamps finished
+		done, _ := strconv.Atoi(os.Getenv(cache.DoneVar))
+		_ = cache.Commit(os.Getppid(), code, done)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"pm/internal/config"
+	"pm/internal/importer"
+	"pm/internal/scaffold"
+)
+
+// initProject implements `pm init [--type T,...] [--import] [--add] [--force] [DIR]`:
+// it detects the project type, writes DIR/.pm.meta.yml from the matching
+// templates (~/.config/pm/templates/<type>.yml overrides the built-ins) and,
+// with --import, adds the repository's existing task definitions.
+func initProject(args []string) {
+	fs := flag.NewFlagSet("init", flag.ExitOnError)
+	types := fs.String("type", "", "comma-separated project types instead of detection")
+	doImport := fs.Bool("import", false, "seed commands from Makefile, justfile, Taskfile, package.json, gradle and compose files")
+	add := fs.Bool("add", false, "register the project right away (pm add)")
+	force := fs.Bool("force", false, "overwrite an existing .pm.meta.yml")
+	_ = fs.Parse(args)
+
+	dir := "."
+	if fs.NArg() > 0 {
+		dir = fs.Arg(0)
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		fail(err.Error())
+	}
+	metaPath := filepath.Join(dir, ".pm.meta.yml")
+	if _, err := os.Stat(metaPath); err == nil && !*force {
+		fail(metaPath + " already exists (use --force to overwrite)")
+	}
+
+	var detected []scaffold.Type
+	if *types != "" {
+		for _, t := range strings.Split(*types, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				detected = append(detected, scaffold.Type(t))
+			}
+		}
+	} else {
+		detected = scaffold.Detect(dir)
+	}
+	meta, err := scaffold.Generate(dir, detected, filepath.Join(config.Home(), "templates"))
+	if err != nil {
+		fail(err.Error())
+	}
+
+	var sources []string
+	if *doImport {
+		res, err := importer.Import(dir)
+		if err != nil {
+			fail(err.Error())
+		}
+		sources = res.Sources
+		// the repository's own tasks win over generic templates
+		for name, c := range res.Meta.Commands {
+			meta.Commands[name] = c
+		}
+		if res.Meta.Docker.ComposeFile != "" {
+			meta.Docker = res.Meta.Docker
+		}
+	}
+	if err := config.WriteProjectMeta(metaPath, meta); err != nil {
+		fail(err.Error())
+	}
+
+	fmt.Printf("# pm: wrote %s\n", metaPath)
+	if len(detected) > 0 {
+		names := make([]string, len(detected))
+		for i, t := range detected {
+			names[i] = string(t)
+		}
+		fmt.Printf("# pm: project type: %s\n", strings.Join(names, ", "))
+	}
+	if len(sources) > 0 {
+		fmt.Printf("# pm: imported commands from %s\n", strings.Join(sources, ", "))
+	}
+	if *add {
+		if err := config.RegAdd(metaPath); err != nil {
+			fail(err.Error())
+		}
+		fmt.Printf("# pm: added project %s\n", meta.Info.Name)
+		return
+	}
+	fmt.Printf("# pm: register it with: pm add %s\n", metaPath)
+}
@@ -1,17 +1,26 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
+	"pm/internal/builder"
+	"pm/internal/cache"
+	"pm/internal/complete"
 	"pm/internal/config"
-	"pm/internal/dsl"
+	"pm/internal/export"
 	"pm/internal/plan"
+	"pm/internal/plugin"
 	"pm/internal/render"
-	"pm/internal/templ"
+	"pm/internal/runner"
+	"pm/internal/tui"
 )
 
 func main() {
@@ -19,35 +28,144 @@ func main() {
 		dialect  string
 		plugins  string
 		showHelp bool
+		pick     bool
+		force    bool
+		watching bool
+		clearScr bool
 	)
-	flag.StringVar(&dialect, "dialect", "", "script dialect to render (bash|pwsh|<external-plugin>) [env PM_DIALECT]")
+	flag.StringVar(&dialect, "dialect", "", "script dialect to render (bash|zsh|fish|nu|sh|pwsh|cmd|<external-plugin>) [env PM_DIALECT]")
 	flag.StringVar(&plugins, "plugins", "", "plugins dir (defaults to ~/.config/pm/plugins) [env PM_PLUGIN_DIR]")
 	flag.BoolVar(&showHelp, "h", false, "show help")
+	flag.BoolVar(&pick, "i", false, "pick a project and command interactively (default without args on a terminal)")
+	flag.BoolVar(&force, "force", false, "run commands even when their inputs: are unchanged")
+	flag.BoolVar(&watching, "watch", false, "run the commands again whenever their files change, until Ctrl-C (also :command --watch)")
+	flag.BoolVar(&clearScr, "clear", false, "clear the screen before every run in watch mode")
 	flag.Parse()
 
+	if v := os.Getenv("PM_PLUGIN_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			plugin.Timeout = d
+		}
+	}
+
 	args := flag.Args()
+	// pm with no args on a terminal opens the picker, pm -i always does
+	if pick || (len(args) == 0 && !showHelp && isTerminal(os.Stdin) && !isWindows()) {
+		picked, err := pickCommand(dialect, plugins)
+		switch {
+		case err != nil && pick:
+			fail(err.Error())
+		case err == nil && picked == nil:
+			return
+		}
+		// without a usable terminal pm falls back to the usage
+		args = picked
+	}
 	if showHelp || len(args) == 0 {
 		fmt.Print(`# pm: usage
-#   pm-bin [--dialect bash|pwsh|<plugin>] [--plugins DIR] <add|rm|ls|PROJECT|META.yml> [args...]
+#   pm-bin [--dialect bash|zsh|fish|nu|sh|pwsh|cmd|<plugin>] [--plugins DIR] [-i] <add|rm|ls|show|which|export|set|init|scan|prune|workspaces|registry repair|plugins ls|completion SHELL|history|recent|again [N]|run|ws:NAME|PROJECT|@TAG|A,B|GLOB|META.yml|DIR|.> [args...]
 # examples:
 #   pm-bin add ~/repos/subzero/.pm.meta.yml
-#   pm-bin ls
+#   pm-bin -i                   # pick a project and command in a terminal UI
+#   pm-bin history [PROJECT] [-n N] [--json]
+#   pm-bin recent [PROJECT]     # distinct invocations, latest first
+#   pm-bin again [N]            # run the latest (or Nth latest) invocation again; also !!
+#   pm-bin run [--log FILE] subzero :build :test  # execute here, without the wrapper
+#   pm-bin --force subzero :build  # run even if its inputs: are unchanged
+#   pm-bin [--clear] subzero :gen :test --watch  # run again on every change, until Ctrl-C
+#   pm-bin ls --tag backend --recent --format table|json|yaml
+#   pm-bin ls --json
+#   pm-bin completion bash|zsh|fish|pwsh
+#   pm-bin show subzero --json  # commands, functions and docker groups
+#   pm-bin which subzero        # root directory: cd "$(pm which subzero)"
+#   pm-bin set subzero pinned=true env.SPRING_PROFILES_ACTIVE=dev
+#   pm-bin init --import ~/repos/subzero
+#   pm-bin scan ~/repos --depth 3
 #   pm-bin --dialect bash subzero :build -DskipTests :up @base api
+#   pm-bin @backend :test       # every project tagged backend
+#   pm-bin api,worker :build
+#   pm-bin '*' git pull
+#   pm-bin ws:platform :up      # every project of a workspace, in dependency order
+#   pm-bin . :build             # project of the current directory
+#   pm-bin :build :test         # same, implicit
+#   pm-bin export subzero --to make|just|taskfile [-o FILE] [--force]
 `)
 		return
 	}
 
+	// pm again [N], pm !!: run an earlier invocation again
+	if args[0] == "again" || args[0] == "!!" {
+		args = again(args[1:])
+	}
+
+	// pm run PROJECT :build: pm-bin executes the plan itself
+	emit := func(pl *plan.Plan, _ string) { renderAndPrint(pl, dialect, plugins) }
+	direct := args[0] == "run"
+	if direct {
+		args, emit = runArgs(args[1:], &force, &dialect)
+	}
+
 	// top-level commands that should print info (no script!)
 	switch args[0] {
+	case "__status":
+		reportStatus(args[1:])
+		return
+	case "history":
+		showHistory(args[1:])
+		return
+	case "recent":
+		showRecent(args[1:])
+		return
+	case "__complete":
+		for _, c := range complete.Complete(args[1:], resolvePluginsDir(plugins)) {
+			fmt.Printf("%s\t%s\n", c.Value, c.Description)
+		}
+		return
+	case "completion":
+		if len(args) < 2 {
+			fail("pm completion " + strings.Join(complete.Shells, "|"))
+		}
+		script, err := complete.Script(args[1])
+		if err != nil {
+			fail(err.Error())
+		}
+		fmt.Print(script)
+		return
 	case "add":
+		force := len(args) > 2 && args[1] == "--force"
+		if force {
+			args = args[1:]
+		}
 		if len(args) < 2 {
-			fail("pm add /path/to/.pm.meta.yml")
+			fail("pm add [--force] /path/to/.pm.meta.yml")
+		}
+		add := config.RegAdd
+		if force {
+			add = config.RegAddForce
 		}
-		if err := config.RegAdd(args[1]); err != nil {
+		if err := add(args[1]); err != nil {
 			fail(err.Error())
 		}
 		fmt.Printf("# pm: added project\n")
 		return
+	case "scan":
+		scanProjects(args[1:])
+		return
+	case "prune":
+		dryRun := len(args) > 1 && args[1] == "--dry-run"
+		removed, err := config.RegPrune(dryRun)
+		if err != nil {
+			fail(err.Error())
+		}
+		verb := "removed"
+		if dryRun {
+			verb = "would remove"
+		}
+		for _, p := range removed {
+			fmt.Printf("# pm: %s %s (missing %s)\n", verb, p.Name, p.Meta)
+		}
+		fmt.Printf("# pm: pruned %d project(s)\n", len(removed))
+		return
 	case "rm":
 		if len(args) < 2 {
 			fail("pm rm <name>")
@@ -58,98 +176,525 @@ func main() {
 		fmt.Printf("# pm: removed project\n")
 		return
 	case "ls":
-		if err := config.RegLs(); err != nil {
+		listProjects(args[1:])
+		return
+	case "show":
+		showProject(args[1:], resolvePluginsDir(plugins))
+		return
+	case "which":
+		whichProject(args[1:])
+		return
+	case "export":
+		exportProject(args[1:], resolvePluginsDir(plugins))
+		return
+	case "set":
+		if len(args) < 3 {
+			fail("pm set PROJECT pinned=true|false dialect=NAME env.NAME=VALUE ...")
+		}
+		if err := config.RegSet(args[1], args[2:]); err != nil {
 			fail(err.Error())
 		}
+		fmt.Printf("# pm: updated %s\n", args[1])
+		return
+	case "init":
+		initProject(args[1:])
+		return
+	case "workspaces":
+		workspacesLs()
+		return
+	case "registry":
+		if len(args) < 2 || args[1] != "repair" {
+			fail("pm registry repair")
+		}
+		registryRepair()
+		return
+	case "plugins":
+		if len(args) < 2 || args[1] != "ls" {
+			fail("pm plugins ls")
+		}
+		pluginsLs(resolvePluginsDir(plugins))
+		return
+	}
+
+	if _, w := watchFlag(args[1:]); (watching || w) && (config.IsSelection(args[0]) || strings.HasPrefix(args[0], config.WorkspacePrefix)) {
+		fail("--watch runs the commands of a single project")
+	}
+
+	// pm ws:platform :up
+	if name, ok := strings.CutPrefix(args[0], config.WorkspacePrefix); ok {
+		record(args[0], args[0], args[1:])
+		emit(workspace(name, args[1:], resolvePluginsDir(plugins)), args[0])
 		return
 	}
 
-	// else: build plan for project or meta.yml path
-	projectRef := args[0]
-	tail := args[1:]
+	// pm @backend :test, pm api,worker :build, pm '*' git pull
+	if config.IsSelection(args[0]) {
+		pl, used := fanOut(args[0], args[1:], resolvePluginsDir(plugins))
+		record(args[0], args[0], args[1:], used...)
+		emit(pl, args[0])
+		return
+	}
 
-	meta, root, err := config.ResolveProject(projectRef)
+	// else: build plan for project, meta.yml path or directory;
+	// `pm :build` resolves the project from the working directory
+	var (
+		meta *config.ProjectMeta
+		root string
+		tail []string
+		err  error
+	)
+	if strings.HasPrefix(args[0], ":") {
+		meta, root, err = config.ResolveCwd()
+		tail = args
+	} else {
+		meta, root, err = config.ResolveProject(args[0])
+		tail = args[1:]
+	}
 	if err != nil {
 		fail(err.Error())
 	}
+	entry, registered := config.Entry(meta.Info.Name)
+	if dialect == "" && os.Getenv("PM_DIALECT") == "" {
+		dialect = entry.Dialect
+	}
 
-	globalCfg, _ := config.LoadGlobal()
+	b := &builder.Builder{
+		Meta:       meta,
+		Root:       root,
+		Global:     config.ProjectGlobal(meta.Info.Name),
+		PluginsDir: resolvePluginsDir(plugins),
+		Env:        entry.Env,
+	}
 
-	chunks := dsl.SplitColonCommands(tail)
-	pl := plan.New()
-	pl.Pushd(root)
+	ref := root
+	if registered {
+		ref = entry.Name
+	}
+	record(meta.Info.Name, ref, tail)
 
-	// raw mode
-	if len(chunks) == 1 && chunks[0].Name == "__RAW__" {
-		line := strings.Join(chunks[0].Args, " ")
-		if strings.TrimSpace(line) != "" {
-			pl.Run(line)
+	// pm PROJECT :test --watch; watch: only narrows what is watched
+	tail, w := watchFlag(tail)
+	if watching || w {
+		// the wrapper's dialect is for its eval; the runner takes the
+		// project's if it can run it
+		d := entry.Dialect
+		if direct {
+			d = runDialect(dialect, meta.Info.Name)
+		} else if !slices.Contains(runner.Dialects, d) {
+			d = ""
 		}
-		renderAndPrint(pl, dialect, plugins)
+		watchProject(b, tail, b.Watch(tail), clearScr, direct, d)
+	}
+
+	b.Cache = &cache.Cache{Force: force}
+	pl := b.Build(tail)
+	// the inputs' hashes are stored once the script reports success
+	if err := b.Cache.SavePending(os.Getppid()); err != nil {
+		fmt.Fprintf(os.Stderr, "# pm: cache: %v\n", err)
+	}
+	emit(pl, meta.Info.Name)
+}
+
+// exportProject implements `pm export PROJECT --to make|just|taskfile`: the
+// project's commands as a Makefile, justfile or Taskfile.yml in the
+// project root, or -o FILE ("-" for stdout).
+func exportProject(args []string, pluginsDir string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	to := fs.String("to", "", "target format: "+strings.Join(export.Formats, "|"))
+	out := fs.String("o", "", "output file (default: conventional name in the project root)")
+	force := fs.Bool("force", false, "overwrite an existing file")
+	refs := parseInterspersed(fs, args)
+	if len(refs) != 1 || *to == "" {
+		fail(fmt.Sprintf("pm export PROJECT --to %s [-o FILE] [--force]", strings.Join(export.Formats, "|")))
+	}
+	meta, root, err := config.ResolveProject(refs[0])
+	if err != nil {
+		fail(err.Error())
+	}
+	b := &builder.Builder{Meta: meta, Root: root, Global: config.ProjectGlobal(meta.Info.Name), PluginsDir: pluginsDir}
+	content, err := export.Generate(b, *to)
+	if err != nil {
+		fail(err.Error())
+	}
+	if *out == "-" {
+		fmt.Print(content)
 		return
 	}
+	path := *out
+	if path == "" {
+		path = filepath.Join(root, export.FileName(*to))
+	}
+	if _, err := os.Stat(path); err == nil && !*force {
+		fail(fmt.Sprintf("%s already exists (use --force to overwrite)", path))
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		fail(err.Error())
+	}
+	fmt.Printf("# pm: exported to %s\n", path)
+}
 
-	// named :commands
-	for _, ch := range chunks {
-		if ch.Name == "help" {
-			pl.Echo("# pm: project commands:")
-			for k, v := range meta.Commands {
-				desc := strings.TrimSpace(v.Description)
-				if desc == "" {
-					desc = "-"
-				}
-				pl.Echo(fmt.Sprintf("  :%s  - %s", k, desc))
-			}
-			pl.Echo("# pm: built-ins: :up (docker compose up -d ...)")
-			continue
+// resolveDialect falls back to PM_DIALECT, then to the OS default.
+func resolveDialect(dialect string) string {
+	if dialect != "" {
+		return dialect
+	}
+	if v := os.Getenv("PM_DIALECT"); v != "" {
+		return v
+	}
+	if isWindows() {
+		return "pwsh"
+	}
+	return "bash"
+}
+
+func renderAndPrint(pl *plan.Plan, dialect, plugins string) {
+	s, err := render.Render(pl, resolveDialect(dialect), resolvePluginsDir(plugins))
+	if err != nil {
+		fail(err.Error())
+	}
+	fmt.Print(s)
+}
+
+// pickCommand runs the interactive picker over the registry and returns
+// the chosen arguments, nil when cancelled.
+func pickCommand(dialect, plugins string) ([]string, error) {
+	projects, err := config.ListProjects(config.LsOptions{})
+	if err != nil {
+		return nil, err
+	}
+	pluginsDir := resolvePluginsDir(plugins)
+	load := func(p config.RegProject) (*builder.Builder, error) {
+		meta, root, err := config.LoadEntry(p)
+		if err != nil {
+			return nil, err
 		}
-		// built-in :up
-		if ch.Name == "up" {
-			cmds := plan.DockerUp(meta, ch.Args)
-			for _, c := range cmds {
-				pl.Run(c)
+		return &builder.Builder{Meta: meta, Root: root, Global: config.ProjectGlobal(p.Name), PluginsDir: pluginsDir, Env: p.Env}, nil
+	}
+	previews := map[string]string{}
+	preview := func(args []string) string {
+		i := slices.IndexFunc(projects, func(p config.RegProject) bool { return p.Name == args[0] })
+		b, err := load(projects[i])
+		if err != nil {
+			return err.Error()
+		}
+		b.Preview = true
+		d := dialect
+		if d == "" && os.Getenv("PM_DIALECT") == "" {
+			d = projects[i].Dialect
+		}
+		s, err := render.Render(b.Build(args[1:]), resolveDialect(d), pluginsDir)
+		if err != nil {
+			return err.Error()
+		}
+		return s
+	}
+	picker := &tui.Picker{
+		Projects: projects,
+		Describe: func(p config.RegProject) (builder.Description, error) {
+			b, err := load(p)
+			if err != nil {
+				return builder.Description{}, err
+			}
+			return b.Describe(), nil
+		},
+		Preview: func(args []string) string {
+			// redrawn on every key: each script is built once, without
+			// running command plugins or hashing inputs
+			key := strings.Join(args, "\x00")
+			if s, ok := previews[key]; ok {
+				return s
 			}
+			previews[key] = preview(args)
+			return previews[key]
+		},
+	}
+	return picker.Run()
+}
+
+// isTerminal reports whether f is a character device other than the null
+// device, which is one too.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	null, err := os.Stat(os.DevNull)
+	return err != nil || !os.SameFile(fi, null)
+}
+
+// fanOut runs tail in every selected project and returns the plan and the
+// projects in it; projects whose meta cannot be loaded are reported and
+// left out.
+func fanOut(sel string, tail []string, pluginsDir string) (*plan.Plan, []string) {
+	projects, err := config.SelectProjects(sel)
+	if err != nil {
+		fail(err.Error())
+	}
+	pl := plan.New()
+	var (
+		bs   []*builder.Builder
+		used []string
+	)
+	for _, p := range projects {
+		meta, root, err := config.LoadEntry(p)
+		if err != nil {
+			pl.Echo(fmt.Sprintf("# pm: skipped %s: %v", p.Name, err))
 			continue
 		}
-		// user-defined
-		cmd, ok := meta.Commands[ch.Name]
-		if !ok {
-			pl.Echo(fmt.Sprintf("# pm: unknown command :%s", ch.Name))
+		// the same global as a run of the project on its own
+		bs = append(bs, &builder.Builder{Meta: meta, Root: root, Global: config.ProjectGlobal(p.Name), PluginsDir: pluginsDir, Env: p.Env})
+		used = append(used, p.Name)
+	}
+	pl.Ops = append(pl.Ops, builder.FanOut(bs, tail).Ops...)
+	return pl, used
+}
+
+// workspace builds tail for `pm ws:NAME`: the workspace's own commands run
+// in its root, everything else in each member in dependency order.
+func workspace(name string, tail []string, pluginsDir string) *plan.Plan {
+	ws, err := config.LoadWorkspace(name)
+	if err != nil {
+		fail(err.Error())
+	}
+	globalCfg, _ := config.LoadGlobal()
+	self, members, err := builder.NewWorkspace(name, ws, globalCfg, pluginsDir)
+	if err != nil {
+		fail(fmt.Sprintf("workspace %s: %v", name, err))
+	}
+	return builder.Workspace(self, members, tail)
+}
+
+// workspacesLs prints every workspace with its members in run order.
+func workspacesLs() {
+	all, err := config.LoadWorkspaces()
+	if err != nil {
+		fail(err.Error())
+	}
+	if len(all) == 0 {
+		fmt.Println("# pm: no workspaces. Define them under workspaces: in global.yml or workspace.yml")
+		return
+	}
+	fmt.Print("# pm: workspaces\n\n")
+	for _, n := range slices.Sorted(maps.Keys(all)) {
+		ws := all[n]
+		fmt.Printf("- %s%s  %s\n", config.WorkspacePrefix, n, ws.Description)
+		members, err := ws.Members()
+		if err != nil {
+			fmt.Printf("  INVALID: %v\n", err)
 			continue
 		}
-		params := map[string]string{
-			"args": strings.Join(ch.Args, " "),
+		var mn []string
+		for _, m := range members {
+			mn = append(mn, m.Name)
 		}
-		cmdLines := cmd.AsLines()
-		for _, raw := range cmdLines {
-			rendered, err := templ.RenderString(raw, params, meta, globalCfg, nil)
-			if err != nil {
-				pl.Echo("# pm: template error: " + err.Error())
-				continue
+		fmt.Printf("  projects: %s\n", strings.Join(mn, " -> "))
+		if len(ws.Commands) > 0 {
+			cmds := slices.Sorted(maps.Keys(ws.Commands))
+			fmt.Printf("  commands: :%s\n", strings.Join(cmds, ", :"))
+		}
+	}
+}
+
+// registryRepair implements `pm registry repair`.
+func registryRepair() {
+	rep, err := config.RegRepair()
+	if err != nil {
+		fail(err.Error())
+	}
+	if rep.OK {
+		fmt.Println("# pm: registry is fine, nothing to repair")
+		return
+	}
+	if rep.Corrupt != "" {
+		fmt.Printf("# pm: moved the unreadable registry to %s\n", rep.Corrupt)
+	}
+	fmt.Printf("# pm: restored %d project(s) from the backup\n", rep.Restored)
+	for _, n := range rep.Salvaged {
+		fmt.Printf("# pm: recovered %s\n", n)
+	}
+}
+
+// listProjects implements `pm ls [--tag T] [--recent] [--format F]`.
+func listProjects(args []string) {
+	fs := flag.NewFlagSet("ls", flag.ExitOnError)
+	var opts config.LsOptions
+	fs.StringVar(&opts.Tag, "tag", "", "only projects with this tag")
+	fs.BoolVar(&opts.Recent, "recent", false, "most recently used first")
+	fs.StringVar(&opts.Format, "format", "text", "output format: "+strings.Join(config.LsFormats, "|"))
+	asJSON := fs.Bool("json", false, "same as --format json")
+	_ = fs.Parse(args)
+	if *asJSON {
+		opts.Format = "json"
+	}
+	if err := config.RegLs(os.Stdout, opts); err != nil {
+		fail(err.Error())
+	}
+}
+
+// showProject implements `pm show PROJECT [--json] [--format text|json|tsv]`.
+func showProject(args []string, pluginsDir string) {
+	fs := flag.NewFlagSet("show", flag.ExitOnError)
+	format := fs.String("format", "text", "output format: text|json|tsv")
+	asJSON := fs.Bool("json", false, "same as --format json")
+	refs := parseInterspersed(fs, args)
+	if len(refs) != 1 {
+		fail("pm show PROJECT [--json] [--format text|json|tsv]")
+	}
+	if *asJSON {
+		*format = "json"
+	}
+	meta, root, err := config.ResolveProject(refs[0])
+	if err != nil {
+		fail(err.Error())
+	}
+	b := &builder.Builder{Meta: meta, Root: root, PluginsDir: pluginsDir}
+	d := b.Describe()
+	if e, ok := config.Entry(meta.Info.Name); ok {
+		d.Meta = e.Meta
+	}
+
+	switch *format {
+	case "json":
+		out, err := json.MarshalIndent(d, "", "  ")
+		if err != nil {
+			fail(err.Error())
+		}
+		fmt.Printf("%s\n", out)
+	case "tsv":
+		// :command<TAB>description and @group<TAB>services, for completion scripts
+		for _, c := range d.Commands {
+			fmt.Printf(":%s\t%s\n", c.Name, c.Description)
+			for _, a := range c.Aliases {
+				fmt.Printf(":%s\t%s\n", a, c.Description)
 			}
-			if strings.TrimSpace(rendered) != "" {
-				pl.Run(rendered)
+		}
+		if d.Docker != nil {
+			for _, g := range d.Docker.Groups {
+				fmt.Printf("@%s\t%s\n", g.Name, strings.Join(g.Services, " "))
 			}
 		}
+	case "text":
+		printDescription(d)
+	default:
+		fail(fmt.Sprintf("unknown format %q (text|json|tsv)", *format))
 	}
-
-	renderAndPrint(pl, dialect, plugins)
 }
 
-func renderAndPrint(pl *plan.Plan, dialect, plugins string) {
-	// select dialect
-	if dialect == "" {
-		if v := os.Getenv("PM_DIALECT"); v != "" {
-			dialect = v
-		} else {
-			// default per OS
-			if isWindows() {
-				dialect = "pwsh"
-			} else {
-				dialect = "bash"
+func printDescription(d builder.Description) {
+	fmt.Printf("# pm: %s", d.Name)
+	if d.Description != "" {
+		fmt.Printf(" - %s", d.Description)
+	}
+	fmt.Printf("\n\nroot: %s\n", d.Root)
+	if d.Meta != "" {
+		fmt.Printf("meta: %s\n", d.Meta)
+	}
+	if len(d.Aliases) > 0 {
+		fmt.Printf("aliases: %s\n", strings.Join(d.Aliases, ", "))
+	}
+	if len(d.Tags) > 0 {
+		fmt.Printf("tags: %s\n", strings.Join(d.Tags, ", "))
+	}
+	fmt.Println("\ncommands:")
+	for _, c := range d.Commands {
+		name := ":" + c.Name
+		if len(c.Aliases) > 0 {
+			name += " (" + strings.Join(c.Aliases, ", ") + ")"
+		}
+		desc := c.Description
+		if desc == "" {
+			desc = "-"
+		}
+		if c.Source != "meta" {
+			desc += " (" + c.Source + ")"
+		}
+		if c.Group != "" {
+			desc += " [" + c.Group + "]"
+		}
+		fmt.Printf("  %s  - %s\n", name, desc)
+		for _, p := range c.Params {
+			fmt.Printf("      --%s\n", p.String())
+		}
+		if len(c.Uses) > 0 {
+			fmt.Printf("      uses: @{%s}\n", strings.Join(c.Uses, "}, @{"))
+		}
+		if len(c.Deps) > 0 {
+			fmt.Printf("      deps: :%s\n", strings.Join(c.Deps, ", :"))
+		}
+	}
+	if len(d.Functions) > 0 {
+		fmt.Println("\nfunctions:")
+		for _, f := range d.Functions {
+			var ps []string
+			for _, p := range f.Params {
+				ps = append(ps, p.String())
 			}
+			fmt.Printf("  _{%s(%s)}\n", f.Name, strings.Join(ps, ", "))
 		}
 	}
+	if d.Docker != nil {
+		fmt.Printf("\ndocker: %s\n", d.Docker.ComposeFile)
+		for _, g := range d.Docker.Groups {
+			fmt.Printf("  @%s  %s\n", g.Name, strings.Join(g.Services, " "))
+		}
+	}
+}
+
+// whichProject implements `pm which PROJECT [--meta]`: the project's root
+// directory, or its meta file.
+func whichProject(args []string) {
+	fs := flag.NewFlagSet("which", flag.ExitOnError)
+	showMeta := fs.Bool("meta", false, "print the meta file instead of the root")
+	refs := parseInterspersed(fs, args)
+	if len(refs) != 1 {
+		fail("pm which PROJECT [--meta]")
+	}
+	meta, root, err := config.ResolveProject(refs[0])
+	if err != nil {
+		fail(err.Error())
+	}
+	if !*showMeta {
+		fmt.Println(root)
+		return
+	}
+	e, ok := config.Entry(meta.Info.Name)
+	if !ok {
+		fail(fmt.Sprintf("project %s is not registered", meta.Info.Name))
+	}
+	fmt.Println(e.Meta)
+}
+
+// scanProjects implements `pm scan DIR [--depth N]`.
+func scanProjects(args []string) {
+	fs := flag.NewFlagSet("scan", flag.ExitOnError)
+	depth := fs.Int("depth", 3, "directory levels to descend below DIR")
+	dirs := parseInterspersed(fs, args)
+	if len(dirs) == 0 {
+		fail("pm scan DIR [--depth N]")
+	}
+	for _, dir := range dirs {
+		rep, err := config.RegScan(dir, *depth)
+		if err != nil {
+			fail(err.Error())
+		}
+		for _, p := range rep.Added {
+			fmt.Printf("# pm: added %s (%s)\n", p.Name, p.Meta)
+		}
+		for _, p := range rep.Updated {
+			fmt.Printf("# pm: updated %s (%s)\n", p.Name, p.Meta)
+		}
+		for _, c := range rep.Conflicts {
+			fmt.Printf("# pm: conflict: %s is defined by %s\n", c.Name, strings.Join(c.Metas, " and "))
+		}
+		for p, err := range rep.Invalid {
+			fmt.Printf("# pm: skipped %s: %v\n", p, err)
+		}
+		fmt.Printf("# pm: %s: %d added, %d updated, %d unchanged, %d conflicts\n",
+			dir, len(rep.Added), len(rep.Updated), len(rep.Unchanged), len(rep.Conflicts))
+	}
+}
+
+func resolvePluginsDir(plugins string) string {
 	if plugins == "" {
 		if v := os.Getenv("PM_PLUGIN_DIR"); v != "" {
 			plugins = v
@@ -158,12 +703,50 @@ func renderAndPrint(pl *plan.Plan, dialect, plugins string) {
 			plugins = filepath.Join(home, ".config", "pm", "plugins")
 		}
 	}
+	return plugins
+}
 
-	s, err := render.Render(pl, dialect, plugins)
+// pluginsLs prints every renderer and command plugin found in dir with its --describe
+// answer; invalid plugins are listed with the validation error.
+func pluginsLs(dir string) {
+	infos, errs, err := plugin.Discover(dir)
 	if err != nil {
 		fail(err.Error())
 	}
-	fmt.Print(s)
+	if len(infos) == 0 {
+		fmt.Printf("# pm: no plugins in %s\n", dir)
+		return
+	}
+	fmt.Printf("# pm: plugins (%s)\n\n", dir)
+	for i, pi := range infos {
+		switch {
+		case errs[i] != nil:
+			fmt.Printf("- %s [%s]  INVALID: %v\n  path: %s\n", pi.Name, pi.Kind, errs[i], pi.Path)
+		case pi.Legacy:
+			fmt.Printf("- %s [%s]  legacy (no --describe)\n  path: %s\n", pi.Name, pi.Kind, pi.Path)
+		case pi.Kind == plugin.KindCommand:
+			fmt.Printf("- :%s [%s]  %s  protocol %d  %s\n  path: %s\n",
+				pi.Name, pi.Kind, pi.Version, pi.ProtocolVersion, pi.Description, pi.Path)
+		default:
+			fmt.Printf("- %s [%s]  %s  protocol %d  ops: %s\n  path: %s\n",
+				pi.Name, pi.Kind, pi.Version, pi.ProtocolVersion, strings.Join(pi.Ops, ","), pi.Path)
+		}
+	}
+}
+
+// parseInterspersed parses args with fs, allowing flags after positional
+// arguments too (pm scan ~/repos --depth 2), and returns the positionals.
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	var pos []string
+	for len(args) > 0 {
+		_ = fs.Parse(args)
+		args = fs.Args()
+		if len(args) > 0 {
+			pos = append(pos, args[0])
+			args = args[1:]
+		}
+	}
+	return pos
 }
 
 func fail(msg string) {
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+
+	"pm/internal/cache"
+	"pm/internal/config"
+	"pm/internal/plan"
+	"pm/internal/runner"
+)
+
+// runArgs implements the flags of `pm run [--log FILE] [--force]
+// [--dialect NAME] TARGET args...`: the plan is executed by pm-bin instead of printed for the
+// wrapper, in the shell of --dialect or else the project's dialect. It
+// returns the target and its arguments and the function that runs a plan
+// and exits with its status.
+func runArgs(args []string, force *bool, dialect *string) ([]string, func(*plan.Plan, string)) {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	log := fs.String("log", os.Getenv("PM_STEP_LOG"), "append every step's duration and status to this JSONL file [env PM_STEP_LOG]")
+	fs.BoolVar(force, "force", *force, "run commands even when their inputs: are unchanged")
+	fs.StringVar(dialect, "dialect", *dialect, "shell to run the steps in ("+strings.Join(runner.Dialects, "|")+"; default: the project's dialect, else sh)")
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 {
+		fail("pm run [--log FILE] [--force] [--dialect NAME] PROJECT :command [args...]")
+	}
+	return fs.Args(), func(pl *plan.Plan, project string) {
+		// Ctrl-C is for the running command; pm-bin waits for it to exit
+		signal.Notify(make(chan os.Signal, 1), os.Interrupt)
+		r := &runner.Runner{Project: project, Log: *log, Dialect: runDialect(*dialect, project)}
+		status := r.Run(pl)
+		_ = config.SetHistoryStatus(os.Getppid(), status)
+		done, _ := strconv.Atoi(r.Getenv(cache.DoneVar))
+		_ = cache.Commit(os.Getppid(), status, done)
+		os.Exit(status)
+	}
+}
+
+// runDialect is the shell pm run uses for project: dialect if set, else
+// the one set with pm set PROJECT dialect=NAME, else the system shell.
+func runDialect(dialect, project string) string {
+	if dialect == "" {
+		if e, ok := config.Entry(project); ok {
+			dialect = e.Dialect
+		}
+	}
+	if dialect != "" && !slices.Contains(runner.Dialects, dialect) {
+		fail(fmt.Sprintf("pm run: cannot run %s scripts, only %s", dialect, strings.Join(runner.Dialects, ", ")))
+	}
+	return dialect
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"time"
+
+	"pm/internal/builder"
+	"pm/internal/config"
+	"pm/internal/runner"
+	"pm/internal/watch"
+)
+
+// Watch mode polls the files this often and runs once they have been
+// quiet for watchDebounce.
+const (
+	watchInterval = 250 * time.Millisecond
+	watchDebounce = 300 * time.Millisecond
+)
+
+// watchFlag removes the --watch of `pm PROJECT :test --watch` from tail;
+// a raw command keeps it (`pm api npx jest --watch`).
+func watchFlag(tail []string) ([]string, bool) {
+	if len(tail) == 0 || !strings.HasPrefix(tail[0], ":") || !slices.Contains(tail, "--watch") {
+		return tail, false
+	}
+	return slices.DeleteFunc(slices.Clone(tail), func(a string) bool { return a == "--watch" }), true
+}
+
+// watchProject builds and runs tail in pm-bin, then again whenever the
+// watched files change, until Ctrl-C, and exits with the last run's
+// status. Through the wrapper stdout is the script it evals, so the
+// commands write to stderr; with pm run (direct) they keep stdout.
+// dialect is the runner's shell, see runDialect.
+func watchProject(b *builder.Builder, tail, patterns []string, clearScreen, direct bool, dialect string) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	out := os.Stderr
+	if direct {
+		out = os.Stdout
+	}
+	w := &watch.Watcher{
+		Root:     b.Root,
+		Patterns: patterns,
+		Debounce: watchDebounce,
+		Interval: watchInterval,
+		Clear:    clearScreen,
+		Out:      out,
+	}
+	status := w.Run(ctx, func() int {
+		r := &runner.Runner{Stdin: os.Stdin, Stdout: out, Stderr: os.Stderr, Project: b.Meta.Info.Name, Dialect: dialect}
+		return r.Run(b.Build(tail))
+	})
+	_ = config.SetHistoryStatus(os.Getppid(), status)
+	os.Exit(status)
+}
@@ -0,0 +1,345 @@
+package builder
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"pm/internal/cache"
+	"pm/internal/config"
+	"pm/internal/dsl"
+	"pm/internal/match"
+	"pm/internal/plan"
+	"pm/internal/plugin"
+	"pm/internal/templ"
+)
+
+// Builder turns the arguments that follow a project reference into a plan.
+type Builder struct {
+	Meta       *config.ProjectMeta
+	Root       string
+	Global     *config.GlobalConfig
+	PluginsDir string
+	// KeepEnv leaves ${ENV} in command lines instead of substituting the
+	// current environment (used when exporting to other task runners).
+	KeepEnv bool
+	// exported after entering Root, from the project's registry entry
+	Env map[string]string
+	// Cache skips commands whose inputs: are unchanged since their last
+	// successful run; nil runs every command.
+	Cache *cache.Cache
+	// Preview builds a plan only to be shown: command plugins are listed
+	// instead of run. Leave Cache nil so that no inputs are hashed either.
+	Preview bool
+
+	// project:command chain of cross-project calls being expanded
+	calls []string
+}
+
+// Build returns the plan for tail, e.g. [:build -x test :up @base].
+func (b *Builder) Build(tail []string) *plan.Plan {
+	chunks := dsl.SplitColonCommands(tail)
+	pl := plan.New()
+	pl.Pushd(b.Root)
+	for _, k := range slices.Sorted(maps.Keys(b.Env)) {
+		pl.Env(k, b.Env[k])
+	}
+
+	// raw mode; with failure hooks the line is a step for them to see
+	// it fail
+	if len(chunks) == 1 && chunks[0].Name == "__RAW__" {
+		line := strings.Join(chunks[0].Args, " ")
+		hooks := b.planHooks()
+		switch {
+		case strings.TrimSpace(line) == "":
+		case len(hooks.OnFailure) > 0:
+			pl.Step(line, []plan.Op{plan.OpRun{Line: line}}, nil)
+		default:
+			pl.Run(line)
+		}
+		pl.Splice(hooks)
+		return pl
+	}
+
+	// named :commands, each one a timed step; with several a table of
+	// their durations and statuses ends the plan
+	done := map[string]bool{}
+	steps := 0
+	for _, ch := range chunks {
+		sub := plan.New()
+		pending := b.pending()
+		b.addChunk(sub, ch, done)
+		if !plan.Runs(sub.Ops) {
+			pl.Ops = append(pl.Ops, sub.Ops...)
+			continue
+		}
+		// a later step failing keeps the stamps of this one; the count is
+		// the step's last op, so it is only exported when the step passed
+		if b.pending() > pending {
+			sub.Env(cache.DoneVar, strconv.Itoa(b.pending()))
+		}
+		pl.Step(strings.TrimSpace(":"+ch.Name+" "+strings.Join(ch.Args, " ")), sub.Ops, b.notify(ch.Name))
+		steps++
+	}
+	if steps > 1 {
+		pl.Timings()
+	}
+	if b.pending() > 0 {
+		// a value left in the shell by an earlier script does not count
+		pl.Ops = slices.Insert(pl.Ops, 1, plan.Op(plan.OpEnv{Name: cache.DoneVar, Value: "0"}))
+	}
+	pl.Splice(b.planHooks())
+	return pl
+}
+
+// pending returns the number of stamps pending in the cache.
+func (b *Builder) pending() int {
+	if b.Cache == nil {
+		return 0
+	}
+	return len(b.Cache.Pending)
+}
+
+// FanOut builds tail for every project in its own block, named after the
+// project, and ends the plan with a summary of which blocks failed. The
+// project's failure hooks run after its block fails, back in the project.
+func FanOut(bs []*Builder, tail []string) *plan.Plan {
+	pl := plan.New()
+	for _, b := range bs {
+		sub := b.Build(tail)
+		var onFailure []plan.Op
+		if hooks := b.planHooks().OnFailure; len(hooks) > 0 {
+			onFailure = append(append(slices.Clone(sub.Entry()), hooks...), plan.OpPopd{})
+		}
+		sub.Popd()
+		pl.Ops = append(pl.Ops, plan.OpBlock{Name: b.Meta.Info.Name, Ops: plan.Flatten(sub.Ops), OnFailure: onFailure})
+	}
+	pl.Summary()
+	return pl
+}
+
+// notify returns the completion notification for the typed command: the
+// command's notify: setting, else the global one.
+func (b *Builder) notify(name string) *plan.Notify {
+	var cmd, global *config.NotifySetting
+	if b.Global != nil {
+		global = b.Global.Notify
+	}
+	if resolved, err := b.resolveCommand(name); err == nil {
+		cmd = b.Meta.Commands[resolved].Notify
+	}
+	n := config.Notification(cmd, global)
+	if n == nil {
+		return nil
+	}
+	return &plan.Notify{Title: "pm: " + b.Meta.Info.Name, After: n.After, Via: n.Via}
+}
+
+func (b *Builder) addChunk(pl *plan.Plan, ch dsl.Chunk, done map[string]bool) {
+	name, err := b.resolveCommand(ch.Name)
+	if err != nil {
+		pl.Echo("# pm: " + err.Error())
+		return
+	}
+	ch.Name = name
+	if ch.Name == "help" {
+		b.help(pl, ch.Args)
+		return
+	}
+	// built-in :up
+	if ch.Name == "up" {
+		b.addHooked(pl, ch.Name, ch.Args, func() {
+			for _, c := range plan.DockerUp(b.Meta, ch.Args) {
+				pl.Run(c)
+			}
+		})
+		return
+	}
+	// user-defined
+	if cmd, ok := b.Meta.Commands[ch.Name]; ok {
+		if err := b.enter(ch.Name); err != nil {
+			pl.Echo("# pm: " + err.Error())
+			return
+		}
+		defer b.leave()
+		if err := b.addDeps(pl, cmd.Deps, done, []string{ch.Name}); err != nil {
+			pl.Echo("# pm: " + err.Error())
+			return
+		}
+		b.addCommand(pl, ch.Name, cmd, ch.Args)
+		done[ch.Name] = true
+		return
+	}
+	// command plugin pm-cmd-<name>
+	if exe := plugin.Lookup(b.PluginsDir, plugin.KindCommand, ch.Name); exe != "" {
+		b.addHooked(pl, ch.Name, ch.Args, func() { b.addPlugin(pl, exe, ch) })
+		return
+	}
+	pl.Echo(fmt.Sprintf("# pm: unknown command :%s", ch.Name))
+}
+
+// resolveCommand maps a typed command name to a built-in, project command
+// or command plugin: exact name, then alias, then unambiguous prefix.
+func (b *Builder) resolveCommand(name string) (string, error) {
+	if _, ok := b.Meta.Commands[name]; ok || name == "help" || name == "up" {
+		return name, nil
+	}
+	cands := []match.Candidate{{Name: "help"}, {Name: "up"}}
+	for k, c := range b.Meta.Commands {
+		cands = append(cands, match.Candidate{Name: k, Aliases: c.Aliases})
+	}
+	for _, p := range plugin.Names(b.PluginsDir, plugin.KindCommand) {
+		if _, shadowed := b.Meta.Commands[p]; !shadowed {
+			cands = append(cands, match.Candidate{Name: p})
+		}
+	}
+	got, err := match.Find(name, cands)
+	var amb *match.AmbiguousError
+	var nf *match.NotFoundError
+	switch {
+	case errors.As(err, &amb):
+		return "", fmt.Errorf("ambiguous command :%s: :%s", name, strings.Join(amb.Matches, ", :"))
+	case errors.As(err, &nf):
+		return "", fmt.Errorf("unknown command :%s%s", name, match.DidYouMean(nf.Suggestions, ":"))
+	}
+	return got, nil
+}
+
+// addDeps adds the dependencies of a command depth-first, each at most once
+// per plan; stack is the chain of commands being expanded, for cycle errors.
+func (b *Builder) addDeps(pl *plan.Plan, deps []string, done map[string]bool, stack []string) error {
+	for _, d := range deps {
+		if done[d] {
+			continue
+		}
+		if slices.Contains(stack, d) {
+			return fmt.Errorf("dependency cycle: %s -> %s", strings.Join(stack, " -> "), d)
+		}
+		cmd, ok := b.Meta.Commands[d]
+		if !ok {
+			return fmt.Errorf("unknown dependency :%s of :%s", d, stack[len(stack)-1])
+		}
+		if err := b.addDeps(pl, cmd.Deps, done, append(stack, d)); err != nil {
+			return err
+		}
+		b.addCommand(pl, d, cmd, nil)
+		done[d] = true
+	}
+	return nil
+}
+
+// AddDeps appends the dependencies of the command called name, each once
+// and without args, the way Build runs them before the command.
+func (b *Builder) AddDeps(pl *plan.Plan, name string) error {
+	return b.addDeps(pl, b.Meta.Commands[name].Deps, map[string]bool{}, []string{name})
+}
+
+// AddCommand appends the rendered lines of a user-defined command, without
+// its dependencies. Declared params are taken from args (--name=value) and
+// the rest becomes @{args}. A line calling another project's command (:api:up or
+// _{pm(api, up)}) is expanded in that project's root.
+func (b *Builder) AddCommand(pl *plan.Plan, cmd config.CommandDef, args []string) {
+	b.addCommand(pl, "", cmd, args)
+}
+
+// addCommand is AddCommand between the before: and after: hooks of name,
+// which see the command's params.
+func (b *Builder) addCommand(pl *plan.Plan, name string, cmd config.CommandDef, args []string) {
+	params, rest, err := bindParams(cmd, args)
+	if err != nil {
+		pl.Echo("# pm: " + err.Error())
+		return
+	}
+	params["args"] = strings.Join(rest, " ")
+	// stamps of cross-project calls in the body are dropped with it
+	pending := b.pending()
+	body := plan.New()
+	before, after := b.commandHooks(name)
+	body.Ops = append(body.Ops, b.hookOps(before, params)...)
+	b.addLines(body, cmd, params)
+	body.Ops = append(body.Ops, b.hookOps(after, params)...)
+	stamp, fresh := b.stamp(pl, name, cmd, body.Ops)
+	if fresh {
+		b.Cache.Pending = b.Cache.Pending[:pending]
+		pl.Echo(fmt.Sprintf("# pm: :%s is up to date (inputs unchanged since its last successful run), skipped; pm --force runs it", name))
+		return
+	}
+	if stamp != nil {
+		b.Cache.Add(*stamp)
+	}
+	pl.Ops = append(pl.Ops, body.Ops...)
+}
+
+// stamp hashes the inputs: of command name for the cache and reports
+// whether it can be skipped. It returns nil without a cache or inputs.
+func (b *Builder) stamp(pl *plan.Plan, name string, cmd config.CommandDef, body []plan.Op) (*cache.Stamp, bool) {
+	if b.Cache == nil || name == "" || len(cmd.Inputs) == 0 {
+		return nil, false
+	}
+	// what the command would run counts as an input too: its lines as
+	// rendered (funcs, #{cfg}, ${ENV}, params) and the registry env
+	var extra []string
+	for _, op := range body {
+		extra = append(extra, fmt.Sprint(op))
+	}
+	for _, k := range slices.Sorted(maps.Keys(b.Env)) {
+		extra = append(extra, k+"="+b.Env[k])
+	}
+	hash, err := cache.Hash(b.Root, cmd.Inputs, extra)
+	if err != nil {
+		pl.Echo(fmt.Sprintf("# pm: inputs of :%s: %v", name, err))
+		return nil, false
+	}
+	s := &cache.Stamp{Project: b.Meta.Info.Name, Command: name, Hash: hash}
+	return s, cache.Exist(b.Root, cmd.Outputs) && b.Cache.Fresh(*s)
+}
+
+// addLines appends the command's lines rendered with params.
+func (b *Builder) addLines(pl *plan.Plan, cmd config.CommandDef, params map[string]string) {
+	opts := templ.Options{KeepEnv: b.KeepEnv}
+	for _, raw := range cmd.AsLines() {
+		if c, ok := parseCall(raw); ok {
+			b.addCall(pl, c, params, opts)
+			continue
+		}
+		rendered, err := templ.RenderStringOpts(raw, params, b.Meta, b.Global, nil, opts)
+		if err != nil {
+			pl.Echo("# pm: template error: " + err.Error())
+			continue
+		}
+		if strings.TrimSpace(rendered) != "" {
+			pl.Run(rendered)
+		}
+	}
+}
+
+func (b *Builder) addPlugin(pl *plan.Plan, exe string, ch dsl.Chunk) {
+	if b.Preview {
+		pl.Echo(fmt.Sprintf("# pm: :%s (plugin)", ch.Name))
+		return
+	}
+	info, err := plugin.Describe(plugin.KindCommand, exe)
+	if err != nil {
+		pl.Echo("# pm: plugin error: " + err.Error())
+		return
+	}
+	var global map[string]any
+	if b.Global != nil {
+		global = b.Global.Raw
+	}
+	ops, err := plugin.RunCommand(info, plugin.CommandRequest{
+		Command: ch.Name,
+		Args:    ch.Args,
+		Root:    b.Root,
+		Project: b.Meta,
+		Global:  global,
+	})
+	if err != nil {
+		pl.Echo("# pm: plugin error: " + err.Error())
+		return
+	}
+	pl.Ops = append(pl.Ops, ops...)
+}
@@ -0,0 +1,102 @@
+package builder
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"pm/internal/config"
+	"pm/internal/dsl"
+	"pm/internal/plan"
+	"pm/internal/templ"
+)
+
+var (
+	// _{pm(api, up, @base)}
+	pmCallRe = regexp.MustCompile(`^\s*_\{pm\((.*)\)\}\s*$`)
+	// :api:up @base
+	colonCallRe = regexp.MustCompile(`^\s*:([^:\s]+):([^:\s]+)(?:\s+(.*))?$`)
+)
+
+// call is a command line that runs another project's command.
+type call struct {
+	Project, Command string
+	// Args is still to be rendered in the caller's context; List says
+	// whether it is a comma-separated _{pm(...)} list or shell words.
+	Args string
+	List bool
+}
+
+// parseCall recognizes a cross-project call. It must be the whole line.
+func parseCall(line string) (call, bool) {
+	if m := pmCallRe.FindStringSubmatch(line); m != nil {
+		parts := strings.SplitN(m[1], ",", 3)
+		if len(parts) < 2 {
+			return call{}, false
+		}
+		c := call{
+			Project: strings.TrimSpace(parts[0]),
+			Command: strings.TrimPrefix(strings.TrimSpace(parts[1]), ":"),
+			List:    true,
+		}
+		if len(parts) == 3 {
+			c.Args = parts[2]
+		}
+		return c, true
+	}
+	if m := colonCallRe.FindStringSubmatch(line); m != nil {
+		return call{Project: m[1], Command: m[2], Args: m[3]}, true
+	}
+	return call{}, false
+}
+
+// addCall expands another project's command with that project's meta,
+// funcs, root and global config, inside its own pushd/popd: the caller's
+// workspace vars do not leak into it.
+func (b *Builder) addCall(pl *plan.Plan, c call, params map[string]string, opts templ.Options) {
+	argstr, err := templ.RenderStringOpts(c.Args, params, b.Meta, b.Global, nil, opts)
+	if err != nil {
+		pl.Echo("# pm: template error: " + err.Error())
+		return
+	}
+	args := strings.Fields(argstr)
+	if c.List {
+		args = templ.SplitArgs(argstr)
+	}
+	meta, root, err := config.ResolveProject(c.Project)
+	if err != nil {
+		pl.Echo(fmt.Sprintf("# pm: :%s:%s: %v", c.Project, c.Command, err))
+		return
+	}
+	sub := &Builder{
+		Meta:       meta,
+		Root:       root,
+		Global:     config.ProjectGlobal(meta.Info.Name),
+		PluginsDir: b.PluginsDir,
+		KeepEnv:    b.KeepEnv,
+		Cache:      b.Cache,
+		Preview:    b.Preview,
+		calls:      slices.Clone(b.calls),
+	}
+	if e, ok := config.Entry(meta.Info.Name); ok {
+		sub.Env = e.Env
+	}
+	pl.Pushd(root)
+	sub.addChunk(pl, dsl.Chunk{Name: c.Command, Args: args}, map[string]bool{})
+	pl.Popd()
+}
+
+// enter records project:command on the call chain; it fails when the
+// command is already being expanded, i.e. projects call each other in a
+// cycle.
+func (b *Builder) enter(name string) error {
+	key := b.Meta.Info.Name + ":" + name
+	if slices.Contains(b.calls, key) {
+		return fmt.Errorf("cross-project cycle: %s -> %s", strings.Join(b.calls, " -> "), key)
+	}
+	b.calls = append(b.calls, key)
+	return nil
+}
+
+func (b *Builder) leave() { b.calls = b.calls[:len(b.calls)-1] }
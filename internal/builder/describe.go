@@ -0,0 +1,194 @@
+package builder
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"pm/internal/config"
+	"pm/internal/plugin"
+	"pm/internal/templ"
+)
+
+// Description is what pm show reports about a project.
+type Description struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description,omitempty"`
+	Root        string        `json:"root"`
+	Meta        string        `json:"meta,omitempty"`
+	Aliases     []string      `json:"aliases,omitempty"`
+	Tags        []string      `json:"tags,omitempty"`
+	Commands    []CommandInfo `json:"commands"`
+	Functions   []FuncInfo    `json:"functions"`
+	// functions of global.yml, called as _{global.name()}
+	GlobalFunctions []FuncInfo  `json:"global_functions,omitempty"`
+	Docker          *DockerInfo `json:"docker,omitempty"`
+}
+
+// CommandInfo describes a :command the project accepts.
+type CommandInfo struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Aliases     []string `json:"aliases,omitempty"`
+	Group       string   `json:"group,omitempty"`
+	// declared params, given as --name=value
+	Params []ParamInfo `json:"params,omitempty"`
+	// other @{param} names the command lines use, e.g. args
+	Uses []string `json:"uses,omitempty"`
+	Deps []string `json:"deps,omitempty"`
+	// globs deciding whether the command is up to date
+	Inputs  []string `json:"inputs,omitempty"`
+	Outputs []string `json:"outputs,omitempty"`
+	Watch   []string `json:"watch,omitempty"`
+	// "meta", "builtin" or "plugin"
+	Source string `json:"source"`
+}
+
+// FuncInfo describes a func: entry of the project.
+type FuncInfo struct {
+	Name   string      `json:"name"`
+	Params []ParamInfo `json:"params,omitempty"`
+}
+
+// ParamInfo describes a function or command parameter.
+type ParamInfo struct {
+	Name        string   `json:"name"`
+	Required    bool     `json:"required,omitempty"`
+	Default     string   `json:"default,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Enum        []string `json:"enum,omitempty"`
+}
+
+// DockerInfo describes the docker: section of the project.
+type DockerInfo struct {
+	ComposeFile string      `json:"compose_file,omitempty"`
+	Groups      []GroupInfo `json:"groups,omitempty"`
+}
+
+// GroupInfo is a docker compose group usable as @name.
+type GroupInfo struct {
+	Name     string   `json:"name"`
+	Services []string `json:"services"`
+}
+
+// Describe returns the project's commands, functions and docker groups,
+// each sorted by name. Command plugins not shadowed by the project's
+// commands are included.
+func (b *Builder) Describe() Description {
+	m := b.Meta
+	d := Description{
+		Name:        m.Info.Name,
+		Description: m.Info.Description,
+		Root:        b.Root,
+		Aliases:     m.Info.Aliases,
+		Tags:        m.Info.Tags,
+		Commands:    []CommandInfo{},
+		Functions:   []FuncInfo{},
+	}
+	for _, name := range slices.Sorted(maps.Keys(m.Commands)) {
+		c := m.Commands[name]
+		d.Commands = append(d.Commands, CommandInfo{
+			Name:        name,
+			Description: strings.TrimSpace(c.Description),
+			Aliases:     c.Aliases,
+			Group:       c.Group,
+			Params:      paramInfos(c.Params),
+			Uses:        undeclared(c),
+			Deps:        c.Deps,
+			Inputs:      c.Inputs,
+			Outputs:     c.Outputs,
+			Watch:       c.Watch,
+			Source:      "meta",
+		})
+	}
+	for _, bi := range []CommandInfo{
+		{Name: "help", Description: "list the project's commands, or one command in detail", Source: "builtin"},
+		{Name: "up", Description: "docker compose up -d with @groups and services", Source: "builtin"},
+	} {
+		if _, shadowed := m.Commands[bi.Name]; !shadowed {
+			d.Commands = append(d.Commands, bi)
+		}
+	}
+	if infos, errs, err := plugin.Discover(b.PluginsDir); err == nil {
+		for i, pi := range infos {
+			if pi.Kind != plugin.KindCommand || errs[i] != nil {
+				continue
+			}
+			if _, shadowed := m.Commands[pi.Name]; shadowed {
+				continue
+			}
+			d.Commands = append(d.Commands, CommandInfo{Name: pi.Name, Description: strings.TrimSpace(pi.Description), Source: "plugin"})
+		}
+	}
+	d.Functions = funcInfos(m.Func)
+	if b.Global != nil {
+		d.GlobalFunctions = funcInfos(b.Global.Func)
+	}
+	if m.Docker.ComposeFile != "" || len(m.Docker.Groups) > 0 {
+		d.Docker = &DockerInfo{ComposeFile: m.Docker.ComposeFile}
+		for _, g := range slices.Sorted(maps.Keys(m.Docker.Groups)) {
+			d.Docker.Groups = append(d.Docker.Groups, GroupInfo{Name: g, Services: m.Docker.Groups[g]})
+		}
+	}
+	return d
+}
+
+func funcInfos(funcs map[string]config.FuncDef) []FuncInfo {
+	out := []FuncInfo{}
+	for _, name := range slices.Sorted(maps.Keys(funcs)) {
+		out = append(out, FuncInfo{Name: name, Params: paramInfos(funcs[name].Params)})
+	}
+	return out
+}
+
+func paramInfos(params map[string]config.ParamMeta) []ParamInfo {
+	var out []ParamInfo
+	for _, k := range slices.Sorted(maps.Keys(params)) {
+		p := params[k]
+		out = append(out, ParamInfo{Name: k, Required: p.Required, Default: p.Default, Description: p.Description, Enum: p.Enum})
+	}
+	return out
+}
+
+// undeclared returns the @{param} names the lines of c use without
+// declaring them.
+func undeclared(c config.CommandDef) []string {
+	var out []string
+	for _, name := range templ.Params(strings.Join(c.AsLines(), "\n")) {
+		if _, declared := c.Params[name]; !declared {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// String formats the param as name=default, marking a required one with !,
+// followed by its allowed values and description.
+func (p ParamInfo) String() string {
+	s := p.Name
+	if p.Default != "" {
+		s += "=" + p.Default
+	}
+	if p.Required {
+		s += "!"
+	}
+	if len(p.Enum) > 0 {
+		s += " (" + strings.Join(p.Enum, "|") + ")"
+	}
+	if p.Description != "" {
+		s += "  " + p.Description
+	}
+	return s
+}
+
+// SortByUse moves the commands named in recent, most recent first, ahead
+// of the others, which keep their order.
+func (d *Description) SortByUse(recent []string) {
+	rank := func(c CommandInfo) int {
+		if i := slices.Index(recent, c.Name); i >= 0 {
+			return i
+		}
+		return len(recent)
+	}
+	slices.SortStableFunc(d.Commands, func(a, b CommandInfo) int { return rank(a) - rank(b) })
+}
@@ -0,0 +1,183 @@
+package builder
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"pm/internal/plan"
+)
+
+// help lists the project's commands, grouped and sorted, with their params
+// and dependencies, then docker groups and functions. With args it shows
+// one command in detail: :help deploy --env=prod.
+func (b *Builder) help(pl *plan.Plan, args []string) {
+	d := b.Describe()
+	if len(args) > 0 {
+		b.helpCommand(pl, d, args[0], args[1:])
+		return
+	}
+	title := "# pm: " + d.Name
+	if d.Description != "" {
+		title += " - " + d.Description
+	}
+	pl.Echo(title)
+
+	// meta commands under their group, ungrouped ones first
+	var groups []string
+	for _, c := range d.Commands {
+		if c.Source == "meta" && !slices.Contains(groups, c.Group) {
+			groups = append(groups, c.Group)
+		}
+	}
+	slices.Sort(groups)
+	for _, g := range groups {
+		if g == "" {
+			pl.Echo("# pm: project commands:")
+		} else {
+			pl.Echo("# pm: " + g + ":")
+		}
+		for _, c := range d.Commands {
+			if c.Source == "meta" && c.Group == g {
+				echoCommand(pl, c)
+			}
+		}
+	}
+	pl.Echo("# pm: built-ins:")
+	for _, c := range d.Commands {
+		if c.Source == "builtin" {
+			echoCommand(pl, c)
+		}
+	}
+	for _, c := range d.Commands {
+		if c.Source == "plugin" {
+			echoCommand(pl, c)
+		}
+	}
+	if d.Docker != nil && len(d.Docker.Groups) > 0 {
+		pl.Echo(fmt.Sprintf("# pm: docker groups (%s), for :up @group:", d.Docker.ComposeFile))
+		for _, g := range d.Docker.Groups {
+			pl.Echo(fmt.Sprintf("  @%s  - %s", g.Name, strings.Join(g.Services, " ")))
+		}
+	}
+	echoFuncs(pl, "# pm: functions:", "", d.Functions)
+	echoFuncs(pl, "# pm: global functions:", "global.", d.GlobalFunctions)
+	pl.Echo("# pm: details: :help COMMAND")
+}
+
+func echoCommand(pl *plan.Plan, c CommandInfo) {
+	name := ":" + c.Name
+	if len(c.Aliases) > 0 {
+		name += " (" + strings.Join(c.Aliases, ", ") + ")"
+	}
+	desc := c.Description
+	if desc == "" {
+		desc = "-"
+	}
+	if c.Source == "plugin" {
+		desc += " (plugin)"
+	}
+	pl.Echo(fmt.Sprintf("  %s  - %s", name, desc))
+	for _, p := range c.Params {
+		pl.Echo("      --" + p.String())
+	}
+	if len(c.Deps) > 0 {
+		pl.Echo("      runs first: :" + strings.Join(c.Deps, ", :"))
+	}
+}
+
+func echoFuncs(pl *plan.Plan, title, prefix string, fs []FuncInfo) {
+	if len(fs) == 0 {
+		return
+	}
+	pl.Echo(title)
+	for _, f := range fs {
+		var ps []string
+		for _, p := range f.Params {
+			ps = append(ps, p.String())
+		}
+		pl.Echo(fmt.Sprintf("  _{%s%s(%s)}", prefix, f.Name, strings.Join(ps, ", ")))
+	}
+}
+
+// helpCommand shows one command: its description, params, dependencies and
+// the lines it expands to with args, missing params shown as <name>.
+func (b *Builder) helpCommand(pl *plan.Plan, d Description, query string, args []string) {
+	name, err := b.resolveCommand(strings.TrimPrefix(query, ":"))
+	if err != nil {
+		pl.Echo("# pm: " + err.Error())
+		return
+	}
+	i := slices.IndexFunc(d.Commands, func(c CommandInfo) bool { return c.Name == name })
+	if i < 0 {
+		pl.Echo("# pm: no help for :" + name)
+		return
+	}
+	c := d.Commands[i]
+	title := "# pm: :" + c.Name
+	if c.Description != "" {
+		title += " - " + c.Description
+	}
+	pl.Echo(title)
+	if len(c.Aliases) > 0 {
+		pl.Echo("  aliases: :" + strings.Join(c.Aliases, ", :"))
+	}
+	if c.Group != "" {
+		pl.Echo("  group: " + c.Group)
+	}
+	if c.Source != "meta" {
+		pl.Echo("  " + c.Source + " command")
+		return
+	}
+	if len(c.Params) > 0 {
+		pl.Echo("  params:")
+		for _, p := range c.Params {
+			pl.Echo("    --" + p.String())
+		}
+	}
+	if len(c.Uses) > 0 {
+		pl.Echo("  uses: @{" + strings.Join(c.Uses, "}, @{") + "}")
+	}
+	if len(c.Deps) > 0 {
+		pl.Echo("  runs first: :" + strings.Join(c.Deps, ", :"))
+	}
+	if len(c.Inputs) > 0 {
+		pl.Echo("  inputs: " + strings.Join(c.Inputs, " "))
+	}
+	if len(c.Outputs) > 0 {
+		pl.Echo("  outputs: " + strings.Join(c.Outputs, " "))
+	}
+	if len(c.Watch) > 0 {
+		pl.Echo("  watch: " + strings.Join(c.Watch, " "))
+	}
+
+	cmd := b.Meta.Commands[name]
+	params, rest, err := parseParams(cmd, args)
+	if err != nil {
+		pl.Echo("# pm: " + err.Error())
+		return
+	}
+	for k := range cmd.Params {
+		if _, ok := params[k]; !ok {
+			params[k] = "<" + k + ">"
+		}
+	}
+	params["args"] = strings.Join(rest, " ")
+	script := plan.New()
+	b.addLines(script, cmd, params)
+	pl.Echo("  script:")
+	for _, op := range script.Ops {
+		switch v := op.(type) {
+		case plan.OpRun:
+			pl.Echo("    " + v.Line)
+		case plan.OpEcho:
+			pl.Echo("    echo " + v.Line)
+		case plan.OpPushd:
+			pl.Echo("    cd " + v.Dir)
+		case plan.OpPopd:
+			pl.Echo("    cd -")
+		case plan.OpEnv:
+			pl.Echo(fmt.Sprintf("    export %s=%s", v.Name, v.Value))
+		}
+	}
+}
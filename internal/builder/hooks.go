@@ -0,0 +1,70 @@
+package builder
+
+import (
+	"slices"
+	"strings"
+
+	"pm/internal/config"
+	"pm/internal/plan"
+)
+
+// The hooks of global.yml wrap the project's: global before hooks run
+// first, global after and failure hooks last.
+
+func (b *Builder) globalHooks() config.HooksDef {
+	if b.Global == nil {
+		return config.HooksDef{}
+	}
+	return b.Global.Hooks
+}
+
+// planHooks renders the hooks around a whole invocation.
+func (b *Builder) planHooks() plan.Hooks {
+	g, p := b.globalHooks(), b.Meta.Hooks
+	params := map[string]string{"args": ""}
+	return plan.Hooks{
+		BeforeAll: b.hookOps(append(slices.Clone(g.BeforeAll), p.BeforeAll...), params),
+		AfterAll:  b.hookOps(append(slices.Clone(p.AfterAll), g.AfterAll...), params),
+		OnFailure: b.hookOps(append(slices.Clone(p.OnFailure), g.OnFailure...), params),
+	}
+}
+
+// memberHooks renders a workspace member's own hooks; the global ones
+// wrap the whole workspace plan.
+func (b *Builder) memberHooks() plan.Hooks {
+	p := b.Meta.Hooks
+	params := map[string]string{"args": ""}
+	return plan.Hooks{
+		BeforeAll: b.hookOps(p.BeforeAll, params),
+		AfterAll:  b.hookOps(p.AfterAll, params),
+		OnFailure: b.hookOps(p.OnFailure, params),
+	}
+}
+
+// commandHooks returns the lines to run before and after command name.
+func (b *Builder) commandHooks(name string) (before, after []string) {
+	g, p := b.globalHooks(), b.Meta.Hooks
+	before = append(slices.Clone(g.Before[name]), p.Before[name]...)
+	after = append(slices.Clone(p.After[name]), g.After[name]...)
+	return before, after
+}
+
+// hookOps renders hook lines like a command's own, with its params.
+func (b *Builder) hookOps(lines []string, params map[string]string) []plan.Op {
+	if len(lines) == 0 {
+		return nil
+	}
+	pl := plan.New()
+	b.addLines(pl, config.CommandDef{Cmd: lines}, params)
+	return pl.Ops
+}
+
+// addHooked adds a built-in or plugin command between its hooks; add
+// appends the command itself.
+func (b *Builder) addHooked(pl *plan.Plan, name string, args []string, add func()) {
+	before, after := b.commandHooks(name)
+	params := map[string]string{"args": strings.Join(args, " ")}
+	pl.Ops = append(pl.Ops, b.hookOps(before, params)...)
+	add()
+	pl.Ops = append(pl.Ops, b.hookOps(after, params)...)
+}
@@ -0,0 +1,58 @@
+package builder
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"pm/internal/config"
+)
+
+// bindParams takes the command's declared params out of args, given as
+// --name=value or --name value, and returns them with defaults applied
+// together with the remaining args, which become @{args}.
+func bindParams(cmd config.CommandDef, args []string) (map[string]string, []string, error) {
+	params, rest, err := parseParams(cmd, args)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, k := range slices.Sorted(maps.Keys(cmd.Params)) {
+		p := cmd.Params[k]
+		v, ok := params[k]
+		if !ok && p.Required {
+			return nil, nil, fmt.Errorf("missing --%s", k)
+		}
+		if ok && len(p.Enum) > 0 && !slices.Contains(p.Enum, v) {
+			return nil, nil, fmt.Errorf("--%s must be one of %s, got %q", k, strings.Join(p.Enum, ", "), v)
+		}
+	}
+	return params, rest, nil
+}
+
+// parseParams is bindParams without checking required and allowed values.
+func parseParams(cmd config.CommandDef, args []string) (map[string]string, []string, error) {
+	params := map[string]string{}
+	for k, p := range cmd.Params {
+		if p.Default != "" {
+			params[k] = p.Default
+		}
+	}
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(args[i], "--"), "=")
+		if _, declared := cmd.Params[name]; !declared || !strings.HasPrefix(args[i], "--") {
+			rest = append(rest, args[i])
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, nil, fmt.Errorf("--%s needs a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		params[name] = value
+	}
+	return params, rest, nil
+}
@@ -0,0 +1,52 @@
+package builder
+
+import (
+	"slices"
+
+	"pm/internal/dsl"
+)
+
+// Watch returns the globs whose changes re-run tail: the watch: of every
+// typed command, else its inputs:, with those of its dependencies. It is
+// nil, every file of the project, when a command declares neither.
+func (b *Builder) Watch(tail []string) (patterns []string) {
+	all := false
+	seen := map[string]bool{}
+	var add func(name string, typed bool)
+	add = func(name string, typed bool) {
+		cmd, ok := b.Meta.Commands[name]
+		if !ok || seen[name] {
+			all = all || (!ok && typed)
+			return
+		}
+		seen[name] = true
+		switch {
+		case len(cmd.Watch) > 0:
+			patterns = append(patterns, cmd.Watch...)
+		case len(cmd.Inputs) > 0:
+			patterns = append(patterns, cmd.Inputs...)
+		case typed:
+			all = true
+		}
+		for _, d := range cmd.Deps {
+			add(d, false)
+		}
+	}
+	for _, ch := range dsl.SplitColonCommands(tail) {
+		if ch.Name == "__RAW__" {
+			all = true
+			continue
+		}
+		name, err := b.resolveCommand(ch.Name)
+		if err != nil {
+			all = true
+			continue
+		}
+		add(name, true)
+	}
+	if all {
+		return nil
+	}
+	slices.Sort(patterns)
+	return slices.Compact(patterns)
+}
@@ -0,0 +1,151 @@
+package builder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"pm/internal/config"
+	"pm/internal/dsl"
+	"pm/internal/plan"
+)
+
+// NewWorkspace returns the builder for a workspace's own commands and one
+// per member project in dependency order, all seeing the workspace's vars
+// and funcs on top of global.
+func NewWorkspace(name string, ws config.WorkspaceDef, global *config.GlobalConfig, pluginsDir string) (*Builder, []*Builder, error) {
+	projects, err := ws.Members()
+	if err != nil {
+		return nil, nil, err
+	}
+	global = global.WithWorkspace(name, ws)
+
+	root := ws.Root
+	if root == "" {
+		root, _ = os.Getwd()
+	}
+	root, _ = filepath.Abs(config.Expand(root))
+	self := &Builder{
+		Meta: &config.ProjectMeta{
+			Info:     config.ProjectInfo{Name: config.WorkspacePrefix + name, Description: ws.Description, Root: root},
+			Func:     ws.Func,
+			Commands: ws.Commands,
+		},
+		Root:       root,
+		Global:     global,
+		PluginsDir: pluginsDir,
+	}
+	var members []*Builder
+	for _, p := range projects {
+		meta, root, err := config.LoadEntry(p)
+		if err != nil {
+			return nil, nil, err
+		}
+		members = append(members, &Builder{Meta: meta, Root: root, Global: global, PluginsDir: pluginsDir, Env: p.Env})
+	}
+	return self, members, nil
+}
+
+// Workspace builds tail for a workspace. ws carries the workspace's own
+// commands (its Meta is made from the workspace definition) and members are
+// its projects in dependency order. A :command the workspace defines runs
+// once in ws.Root; any other runs in every member that has it, one after
+// another, so a failure stops the members that come later. Raw tails run in
+// every member. The global hooks wrap the whole plan, a member's own hooks
+// each of its runs.
+func Workspace(ws *Builder, members []*Builder, tail []string) *plan.Plan {
+	pl := plan.New()
+	pl.Pushd(ws.Root)
+	hooks := ws.planHooks()
+
+	// member returns what add runs in m between m's hooks, and m's
+	// failure hooks
+	member := func(m *Builder, add func(sub *plan.Plan)) (*plan.Plan, []plan.Op) {
+		body := plan.New()
+		add(body)
+		var h plan.Hooks
+		if plan.Runs(body.Ops) {
+			h = m.memberHooks()
+		}
+		sub := plan.New()
+		sub.Pushd(m.Root)
+		sub.Ops = append(sub.Ops, h.BeforeAll...)
+		sub.Ops = append(sub.Ops, body.Ops...)
+		sub.Ops = append(sub.Ops, h.AfterAll...)
+		sub.Popd()
+		return sub, h.OnFailure
+	}
+
+	chunks := dsl.SplitColonCommands(tail)
+	if len(chunks) == 1 && chunks[0].Name == "__RAW__" {
+		line := strings.Join(chunks[0].Args, " ")
+		if strings.TrimSpace(line) == "" {
+			return pl
+		}
+		for _, m := range members {
+			sub, onFailure := member(m, func(sub *plan.Plan) { sub.Run(line) })
+			if len(onFailure) == 0 && len(hooks.OnFailure) == 0 {
+				pl.Ops = append(pl.Ops, sub.Ops...)
+				continue
+			}
+			// a step, for the failure hooks to see it fail
+			pl.Ops = append(pl.Ops, plan.OpStep{Name: m.Meta.Info.Name + " " + line, Ops: sub.Ops, OnFailure: onFailure})
+		}
+		pl.Splice(hooks)
+		return pl
+	}
+
+	// every command that runs something is a timed step
+	steps := 0
+	step := func(name string, sub *plan.Plan, notify *plan.Notify, onFailure []plan.Op) {
+		if !plan.Runs(sub.Ops) {
+			pl.Ops = append(pl.Ops, sub.Ops...)
+			return
+		}
+		pl.Ops = append(pl.Ops, plan.OpStep{Name: name, Ops: sub.Ops, Notify: notify, OnFailure: onFailure})
+		steps++
+	}
+	for _, ch := range chunks {
+		typed := strings.TrimSpace(":" + ch.Name + " " + strings.Join(ch.Args, " "))
+		if ch.Name == "help" {
+			ws.help(pl, ch.Args)
+			names := make([]string, len(members))
+			for i, m := range members {
+				names[i] = m.Meta.Info.Name
+			}
+			pl.Echo("# pm: members, in order: " + strings.Join(names, ", "))
+			continue
+		}
+		if ws.hasOwn(ch.Name) {
+			sub := plan.New()
+			ws.addChunk(sub, ch, map[string]bool{})
+			step(typed, sub, ws.notify(ch.Name), nil)
+			continue
+		}
+		for _, m := range members {
+			if _, err := m.resolveCommand(ch.Name); err != nil {
+				pl.Echo(fmt.Sprintf("# pm: %s: no :%s, skipped", m.Meta.Info.Name, ch.Name))
+				continue
+			}
+			sub, onFailure := member(m, func(sub *plan.Plan) { m.addChunk(sub, ch, map[string]bool{}) })
+			step(m.Meta.Info.Name+" "+typed, sub, m.notify(ch.Name), onFailure)
+		}
+	}
+	if steps > 1 {
+		pl.Timings()
+	}
+	pl.Splice(hooks)
+	return pl
+}
+
+// hasOwn reports whether name is one of b's own commands or their aliases.
+func (b *Builder) hasOwn(name string) bool {
+	for k, c := range b.Meta.Commands {
+		if k == name || slices.Contains(c.Aliases, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,181 @@
+// Package cache remembers the hash of a command's inputs: after it ran
+// successfully with them pm leaves the command out of the plan until they
+// change. The hash of an invocation is only pending until the wrapper (or
+// pm run) reports its status; a failed run stores the hashes of the steps
+// that finished before it failed, which the script counts in DoneVar.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"pm/internal/fileset"
+)
+
+// Dir is the cache directory: PM_CACHE, default ~/.cache/pm.
+func Dir() string {
+	if v := os.Getenv("PM_CACHE"); v != "" {
+		return v
+	}
+	if d, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(d, "pm")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".cache", "pm")
+}
+
+// DoneVar is exported by the script after every step with stamps: the
+// number of pending stamps whose steps finished.
+const DoneVar = "PM_STEPS_DONE"
+
+// DoneFile names a file the nu script also writes DoneVar to: the nu
+// wrapper runs it in a child nu, whose environment it cannot read back.
+const DoneFile = "PM_STEPS_FILE"
+
+// Stamp is the hash a project's command ran with.
+type Stamp struct {
+	Project string `json:"project"`
+	Command string `json:"command"`
+	Hash    string `json:"hash"`
+}
+
+func (s Stamp) file() string {
+	return filepath.Join(Dir(), safeName(s.Project), safeName(s.Command))
+}
+
+// safeName keeps a name usable as a single path element.
+func safeName(s string) string {
+	return strings.NewReplacer("/", "_", `\`, "_", ":", "_").Replace(s)
+}
+
+// Cache collects the stamps of one invocation.
+type Cache struct {
+	// Force runs every command as if its inputs had changed.
+	Force bool
+	// Pending are the stamps of the commands in the plan, stored once it
+	// succeeds.
+	Pending []Stamp
+}
+
+// Fresh reports whether the command last ran successfully with s.Hash.
+func (c *Cache) Fresh(s Stamp) bool {
+	if c.Force {
+		return false
+	}
+	b, err := os.ReadFile(s.file())
+	return err == nil && strings.TrimSpace(string(b)) == s.Hash
+}
+
+// Add makes s pending.
+func (c *Cache) Add(s Stamp) { c.Pending = append(c.Pending, s) }
+
+// Hash hashes the files matching inputs under root, by path and content,
+// with extra (the command's lines and params): changing any of them
+// changes the hash.
+func Hash(root string, inputs, extra []string) (string, error) {
+	files, err := fileset.Glob(root, inputs)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, e := range extra {
+		fmt.Fprintf(h, "%q\n", e)
+	}
+	for _, f := range files {
+		fh, err := hashFile(filepath.Join(root, filepath.FromSlash(f)))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%q %x\n", f, fh)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// Exist reports whether every pattern matches at least one file under
+// root: outputs deleted since the last run make a command stale.
+func Exist(root string, patterns []string) bool {
+	for _, p := range patterns {
+		files, err := fileset.Glob(root, []string{p})
+		if err != nil || len(files) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func pendingFile(shell int) string {
+	return filepath.Join(Dir(), "pending", strconv.Itoa(shell)+".json")
+}
+
+// SavePending keeps c's pending stamps for the status of the script shell
+// runs.
+func (c *Cache) SavePending(shell int) error {
+	path := pendingFile(shell)
+	if len(c.Pending) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	b, err := json.Marshal(c.Pending)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o644)
+}
+
+// Commit stores the stamps pending for shell: all of them when its script
+// exited with status 0, else the first done.
+func Commit(shell, status, done int) error {
+	path := pendingFile(shell)
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	var stamps []Stamp
+	if err := json.Unmarshal(b, &stamps); err != nil {
+		return err
+	}
+	if status != 0 {
+		stamps = stamps[:min(max(done, 0), len(stamps))]
+	}
+	for _, s := range stamps {
+		if err := os.MkdirAll(filepath.Dir(s.file()), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(s.file(), []byte(s.Hash+"\n"), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a.go", "package a")
+	write("notes.txt", "x")
+	hash := func(extra ...string) string {
+		t.Helper()
+		h, err := Hash(root, []string{"*.go"}, extra)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+
+	h := hash("go build")
+	write("notes.txt", "y")
+	if hash("go build") != h {
+		t.Fatal("a file outside the inputs changed the hash")
+	}
+	if hash("go build -race") == h {
+		t.Fatal("other command lines kept the hash")
+	}
+	write("a.go", "package b")
+	if hash("go build") == h {
+		t.Fatal("a changed input kept the hash")
+	}
+	if !Exist(root, []string{"*.go", "notes.txt"}) || Exist(root, []string{"*.go", "bin/*"}) {
+		t.Fatal("Exist wants every pattern to match")
+	}
+}
+
+// TestCommit: stamps are stored only when the script succeeded, and
+// --force ignores them.
+func TestCommit(t *testing.T) {
+	t.Setenv("PM_CACHE", t.TempDir())
+	s := Stamp{Project: "api", Command: "build", Hash: "abc"}
+
+	c := &Cache{}
+	c.Add(s)
+	if err := c.SavePending(42); err != nil {
+		t.Fatal(err)
+	}
+	if err := Commit(42, 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if c.Fresh(s) {
+		t.Fatal("a failed run stored its stamp")
+	}
+
+	if err := c.SavePending(42); err != nil {
+		t.Fatal(err)
+	}
+	if err := Commit(7, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if c.Fresh(s) {
+		t.Fatal("another shell's status stored the stamp")
+	}
+	if err := Commit(42, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if !c.Fresh(s) {
+		t.Fatal("a successful run did not store its stamp")
+	}
+	if c.Fresh(Stamp{Project: "api", Command: "build", Hash: "def"}) {
+		t.Fatal("changed inputs are fresh")
+	}
+	if (&Cache{Force: true}).Fresh(s) {
+		t.Fatal("Force kept the command out")
+	}
+}
+
+// TestCommit_Done: a failed script stores the stamps of the steps that
+// finished before it failed.
+func TestCommit_Done(t *testing.T) {
+	t.Setenv("PM_CACHE", t.TempDir())
+	codegen := Stamp{Project: "api", Command: "codegen", Hash: "abc"}
+	build := Stamp{Project: "api", Command: "build", Hash: "def"}
+
+	c := &Cache{}
+	c.Add(codegen)
+	c.Add(build)
+	if err := c.SavePending(42); err != nil {
+		t.Fatal(err)
+	}
+	if err := Commit(42, 2, 1); err != nil {
+		t.Fatal(err)
+	}
+	if !c.Fresh(codegen) || c.Fresh(build) {
+		t.Fatal("want only the finished step's stamp stored")
+	}
+}
@@ -0,0 +1,414 @@
+// Package complete computes shell completion candidates for pm and emits
+// the thin per-shell scripts that ask pm-bin for them.
+package complete
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"pm/internal/builder"
+	"pm/internal/config"
+	"pm/internal/docker"
+	"pm/internal/export"
+	"pm/internal/match"
+)
+
+// Candidate is a completion with an optional description.
+type Candidate struct {
+	Value       string
+	Description string
+}
+
+// Subcommands are the words pm handles itself instead of a project.
+var Subcommands = []Candidate{
+	{"add", "register a project"},
+	{"rm", "unregister a project"},
+	{"ls", "list projects"},
+	{"show", "describe a project"},
+	{"which", "print a project's root"},
+	{"export", "write a Makefile, justfile or Taskfile.yml"},
+	{"set", "change a project's settings"},
+	{"init", "create .pm.meta.yml"},
+	{"scan", "register every project under a directory"},
+	{"prune", "drop projects whose meta file is gone"},
+	{"workspaces", "list workspaces"},
+	{"registry", "registry maintenance"},
+	{"plugins", "list plugins"},
+	{"completion", "print a shell completion script"},
+	{"history", "list earlier invocations"},
+	{"recent", "list recent distinct invocations"},
+	{"again", "run an earlier invocation again"},
+	{"run", "execute a command without the wrapper"},
+}
+
+// Complete returns the candidates for the last of words, the arguments
+// typed after pm; the last one is the word being completed and may be
+// empty. No candidates means the shell should complete file names.
+func Complete(words []string, pluginsDir string) []Candidate {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	cur, prev := words[len(words)-1], words[:len(words)-1]
+	var out []Candidate
+	switch {
+	case len(prev) == 0:
+		out = first(cur, pluginsDir)
+	case prev[0] == "--force":
+		return Complete(append(slices.Clone(prev[1:]), cur), pluginsDir)
+	case prev[0] == "run":
+		// pm run [--log FILE] [--force] PROJECT ... completes like pm PROJECT ...
+		rest := prev[1:]
+		if len(rest) > 0 && rest[0] == "--log" {
+			if len(rest) == 1 {
+				return nil
+			}
+			rest = rest[2:]
+		}
+		return Complete(append(slices.Clone(rest), cur), pluginsDir)
+	case slices.ContainsFunc(Subcommands, func(c Candidate) bool { return c.Value == prev[0] }):
+		out = subcommand(prev)
+	default:
+		out = project(prev, cur, pluginsDir)
+	}
+	return filter(out, cur)
+}
+
+func filter(cands []Candidate, prefix string) []Candidate {
+	var out []Candidate
+	seen := map[string]bool{}
+	for _, c := range cands {
+		if strings.HasPrefix(c.Value, prefix) && !seen[c.Value] {
+			seen[c.Value] = true
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func values(vs ...string) []Candidate {
+	out := make([]Candidate, len(vs))
+	for i, v := range vs {
+		out[i] = Candidate{Value: v}
+	}
+	return out
+}
+
+// first completes the word after pm: subcommands, projects, workspaces and
+// tags, or the commands of the current directory's project.
+func first(cur, pluginsDir string) []Candidate {
+	if strings.HasPrefix(cur, ":") {
+		meta, root, err := config.ResolveCwd()
+		if err != nil {
+			return nil
+		}
+		return commands(newTarget(meta, root, pluginsDir))
+	}
+	out := slices.Clone(Subcommands)
+	out = append(out, projects()...)
+	if all, err := config.LoadWorkspaces(); err == nil {
+		for _, n := range slices.Sorted(maps.Keys(all)) {
+			out = append(out, Candidate{config.WorkspacePrefix + n, all[n].Description})
+		}
+	}
+	for _, t := range tags() {
+		out = append(out, Candidate{"@" + t, "projects tagged " + t})
+	}
+	return out
+}
+
+func projects() []Candidate {
+	ps, err := config.ListProjects(config.LsOptions{})
+	if err != nil {
+		return nil
+	}
+	var out []Candidate
+	for _, p := range ps {
+		out = append(out, Candidate{p.Name, p.Description})
+		for _, a := range p.Aliases {
+			out = append(out, Candidate{a, p.Description})
+		}
+	}
+	return out
+}
+
+func tags() []string {
+	ps, _ := config.ListProjects(config.LsOptions{})
+	var out []string
+	for _, p := range ps {
+		for _, t := range p.Tags {
+			if !slices.Contains(out, t) {
+				out = append(out, t)
+			}
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+// subcommand completes the arguments of pm's own commands.
+func subcommand(prev []string) []Candidate {
+	last := prev[len(prev)-1]
+	switch prev[0] {
+	case "show":
+		if len(prev) == 1 {
+			return projects()
+		}
+		if last == "--format" {
+			return values("text", "json", "tsv")
+		}
+		return []Candidate{{"--json", "JSON output"}, {"--format", "text|json|tsv"}}
+	case "which":
+		if len(prev) == 1 {
+			return projects()
+		}
+		return []Candidate{{"--meta", "print the meta file"}}
+	case "export":
+		if len(prev) == 1 {
+			return projects()
+		}
+		if last == "--to" {
+			return values(export.Formats...)
+		}
+		return []Candidate{{"--to", strings.Join(export.Formats, "|")}, {"-o", "output file, - for stdout"}, {"--force", "overwrite an existing file"}}
+	case "rm":
+		if len(prev) == 1 {
+			return projects()
+		}
+	case "set":
+		if len(prev) == 1 {
+			return projects()
+		}
+		return []Candidate{
+			{"pinned=true", "list first"}, {"pinned=false", "unpin"},
+			{"dialect=", "dialect without --dialect"}, {"env.", "env.NAME=VALUE exported before commands"},
+		}
+	case "ls":
+		switch last {
+		case "--format":
+			return values(config.LsFormats...)
+		case "--tag":
+			return values(tags()...)
+		}
+		return []Candidate{
+			{"--tag", "only projects with a tag"}, {"--recent", "most recently used first"},
+			{"--format", strings.Join(config.LsFormats, "|")}, {"--json", "JSON output"},
+		}
+	case "registry":
+		if len(prev) == 1 {
+			return []Candidate{{"repair", "recover an unreadable registry"}}
+		}
+	case "plugins":
+		if len(prev) == 1 {
+			return []Candidate{{"ls", "list plugins"}}
+		}
+	case "completion":
+		if len(prev) == 1 {
+			return values(Shells...)
+		}
+	case "history", "recent":
+		if last == "-n" {
+			return nil
+		}
+		out := []Candidate{{"-n", "number of entries"}, {"--json", "JSON output"}}
+		if len(prev) == 1 {
+			out = append(projects(), out...)
+		}
+		return out
+	case "again":
+		if len(prev) == 1 {
+			h, _ := config.LoadHistory()
+			var out []Candidate
+			for n := 1; n <= min(len(h), 9); n++ {
+				e, _ := h.Back(n)
+				out = append(out, Candidate{strconv.Itoa(n), e.String()})
+			}
+			return out
+		}
+	}
+	return nil
+}
+
+// target is a project being completed: its description and compose file.
+type target struct {
+	builder.Description
+	compose string
+}
+
+// newTarget describes the project, its most used commands first.
+func newTarget(meta *config.ProjectMeta, root, pluginsDir string) target {
+	b := &builder.Builder{Meta: meta, Root: root, PluginsDir: pluginsDir}
+	d := b.Describe()
+	if h, err := config.LoadHistory(); err == nil {
+		d.SortByUse(h.Commands(d.Name))
+	}
+	return target{d, docker.ComposeFile(meta, root)}
+}
+
+// project completes the words after a project reference: :commands,
+// @groups and services for :up, and --params of the current command.
+func project(prev []string, cur, pluginsDir string) []Candidate {
+	ts, tail := resolve(prev, pluginsDir)
+	if len(ts) == 0 {
+		return nil
+	}
+	if strings.HasPrefix(cur, ":") || (cur == "" && len(tail) == 0) {
+		var out []Candidate
+		for _, t := range ts {
+			out = append(out, commands(t)...)
+		}
+		return out
+	}
+	if len(ts) != 1 {
+		return nil
+	}
+	t := ts[0]
+	cmd := current(t, tail)
+	switch {
+	case strings.HasPrefix(cur, "@"):
+		return groups(t)
+	case cmd == "up":
+		return append(groups(t), services(t)...)
+	}
+	return params(t, cmd, tail, cur)
+}
+
+// resolve loads the project, workspace or selection prev starts with and
+// returns the words after it.
+func resolve(prev []string, pluginsDir string) ([]target, []string) {
+	ref, tail := prev[0], prev[1:]
+	switch {
+	case strings.HasPrefix(ref, ":"):
+		meta, root, err := config.ResolveCwd()
+		if err != nil {
+			return nil, nil
+		}
+		return []target{newTarget(meta, root, pluginsDir)}, prev
+	case strings.HasPrefix(ref, config.WorkspacePrefix):
+		name := strings.TrimPrefix(ref, config.WorkspacePrefix)
+		ws, err := config.LoadWorkspace(name)
+		if err != nil {
+			return nil, nil
+		}
+		self, members, err := builder.NewWorkspace(name, ws, nil, pluginsDir)
+		if err != nil {
+			return nil, nil
+		}
+		ts := []target{{Description: self.Describe()}}
+		for _, m := range members {
+			ts = append(ts, newTarget(m.Meta, m.Root, pluginsDir))
+		}
+		return ts, tail
+	case config.IsSelection(ref):
+		ps, err := config.SelectProjects(ref)
+		if err != nil {
+			return nil, nil
+		}
+		var ts []target
+		for _, p := range ps {
+			if meta, root, err := config.LoadEntry(p); err == nil {
+				ts = append(ts, newTarget(meta, root, pluginsDir))
+			}
+		}
+		return ts, tail
+	}
+	meta, root, err := config.ResolveProject(ref)
+	if err != nil {
+		return nil, nil
+	}
+	return []target{newTarget(meta, root, pluginsDir)}, tail
+}
+
+func commands(t target) []Candidate {
+	var out []Candidate
+	for _, c := range t.Commands {
+		out = append(out, Candidate{":" + c.Name, c.Description})
+		for _, a := range c.Aliases {
+			out = append(out, Candidate{":" + a, c.Description})
+		}
+	}
+	return out
+}
+
+func groups(t target) []Candidate {
+	if t.Docker == nil {
+		return nil
+	}
+	var out []Candidate
+	for _, g := range t.Docker.Groups {
+		out = append(out, Candidate{"@" + g.Name, strings.Join(g.Services, " ")})
+	}
+	return out
+}
+
+func services(t target) []Candidate {
+	names, err := docker.Services(t.compose)
+	if err != nil {
+		return nil
+	}
+	out := values(names...)
+	for i := range out {
+		out[i].Description = "service"
+	}
+	return out
+}
+
+// current returns the resolved name of the last :command in tail.
+func current(t target, tail []string) string {
+	for i := len(tail) - 1; i >= 0; i-- {
+		if !strings.HasPrefix(tail[i], ":") {
+			continue
+		}
+		var cands []match.Candidate
+		for _, c := range t.Commands {
+			cands = append(cands, match.Candidate{Name: c.Name, Aliases: c.Aliases})
+		}
+		name, _ := match.Find(strings.TrimPrefix(tail[i], ":"), cands)
+		return name
+	}
+	return ""
+}
+
+// params completes --name= for the command's declared params, and their
+// allowed values after --name= or --name.
+func params(t target, cmd string, tail []string, cur string) []Candidate {
+	i := slices.IndexFunc(t.Commands, func(c builder.CommandInfo) bool { return c.Name == cmd })
+	if i < 0 {
+		return nil
+	}
+	ps := t.Commands[i].Params
+	find := func(flag string) *builder.ParamInfo {
+		name, ok := strings.CutPrefix(flag, "--")
+		if !ok {
+			return nil
+		}
+		for j := range ps {
+			if ps[j].Name == name {
+				return &ps[j]
+			}
+		}
+		return nil
+	}
+	// --env <TAB>
+	if len(tail) > 0 {
+		if p := find(tail[len(tail)-1]); p != nil {
+			return values(p.Enum...)
+		}
+	}
+	// --env=<TAB>
+	if flag, _, ok := strings.Cut(cur, "="); ok {
+		var out []Candidate
+		if p := find(flag); p != nil {
+			for _, v := range p.Enum {
+				out = append(out, Candidate{flag + "=" + v, ""})
+			}
+		}
+		return out
+	}
+	var out []Candidate
+	for _, p := range ps {
+		out = append(out, Candidate{"--" + p.Name + "=", p.Description})
+	}
+	return out
+}
@@ -0,0 +1,118 @@
+package complete
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"pm/internal/config"
+)
+
+const meta = `info:
+  name: api
+  description: REST API
+  root: .
+  aliases: [backend-api]
+  tags: [backend]
+commands:
+  build:
+    description: Build
+    aliases: [b]
+    cmd: ./gradlew build @{args}
+  deploy:
+    description: Deploy
+    params:
+      env: {required: true, enum: [dev, prod], description: target}
+    cmd: ./deploy @{env}
+docker:
+  compose_file: dc.yml
+  groups:
+    base: [pg, redis]
+`
+
+func setup(t *testing.T) {
+	t.Helper()
+	td := t.TempDir()
+	t.Setenv("PM_CONFIGS", filepath.Join(td, "cfg"))
+	dir := filepath.Join(td, "api")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(dir, config.MetaFileName)
+	if err := os.WriteFile(p, []byte(meta), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "dc.yml"), []byte("services:\n  pg: {image: pg}\n  web: {build: .}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.RegAdd(p); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func joined(cands []Candidate) string {
+	var out []string
+	for _, c := range cands {
+		out = append(out, c.Value)
+	}
+	return strings.Join(out, " ")
+}
+
+func TestComplete(t *testing.T) {
+	setup(t)
+	cases := []struct {
+		words []string
+		want  string
+	}{
+		{[]string{"a"}, "add again api"},
+		{[]string{"b"}, "backend-api"},
+		{[]string{"@"}, "@backend"},
+		{[]string{"api", ""}, ":build :b :deploy :help :up"},
+		{[]string{"api", ":d"}, ":deploy"},
+		{[]string{"run", "--log", "f", "api", ":d"}, ":deploy"},
+		{[]string{"--force", "api", ":d"}, ":deploy"},
+		{[]string{"run", "--force", "api", ":d"}, ":deploy"},
+		{[]string{"backend-api", ":b"}, ":build :b"},
+		{[]string{"api", ":up", ""}, "@base pg web"},
+		{[]string{"api", ":up", "@"}, "@base"},
+		{[]string{"api", ":dep", "--"}, "--env="},
+		{[]string{"api", ":deploy", "--env="}, "--env=dev --env=prod"},
+		{[]string{"api", ":deploy", "--env", "p"}, "prod"},
+		{[]string{"api", ":build", ""}, ""},
+		{[]string{"api", "docker"}, ""},
+		{[]string{"nope", ""}, ""},
+		{[]string{"@backend", ":"}, ":build :b :deploy :help :up"},
+		{[]string{"ls", "--format", "t"}, "text table tsv"},
+		{[]string{"show", ""}, "api backend-api"},
+		{[]string{"completion", ""}, "bash zsh fish pwsh"},
+	}
+	for _, c := range cases {
+		if got := joined(Complete(c.words, "")); got != c.want {
+			t.Errorf("Complete(%q) = %q, want %q", c.words, got, c.want)
+		}
+	}
+	if got := Complete([]string{"api", ":b"}, ""); got[0].Description != "Build" {
+		t.Errorf("missing description: %+v", got)
+	}
+}
+
+// The completion files in shell/ are the generated scripts.
+func TestScript_ShellFiles(t *testing.T) {
+	for _, sh := range []string{"bash", "zsh"} {
+		want, err := Script(sh)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(filepath.Join("..", "..", "shell", "pm-completion."+sh))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("shell/pm-completion.%s is stale: pm-bin completion %s > shell/pm-completion.%s", sh, sh, sh)
+		}
+	}
+	if _, err := Script("tcsh"); err == nil {
+		t.Error("expected an error for an unknown shell")
+	}
+}
@@ -0,0 +1,100 @@
+package complete
+
+import "fmt"
+
+// Shells are the shells `pm completion` emits scripts for.
+var Shells = []string{"bash", "zsh", "fish", "pwsh"}
+
+// Each script passes the words typed after pm to `pm __complete` and reads
+// back "value<TAB>description" lines; no lines means complete file names.
+var scripts = map[string]string{
+	"bash": `# bash completion for pm, generated by: pm completion bash
+# source it from ~/.bashrc: source <(pm completion bash)
+
+_pm_complete() {
+  local line=${COMP_LINE:0:COMP_POINT} words out c strip
+  read -ra words <<<"$line"
+  [[ $line == *[[:space:]] ]] && words+=("")
+  out=$(pm __complete "${words[@]:1}" 2>/dev/null)
+  COMPREPLY=()
+  # pm ranks the candidates (recently used first), keep its order
+  compopt -o nosort 2>/dev/null
+  if [[ -z $out ]]; then
+    COMPREPLY=($(compgen -f -- "${COMP_WORDS[COMP_CWORD]}"))
+    return 0
+  fi
+  # bash splits words at : and =, so drop what it already considers typed
+  strip=${words[${#words[@]}-1]}
+  strip=${strip%"${COMP_WORDS[COMP_CWORD]}"}
+  while IFS=$'\t' read -r c _; do
+    COMPREPLY+=("${c#"$strip"}")
+    [[ $c == *[=.] ]] && compopt -o nospace 2>/dev/null
+  done <<<"$out"
+  return 0
+}
+
+complete -F _pm_complete pm
+`,
+	"zsh": `#compdef pm
+# zsh completion for pm, generated by: pm completion zsh
+# put it on $fpath as _pm, or source it from ~/.zshrc: source <(pm completion zsh)
+
+_pm() {
+  local line
+  local -a cands
+  for line in ${(f)"$(pm __complete "${(@)words[2,CURRENT]}" 2>/dev/null)"}; do
+    cands+=("${${line%%$'\t'*}//:/\\:}:${line#*$'\t'}")
+  done
+  if (( ${#cands} )); then
+    _describe 'pm' cands
+  else
+    _files
+  fi
+}
+
+if [[ $zsh_eval_context[-1] == loadautofunc ]]; then
+  _pm "$@"
+else
+  compdef _pm pm
+fi
+`,
+	"fish": `# fish completion for pm, generated by: pm completion fish
+# save it as ~/.config/fish/completions/pm.fish: pm completion fish > ~/.config/fish/completions/pm.fish
+
+function __pm_complete
+    set -l words (commandline -opc)
+    set -e words[1]
+    set -l cur (commandline -ct)
+    pm __complete $words "$cur" 2>/dev/null
+end
+
+complete -c pm -e
+complete -c pm -f -k -a '(__pm_complete)'
+complete -c pm -F -n 'not count (__pm_complete) >/dev/null'
+`,
+	"pwsh": `# PowerShell completion for pm, generated by: pm completion pwsh
+# add it to $PROFILE: pm completion pwsh | Out-String | Invoke-Expression
+
+Register-ArgumentCompleter -Native -CommandName pm -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+    $words = @($commandAst.CommandElements | Select-Object -Skip 1 | ForEach-Object { $_.ToString() })
+    if ($wordToComplete -eq '') { $words += '' }
+    $results = @(& pm __complete @words 2>$null | ForEach-Object {
+        $value, $desc = $_ -split "` + "`" + `t", 2
+        if (-not $desc) { $desc = $value }
+        [System.Management.Automation.CompletionResult]::new($value, $value, 'ParameterValue', $desc)
+    })
+    if ($results.Count -eq 0) { return $null }
+    $results
+}
+`,
+}
+
+// Script returns the completion script for shell.
+func Script(shell string) (string, error) {
+	s, ok := scripts[shell]
+	if !ok {
+		return "", fmt.Errorf("no completion for %s (bash|zsh|fish|pwsh)", shell)
+	}
+	return s, nil
+}
@@ -0,0 +1,187 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// The history is every invocation pm built a script for, one JSON object
+// per line, oldest first. Only the latest historyLimit entries are kept.
+const historyLimit = 5000
+
+func historyFile() string     { return filepath.Join(Home(), "history") }
+func historyLockFile() string { return historyFile() + ".lock" }
+
+// HistoryEntry is one recorded invocation.
+type HistoryEntry struct {
+	Time time.Time `json:"time"`
+	// Project is the project's name, or the selection or ws:NAME used.
+	Project string `json:"project"`
+	// Ref resolves the project again from any directory: the name, the
+	// selection, or the root of an unregistered project.
+	Ref  string   `json:"ref"`
+	Args []string `json:"args,omitempty"`
+	// Projects are the projects a selection ran in.
+	Projects []string `json:"projects,omitempty"`
+	Cwd      string   `json:"cwd,omitempty"`
+	// Status is the exit status the wrapper reported, nil until then.
+	Status *int `json:"status,omitempty"`
+	// Shell is the pid of the process that ran pm-bin; the wrapper's
+	// status report comes from the same process.
+	Shell int `json:"shell,omitempty"`
+}
+
+// Command returns the pm-bin arguments that run the entry again.
+func (e HistoryEntry) Command() []string {
+	return append([]string{e.Ref}, e.Args...)
+}
+
+// String is the invocation as typed: project and arguments.
+func (e HistoryEntry) String() string {
+	return strings.TrimSpace(e.Project + " " + strings.Join(e.Args, " "))
+}
+
+// History is the recorded invocations, oldest first.
+type History []HistoryEntry
+
+// LoadHistory reads the history; a missing file is an empty history and
+// unreadable lines are skipped.
+func LoadHistory() (History, error) {
+	b, err := os.ReadFile(historyFile())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var h History
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		var e HistoryEntry
+		if json.Unmarshal(sc.Bytes(), &e) == nil && e.Ref != "" {
+			h = append(h, e)
+		}
+	}
+	return h, sc.Err()
+}
+
+// RecordHistory appends e, stamped with the current time.
+func RecordHistory(e HistoryEntry) error {
+	unlock, err := lockPath(historyLockFile(), "history")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	e.Time = now()
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(historyFile(), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// SetHistoryStatus records status on the latest entry shell ran, unless
+// it already has one, and trims the history to its limit.
+func SetHistoryStatus(shell, status int) error {
+	unlock, err := lockPath(historyLockFile(), "history")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	h, err := LoadHistory()
+	if err != nil {
+		return err
+	}
+	i := len(h) - 1
+	for i >= 0 && h[i].Shell != shell {
+		i--
+	}
+	if i < 0 || h[i].Status != nil {
+		return nil
+	}
+	h[i].Status = &status
+	h = h[max(len(h)-historyLimit, 0):]
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range h {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return writeAtomic(historyFile(), buf.Bytes())
+}
+
+// Back returns the nth invocation counting back from the latest, 1.
+func (h History) Back(n int) (HistoryEntry, error) {
+	if n < 1 || n > len(h) {
+		if len(h) == 0 {
+			return HistoryEntry{}, fmt.Errorf("history is empty")
+		}
+		return HistoryEntry{}, fmt.Errorf("no history entry %d (1..%d)", n, len(h))
+	}
+	return h[len(h)-n], nil
+}
+
+// Recent returns up to n distinct invocations, latest first; with a
+// project only its own.
+func (h History) Recent(project string, n int) History {
+	var out History
+	seen := map[string]bool{}
+	for _, e := range slices.Backward(h) {
+		if len(out) == n {
+			break
+		}
+		if (project != "" && e.Project != project) || seen[e.String()] {
+			continue
+		}
+		seen[e.String()] = true
+		out = append(out, e)
+	}
+	return out
+}
+
+// LastUsed returns when each project was last run, alone or as part of a
+// selection.
+func (h History) LastUsed() map[string]time.Time {
+	out := map[string]time.Time{}
+	for _, e := range h {
+		for _, p := range append([]string{e.Project}, e.Projects...) {
+			if e.Time.After(out[p]) {
+				out[p] = e.Time
+			}
+		}
+	}
+	return out
+}
+
+// Commands returns the :command names run in project, most recent first.
+func (h History) Commands(project string) []string {
+	var out []string
+	for _, e := range slices.Backward(h) {
+		if e.Project != project {
+			continue
+		}
+		for _, a := range e.Args {
+			if name, ok := strings.CutPrefix(a, ":"); ok && name != "" && !slices.Contains(out, name) {
+				out = append(out, name)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	t.Setenv("PM_CONFIGS", t.TempDir())
+	for _, e := range []HistoryEntry{
+		{Project: "api", Ref: "api", Args: []string{":build"}, Shell: 10},
+		{Project: "web", Ref: "web", Args: []string{":test"}, Shell: 20},
+		{Project: "api", Ref: "api", Args: []string{":test", ":build"}, Shell: 10},
+		{Project: "api", Ref: "api", Args: []string{":build"}, Shell: 20},
+	} {
+		if err := RecordHistory(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the status goes to shell 10's latest entry
+	if err := SetHistoryStatus(10, 2); err != nil {
+		t.Fatal(err)
+	}
+	h, err := LoadHistory()
+	if err != nil || len(h) != 4 {
+		t.Fatalf("LoadHistory = %d entries, %v", len(h), err)
+	}
+	if h[2].Status == nil || *h[2].Status != 2 || h[0].Status != nil || h[3].Status != nil {
+		t.Errorf("status landed on the wrong entry: %+v", h)
+	}
+
+	if e, err := h.Back(1); err != nil || e.String() != "api :build" {
+		t.Errorf("Back(1) = %q, %v", e, err)
+	}
+	if e, err := h.Back(3); err != nil || e.String() != "web :test" {
+		t.Errorf("Back(3) = %q, %v", e, err)
+	}
+	if _, err := h.Back(5); err == nil {
+		t.Error("Back(5) should fail with 4 entries")
+	}
+
+	var recent []string
+	for _, e := range h.Recent("", 10) {
+		recent = append(recent, e.String())
+	}
+	if want := []string{"api :build", "api :test :build", "web :test"}; !reflect.DeepEqual(recent, want) {
+		t.Errorf("Recent = %q, want %q", recent, want)
+	}
+	if got := h.Commands("api"); !reflect.DeepEqual(got, []string{"build", "test"}) {
+		t.Errorf("Commands = %q", got)
+	}
+}
+
+func TestHistory_SkipsBadLines(t *testing.T) {
+	t.Setenv("PM_CONFIGS", t.TempDir())
+	if err := RecordHistory(HistoryEntry{Project: "api", Ref: "api"}); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(historyFile(), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("{not json\n")
+	f.Close()
+	h, err := LoadHistory()
+	if err != nil || len(h) != 1 {
+		t.Fatalf("LoadHistory = %v, %v", h, err)
+	}
+	b, _ := os.ReadFile(historyFile())
+	if !strings.Contains(string(b), `"ref":"api"`) {
+		t.Errorf("unexpected history file:\n%s", b)
+	}
+}
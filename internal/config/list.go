@@ -0,0 +1,129 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LsFormats are the output formats of pm ls.
+var LsFormats = []string{"text", "table", "json", "yaml", "tsv"}
+
+// LsOptions filter, order and format pm ls.
+type LsOptions struct {
+	// only projects with this tag
+	Tag string
+	// most recently used first instead of registration order
+	Recent bool
+	// one of LsFormats; empty means text
+	Format string
+}
+
+// ListProjects returns the registered projects matching opts, pinned
+// projects first, with LastUsed taken from the history.
+func ListProjects(opts LsOptions) ([]RegProject, error) {
+	reg, err := loadRegistry()
+	if err != nil {
+		return nil, err
+	}
+	h, _ := LoadHistory()
+	used := h.LastUsed()
+	var out []RegProject
+	for _, p := range reg.Projects {
+		if opts.Tag == "" || slices.Contains(p.Tags, opts.Tag) {
+			p.LastUsed = used[p.Name]
+			out = append(out, p)
+		}
+	}
+	slices.SortStableFunc(out, func(a, b RegProject) int {
+		if a.Pinned != b.Pinned {
+			if a.Pinned {
+				return -1
+			}
+			return 1
+		}
+		if opts.Recent {
+			return b.LastUsed.Compare(a.LastUsed)
+		}
+		return 0
+	})
+	return out, nil
+}
+
+// RegLs writes the projects matching opts to w in opts.Format.
+func RegLs(w io.Writer, opts LsOptions) error {
+	ps, err := ListProjects(opts)
+	if err != nil {
+		return err
+	}
+	if ps == nil {
+		ps = []RegProject{}
+	}
+	switch opts.Format {
+	case "json":
+		b, err := json.MarshalIndent(ps, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", b)
+		return err
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		return enc.Encode(ps)
+	case "tsv":
+		// name<TAB>description, for completion scripts
+		for _, p := range ps {
+			fmt.Fprintf(w, "%s\t%s\n", p.Name, p.Description)
+		}
+		return nil
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tTAGS\tLAST USED\tDESCRIPTION")
+		for _, p := range ps {
+			name := p.Name
+			if p.Pinned {
+				name += " *"
+			}
+			used := "-"
+			if !p.LastUsed.IsZero() {
+				used = p.LastUsed.Local().Format("2006-01-02 15:04")
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", name, strings.Join(p.Tags, ","), used, p.Description)
+		}
+		return tw.Flush()
+	case "", "text":
+	default:
+		return fmt.Errorf("unknown format %q (%s)", opts.Format, strings.Join(LsFormats, "|"))
+	}
+
+	if len(ps) == 0 {
+		if opts.Tag != "" {
+			fmt.Fprintf(w, "# pm: no projects tagged %s\n", opts.Tag)
+			return nil
+		}
+		fmt.Fprintln(w, "# pm: empty. Use: pm add /path/to/.pm.meta.yml")
+		return nil
+	}
+	fmt.Fprint(w, "# pm: projects\n\n")
+	for _, p := range ps {
+		pin := ""
+		if p.Pinned {
+			pin = " (pinned)"
+		}
+		fmt.Fprintf(w, "- %s%s\n", p.Name, pin)
+		if p.Description != "" {
+			fmt.Fprintf(w, "  description: %s\n", p.Description)
+		}
+		fmt.Fprintf(w, "  meta: %s\n  root: %s\n", p.Meta, p.Root)
+		if len(p.Tags) > 0 {
+			fmt.Fprintf(w, "  tags: %s\n", strings.Join(p.Tags, ", "))
+		}
+	}
+	return nil
+}
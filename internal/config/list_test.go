@@ -0,0 +1,203 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func names(ps []RegProject) string {
+	var out []string
+	for _, p := range ps {
+		out = append(out, p.Name)
+	}
+	return strings.Join(out, ",")
+}
+
+func TestRegistry_MigrateV1(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("PM_CONFIGS", td)
+	dir := filepath.Join(td, "api")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	meta := filepath.Join(dir, MetaFileName)
+	if err := os.WriteFile(meta, []byte("info:\n  name: api\n  description: REST API\n  root: .\n  tags: [backend]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	v1 := "projects:\n  - name: api\n    meta: " + meta + "\n    root: " + dir + "\n"
+	if err := os.WriteFile(registryFile(), []byte(v1), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(registryFile(), mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	reg, err := loadRegistry()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := reg.Projects[0]
+	if reg.Version != RegistryVersion || p.Description != "REST API" || p.Tags[0] != "backend" || !p.Added.Equal(mtime) {
+		t.Fatalf("not migrated: %+v", reg)
+	}
+	if err := RegSet("api", []string{"pinned=true"}); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := os.ReadFile(registryFile())
+	if !strings.HasPrefix(string(b), "version: 2\n") || !strings.Contains(string(b), "added:") {
+		t.Errorf("registry not written in the new format:\n%s", b)
+	}
+
+	if err := os.WriteFile(registryFile(), []byte("version: 99\nprojects: []\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadRegistry(); err == nil || !strings.Contains(err.Error(), "update pm") {
+		t.Errorf("expected an error for a newer registry, got %v", err)
+	}
+}
+
+func TestRegSet_KeptOnRefresh(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("PM_CONFIGS", filepath.Join(td, "cfg"))
+	meta := writeMeta(t, filepath.Join(td, "api"), "api")
+	if err := RegAdd(meta); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegSet("ap", []string{"pinned=true", "dialect=zsh", "env.PROFILE=dev", "env.X=1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegSet("api", []string{"env.X="}); err != nil {
+		t.Fatal(err)
+	}
+	for _, unknown := range []string{"color=red", "shell=zsh"} {
+		if err := RegSet("api", []string{unknown}); err == nil {
+			t.Errorf("expected an error for the unknown setting %q", unknown)
+		}
+	}
+	for _, bad := range []string{"env.=x", "env.A B=x", "env.X;rm -rf ~=1", "env.1X=1"} {
+		if err := RegSet("api", []string{bad}); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+	if _, err := RegScan(td, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegAdd(meta); err != nil {
+		t.Fatal(err)
+	}
+	e, ok := Entry("api")
+	if !ok || !e.Pinned || e.Dialect != "zsh" || len(e.Env) != 1 || e.Env["PROFILE"] != "dev" || e.Added.IsZero() {
+		t.Fatalf("settings lost: %+v", e)
+	}
+}
+
+func TestListProjects(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("PM_CONFIGS", filepath.Join(td, "cfg"))
+	defer func(f func() time.Time) { now = f }(now)
+	clock := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { clock = clock.Add(time.Minute); return clock }
+
+	for _, n := range []string{"api", "web", "db"} {
+		if err := RegAdd(writeMeta(t, filepath.Join(td, n), n)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := RegSet("db", []string{"pinned=true"}); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := os.ReadFile(registryFile())
+	for _, e := range []HistoryEntry{
+		{Project: "web", Ref: "web"},
+		{Project: "@all", Ref: "@all", Projects: []string{"api", "db"}},
+	} {
+		if err := RecordHistory(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ps, _ := ListProjects(LsOptions{})
+	if got := names(ps); got != "db,api,web" {
+		t.Errorf("default order: %s", got)
+	}
+	ps, _ = ListProjects(LsOptions{Recent: true})
+	if got := names(ps); got != "db,api,web" {
+		t.Errorf("recent order: %s", got)
+	}
+	if ps[2].LastUsed.IsZero() || !ps[1].LastUsed.After(ps[2].LastUsed) {
+		t.Errorf("last used: %+v", ps)
+	}
+	// using a project does not rewrite the registry
+	if after, _ := os.ReadFile(registryFile()); !bytes.Equal(before, after) {
+		t.Errorf("registry changed:\n%s", after)
+	}
+
+	var buf bytes.Buffer
+	if err := RegLs(&buf, LsOptions{Format: "json"}); err != nil {
+		t.Fatal(err)
+	}
+	var fromJSON []RegProject
+	if err := json.Unmarshal(buf.Bytes(), &fromJSON); err != nil || names(fromJSON) != "db,api,web" {
+		t.Errorf("json: %v %s", err, buf.String())
+	}
+	if !fromJSON[1].LastUsed.Equal(ps[1].LastUsed) {
+		t.Errorf("json last_used = %v, want %v", fromJSON[1].LastUsed, ps[1].LastUsed)
+	}
+	if b, _ := json.Marshal(RegProject{Name: "new"}); strings.Contains(string(b), "added") || strings.Contains(string(b), "last_used") {
+		t.Errorf("unset times in json: %s", b)
+	}
+	buf.Reset()
+	if err := RegLs(&buf, LsOptions{Format: "yaml"}); err != nil {
+		t.Fatal(err)
+	}
+	var fromYAML []RegProject
+	if err := yaml.Unmarshal(buf.Bytes(), &fromYAML); err != nil || len(fromYAML) != 3 {
+		t.Errorf("yaml: %v %s", err, buf.String())
+	}
+	buf.Reset()
+	if err := RegLs(&buf, LsOptions{Format: "table"}); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 4 || !strings.HasPrefix(lines[1], "db *") {
+		t.Errorf("table:\n%s", buf.String())
+	}
+	buf.Reset()
+	if err := RegLs(&buf, LsOptions{Format: "tsv", Tag: "none"}); err != nil || buf.Len() != 0 {
+		t.Errorf("tsv of nothing: %v %q", err, buf.String())
+	}
+	if err := RegLs(&buf, LsOptions{Format: "tsv"}); err != nil || !strings.HasPrefix(buf.String(), "db\t\n") {
+		t.Errorf("tsv: %v %q", err, buf.String())
+	}
+	if err := RegLs(&buf, LsOptions{Format: "xml"}); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestListProjects_Tag(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("PM_CONFIGS", filepath.Join(td, "cfg"))
+	for n, tag := range map[string]string{"api": "backend", "web": "frontend"} {
+		p := filepath.Join(td, n, MetaFileName)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("info:\n  name: "+n+"\n  root: .\n  tags: ["+tag+"]\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := RegAdd(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ps, _ := ListProjects(LsOptions{Tag: "backend"})
+	if got := names(ps); got != "api" {
+		t.Errorf("tag filter: %s", got)
+	}
+}
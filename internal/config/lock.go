@@ -0,0 +1,141 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Registry mutations are serialised across pm processes by a lock file
+// created with O_EXCL, which works the same on every platform. The file
+// holds the owner's pid and a random token: only the owner removes it. A
+// lock older than lockStale is left over from a crashed pm and is taken
+// over by renaming it away, so only one waiting pm breaks it.
+var (
+	lockWait  = 5 * time.Second
+	lockStale = 30 * time.Second
+)
+
+func lockFile() string { return registryFile() + ".lock" }
+
+// lockRegistry takes the registry lock and returns the function that
+// releases it.
+func lockRegistry() (func(), error) { return lockPath(lockFile(), "registry") }
+
+// lockPath takes the lock file path guarding what.
+func lockPath(path, what string) (func(), error) {
+	if err := ensureHome(); err != nil {
+		return nil, err
+	}
+	token := fmt.Sprintf("%d %s\n", os.Getpid(), randomHex())
+	deadline := time.Now().Add(lockWait)
+	for {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err == nil {
+			_, err = f.WriteString(token)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				os.Remove(path)
+				return nil, err
+			}
+			return func() { unlockPath(path, token) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if breakStale(path) {
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s is locked by another pm (pid %s); remove %s if none is running",
+				what, lockOwner(path), path)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// unlockPath removes the lock file if it still holds token: a lock broken
+// as stale and taken by another pm is left alone.
+func unlockPath(path, token string) {
+	if b, err := os.ReadFile(path); err == nil && string(b) == token {
+		os.Remove(path)
+	}
+}
+
+// breakStale moves a stale lock out of the way and reports whether it did.
+// The rename succeeds for one pm only; if the lock it moved turns out to be
+// fresh (retaken since the check), it is put back unless a new one exists.
+func breakStale(path string) bool {
+	if st, err := os.Stat(path); err != nil || time.Since(st.ModTime()) <= lockStale {
+		return false
+	}
+	moved := path + ".stale." + randomHex()
+	if err := os.Rename(path, moved); err != nil {
+		return false
+	}
+	defer os.Remove(moved)
+	if st, err := os.Stat(moved); err == nil && time.Since(st.ModTime()) <= lockStale {
+		os.Link(moved, path)
+		return false
+	}
+	return true
+}
+
+// randomHex returns 8 random bytes in hex, for lock tokens and names.
+func randomHex() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// lockOwner returns the pid recorded in the lock file, or "?".
+func lockOwner(path string) string {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "?"
+	}
+	if f := strings.Fields(string(b)); len(f) > 0 {
+		return f[0]
+	}
+	return "?"
+}
+
+// writeAtomic replaces path with data through a temp file in the same
+// directory, so readers see either the old or the new content.
+func writeAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Chmod(tmp, 0o644); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
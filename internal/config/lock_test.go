@@ -0,0 +1,164 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRegAdd_Concurrent(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("PM_CONFIGS", filepath.Join(td, "cfg"))
+	const n = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		p := writeMeta(t, filepath.Join(td, fmt.Sprint("p", i)), fmt.Sprint("p", i))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- RegAdd(p)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	reg, err := loadRegistry()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reg.Projects) != n {
+		t.Fatalf("lost updates: %d of %d projects registered", len(reg.Projects), n)
+	}
+	if fileExists(lockFile()) {
+		t.Error("lock file left behind")
+	}
+	tmps, _ := filepath.Glob(filepath.Join(Home(), "*.tmp"))
+	if len(tmps) > 0 {
+		t.Errorf("temp files left behind: %v", tmps)
+	}
+}
+
+func TestLockRegistry_WaitAndStale(t *testing.T) {
+	t.Setenv("PM_CONFIGS", t.TempDir())
+	defer func(w, s time.Duration) { lockWait, lockStale = w, s }(lockWait, lockStale)
+	lockWait, lockStale = 50*time.Millisecond, time.Hour
+
+	unlock, err := lockRegistry()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lockRegistry(); err == nil || !strings.Contains(err.Error(), "locked by another pm") {
+		t.Fatalf("expected lock timeout, got %v", err)
+	}
+	unlock()
+
+	// a lock left by a crashed pm is taken over
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.WriteFile(lockFile(), []byte("99999\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(lockFile(), old, old); err != nil {
+		t.Fatal(err)
+	}
+	unlock, err = lockRegistry()
+	if err != nil {
+		t.Fatalf("stale lock not taken over: %v", err)
+	}
+	if left, _ := filepath.Glob(lockFile() + ".stale.*"); len(left) > 0 {
+		t.Errorf("broken lock left behind: %v", left)
+	}
+	unlock()
+}
+
+// TestLockRegistry_UnlockOwnOnly: a pm whose lock was broken and retaken
+// must not remove the new owner's lock.
+func TestLockRegistry_UnlockOwnOnly(t *testing.T) {
+	t.Setenv("PM_CONFIGS", t.TempDir())
+
+	unlock, err := lockRegistry()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(lockFile(), []byte("99999 other\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+	if !fileExists(lockFile()) {
+		t.Fatal("unlock removed a lock it does not own")
+	}
+	if owner := lockOwner(lockFile()); owner != "99999" {
+		t.Errorf("lockOwner = %q", owner)
+	}
+}
+
+func TestSaveRegistry_Backup(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("PM_CONFIGS", filepath.Join(td, "cfg"))
+	if err := RegAdd(writeMeta(t, filepath.Join(td, "a"), "a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegAdd(writeMeta(t, filepath.Join(td, "b"), "b")); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(registryBackup())
+	if err != nil {
+		t.Fatal(err)
+	}
+	bak, err := parseRegistry(b)
+	if err != nil || len(bak.Projects) != 1 || bak.Projects[0].Name != "a" {
+		t.Fatalf("backup should hold the previous version, got %s", b)
+	}
+}
+
+func TestRegRepair(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("PM_CONFIGS", filepath.Join(td, "cfg"))
+	if rep, err := RegRepair(); err != nil || !rep.OK {
+		t.Fatalf("empty registry: %+v, %v", rep, err)
+	}
+	for _, n := range []string{"a", "b"} {
+		if err := RegAdd(writeMeta(t, filepath.Join(td, n), n)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if rep, err := RegRepair(); err != nil || !rep.OK {
+		t.Fatalf("healthy registry: %+v, %v", rep, err)
+	}
+
+	// a crash mid-write: c is only in the truncated file, b is in the backup
+	cMeta := writeMeta(t, filepath.Join(td, "c"), "c")
+	broken := "projects:\n  - name: c\n    meta: " + cMeta + "\n    root: [\n"
+	if err := os.WriteFile(registryFile(), []byte(broken), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ResolveProject("a"); err == nil || !strings.Contains(err.Error(), "pm registry repair") {
+		t.Fatalf("expected a hint to repair, got %v", err)
+	}
+	rep, err := RegRepair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Restored != 1 || len(rep.Salvaged) != 1 || rep.Salvaged[0] != "c" || !fileExists(rep.Corrupt) {
+		t.Fatalf("unexpected report: %+v", rep)
+	}
+	reg, err := loadRegistry()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, p := range reg.Projects {
+		names = append(names, p.Name)
+	}
+	if strings.Join(names, ",") != "a,c" {
+		t.Errorf("repaired registry has %v", names)
+	}
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// RegistryVersion is the registry.yml format this pm writes. Older files are
+// migrated on load and written in the new format by the next change.
+const RegistryVersion = 2
+
+// migrations[v] upgrades a registry from version v to v+1. Files written
+// before versioning have no version: and count as version 1.
+var migrations = map[int]func(reg *Registry){
+	1: migrateV1,
+}
+
+// migrateRegistry brings reg up to RegistryVersion.
+func migrateRegistry(reg *Registry) error {
+	if reg.Version == 0 {
+		reg.Version = 1
+	}
+	if reg.Version > RegistryVersion {
+		return fmt.Errorf("registry.yml is version %d, this pm understands up to %d; update pm", reg.Version, RegistryVersion)
+	}
+	for reg.Version < RegistryVersion {
+		migrations[reg.Version](reg)
+		reg.Version++
+	}
+	return nil
+}
+
+// migrateV1 fills the fields version 2 copies from meta files and takes
+// the registry's modification time as the time every project was added.
+func migrateV1(reg *Registry) {
+	added := registryTime()
+	for i, p := range reg.Projects {
+		reg.Projects[i].Added = added
+		meta, err := LoadProjectMeta(p.Meta)
+		if err != nil {
+			continue
+		}
+		reg.Projects[i].Description = meta.Info.Description
+		if p.Aliases == nil {
+			reg.Projects[i].Aliases = meta.Info.Aliases
+		}
+		if p.Tags == nil {
+			reg.Projects[i].Tags = meta.Info.Tags
+		}
+	}
+}
+
+// registryTime is the modification time of registry.yml, or now.
+func registryTime() time.Time {
+	if st, err := os.Stat(registryFile()); err == nil {
+		return st.ModTime().UTC().Truncate(time.Second)
+	}
+	return now()
+}
+
+// now is replaced in tests.
+var now = func() time.Time { return time.Now().UTC().Truncate(time.Second) }
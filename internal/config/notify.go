@@ -0,0 +1,98 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// NotifyVia lists how a notification can be sent: a desktop notification
+// (notify-send, osascript or BurntToast, the terminal bell without one),
+// the terminal bell, or an OSC 9 escape for terminals that show it.
+var NotifyVia = []string{"desktop", "bell", "osc9"}
+
+// NotifySetting is a notify: setting. It is written as true or false, as
+// a threshold such as 30s (only commands that ran at least that long), or
+// as {after: 30s, via: bell}.
+type NotifySetting struct {
+	Off   bool          `json:"off,omitempty"`
+	After time.Duration `json:"after,omitempty"`
+	// one of NotifyVia; empty is desktop
+	Via string `json:"via,omitempty"`
+}
+
+func (n *NotifySetting) UnmarshalYAML(node *yaml.Node) error {
+	*n = NotifySetting{}
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if on, err := strconv.ParseBool(node.Value); err == nil {
+			n.Off = !on
+			return nil
+		}
+		return n.setAfter(node.Value)
+	case yaml.MappingNode:
+		var m struct {
+			After string `yaml:"after"`
+			Via   string `yaml:"via"`
+		}
+		if err := node.Decode(&m); err != nil {
+			return err
+		}
+		if m.Via != "" && !slices.Contains(NotifyVia, m.Via) {
+			return fmt.Errorf("line %d: notify via %q: want one of %v", node.Line, m.Via, NotifyVia)
+		}
+		n.Via = m.Via
+		if m.After == "" {
+			return nil
+		}
+		return n.setAfter(m.After)
+	}
+	return fmt.Errorf("line %d: notify: want true, false, a duration like 30s or {after, via}", node.Line)
+}
+
+func (n *NotifySetting) setAfter(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return fmt.Errorf("notify: %q is not true, false or a duration like 30s", s)
+	}
+	n.After = d
+	return nil
+}
+
+// MarshalYAML writes the shortest form that reads back the same.
+func (n NotifySetting) MarshalYAML() (any, error) {
+	switch {
+	case n.Off:
+		return false, nil
+	case n.Via != "":
+		m := map[string]string{"via": n.Via}
+		if n.After > 0 {
+			m["after"] = n.After.String()
+		}
+		return m, nil
+	case n.After > 0:
+		return n.After.String(), nil
+	}
+	return true, nil
+}
+
+// Notification returns the setting that applies to a command: its own,
+// else the global one, taking the way to notify from the global setting
+// when the command does not name one. Nil means no notification.
+func Notification(cmd, global *NotifySetting) *NotifySetting {
+	n := cmd
+	if n == nil {
+		n = global
+	}
+	if n == nil || n.Off {
+		return nil
+	}
+	out := *n
+	if out.Via == "" && global != nil {
+		out.Via = global.Via
+	}
+	return &out
+}
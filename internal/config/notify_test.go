@@ -0,0 +1,58 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestNotifySetting_YAML(t *testing.T) {
+	cases := map[string]NotifySetting{
+		"true":                   {},
+		"false":                  {Off: true},
+		"30s":                    {After: 30 * time.Second},
+		"{after: 2m, via: osc9}": {After: 2 * time.Minute, Via: "osc9"},
+		"{via: bell}":            {Via: "bell"},
+	}
+	for in, want := range cases {
+		var got NotifySetting
+		if err := yaml.Unmarshal([]byte(in), &got); err != nil {
+			t.Fatalf("%s: %v", in, err)
+		}
+		if got != want {
+			t.Errorf("%s = %+v, want %+v", in, got, want)
+		}
+		// and back
+		b, err := yaml.Marshal(got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var again NotifySetting
+		if err := yaml.Unmarshal(b, &again); err != nil || again != want {
+			t.Errorf("%s: round trip through %q = %+v, %v", in, b, again, err)
+		}
+	}
+	for _, in := range []string{"soon", "-5s", "{via: pigeon}", "[30s]"} {
+		var n NotifySetting
+		if err := yaml.Unmarshal([]byte(in), &n); err == nil {
+			t.Errorf("%s: want an error, got %+v", in, n)
+		}
+	}
+}
+
+func TestNotification(t *testing.T) {
+	global := &NotifySetting{After: 30 * time.Second, Via: "bell"}
+	if got := Notification(nil, nil); got != nil {
+		t.Errorf("nothing set = %+v", got)
+	}
+	if got := Notification(nil, global); *got != *global {
+		t.Errorf("global only = %+v", got)
+	}
+	if got := Notification(&NotifySetting{}, global); got.After != 0 || got.Via != "bell" {
+		t.Errorf("notify: true = %+v", got)
+	}
+	if got := Notification(&NotifySetting{Off: true}, global); got != nil {
+		t.Errorf("notify: false = %+v", got)
+	}
+}
@@ -1,16 +1,23 @@
 package config
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"pm/internal/match"
 )
 
-func pmHome() string {
+// Home is the pm config directory (PM_CONFIGS, default ~/.config/pm).
+func Home() string {
 	if v := os.Getenv("PM_CONFIGS"); v != "" {
 		return expand(v)
 	}
@@ -18,13 +25,16 @@ func pmHome() string {
 	return filepath.Join(home, ".config", "pm")
 }
 
-func registryFile() string { return filepath.Join(pmHome(), "registry.yml") }
-func globalFile() string   { return filepath.Join(pmHome(), "global.yml") }
+func registryFile() string { return filepath.Join(Home(), "registry.yml") }
+func globalFile() string   { return filepath.Join(Home(), "global.yml") }
 
 func ensureHome() error {
-	return os.MkdirAll(pmHome(), 0o755)
+	return os.MkdirAll(Home(), 0o755)
 }
 
+func registryBackup() string { return registryFile() + ".bak" }
+
+// loadRegistry reads the registry; a missing file is an empty registry.
 func loadRegistry() (*Registry, error) {
 	if err := ensureHome(); err != nil {
 		return nil, err
@@ -33,14 +43,29 @@ func loadRegistry() (*Registry, error) {
 	b, err := os.ReadFile(path)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
-			reg := &Registry{Projects: []RegProject{}}
-			if err := saveRegistry(reg); err != nil {
-				return nil, err
-			}
-			return reg, nil
+			return newRegistry(), nil
 		}
 		return nil, err
 	}
+	reg, err := parseRegistry(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s is corrupted (pm registry repair): %w", path, err)
+	}
+	if err := migrateRegistry(reg); err != nil {
+		return nil, err
+	}
+	// last_used comes from the history; older pm kept it here
+	for i := range reg.Projects {
+		reg.Projects[i].LastUsed = time.Time{}
+	}
+	return reg, nil
+}
+
+func newRegistry() *Registry {
+	return &Registry{Version: RegistryVersion, Projects: []RegProject{}}
+}
+
+func parseRegistry(b []byte) (*Registry, error) {
 	var reg Registry
 	if err := yaml.Unmarshal(b, &reg); err != nil {
 		return nil, err
@@ -51,6 +76,8 @@ func loadRegistry() (*Registry, error) {
 	return &reg, nil
 }
 
+// saveRegistry keeps the previous registry as registry.yml.bak and
+// replaces the file atomically. Callers hold the registry lock.
 func saveRegistry(r *Registry) error {
 	if err := ensureHome(); err != nil {
 		return err
@@ -59,106 +86,194 @@ func saveRegistry(r *Registry) error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(registryFile(), b, 0o644)
+	if prev, err := os.ReadFile(registryFile()); err == nil && len(prev) > 0 {
+		if _, err := parseRegistry(prev); err == nil {
+			if err := writeAtomic(registryBackup(), prev); err != nil {
+				return err
+			}
+		}
+	}
+	return writeAtomic(registryFile(), b)
 }
 
-// RegAdd adds a project to the registry from a meta file path.
-func RegAdd(metaPath string) error {
-	metaPath = expand(metaPath)
-	if _, err := os.Stat(metaPath); err != nil {
-		return fmt.Errorf("meta file not found: %s", metaPath)
-	}
-	meta, err := LoadProjectMeta(metaPath)
+// withRegistry loads the registry under the registry lock, applies fn and
+// saves the result when fn reports a change.
+func withRegistry(fn func(reg *Registry) (bool, error)) error {
+	unlock, err := lockRegistry()
 	if err != nil {
 		return err
 	}
-	if meta.Info.Name == "" || meta.Info.Root == "" {
-		return errors.New("meta.yml must contain info.name and info.root")
-	}
+	defer unlock()
 	reg, err := loadRegistry()
 	if err != nil {
 		return err
 	}
-	// dedup by name
-	out := make([]RegProject, 0, len(reg.Projects))
-	for _, p := range reg.Projects {
-		if p.Name != meta.Info.Name {
-			out = append(out, p)
-		}
+	changed, err := fn(reg)
+	if err != nil || !changed {
+		return err
 	}
-	out = append(out, RegProject{
-		Name: meta.Info.Name,
-		Meta: abs(metaPath),
-		Root: abs(expand(meta.Info.Root)),
-	})
-	reg.Projects = out
 	return saveRegistry(reg)
 }
 
-// RegRm removes a project from the registry by name.
-func RegRm(name string) error {
-	reg, err := loadRegistry()
-	if err != nil {
-		return err
-	}
-	changed := false
-	out := make([]RegProject, 0, len(reg.Projects))
-	for _, p := range reg.Projects {
+// index returns the position of the project called name, or -1.
+func (r *Registry) index(name string) int {
+	for i, p := range r.Projects {
 		if p.Name == name {
-			changed = true
-			continue
+			return i
 		}
-		out = append(out, p)
-	}
-	if !changed {
-		return fmt.Errorf("project not found: %s", name)
 	}
-	reg.Projects = out
-	return saveRegistry(reg)
+	return -1
 }
 
-// RegLs lists all projects in the registry.
-func RegLs() error {
-	reg, err := loadRegistry()
+// RegAdd adds a project to the registry from a meta file path. An entry
+// with the same name is replaced only when it comes from the same meta
+// file or its meta file is gone.
+func RegAdd(metaPath string) error { return regAdd(metaPath, false) }
+
+// RegAddForce is RegAdd that replaces any entry with the same name.
+func RegAddForce(metaPath string) error { return regAdd(metaPath, true) }
+
+func regAdd(metaPath string, force bool) error {
+	e, err := regEntry(metaPath)
 	if err != nil {
 		return err
 	}
-	if len(reg.Projects) == 0 {
-		fmt.Println("# pm: empty. Use: pm add /path/to/.pm.meta.yml")
-		return nil
+	return withRegistry(func(reg *Registry) (bool, error) {
+		i := reg.index(e.Name)
+		if i < 0 {
+			reg.Projects = append(reg.Projects, e)
+			return true, nil
+		}
+		old := reg.Projects[i]
+		if old.Meta != e.Meta && fileExists(old.Meta) && !force {
+			return false, fmt.Errorf("project %s is already registered from %s (pm add --force to replace)", e.Name, old.Meta)
+		}
+		reg.Projects[i] = e.withState(old)
+		return true, nil
+	})
+}
+
+// RegRm removes a project from the registry by name.
+func RegRm(name string) error {
+	return withRegistry(func(reg *Registry) (bool, error) {
+		i := reg.index(name)
+		if i < 0 {
+			return false, fmt.Errorf("project not found: %s", name)
+		}
+		reg.Projects = slices.Delete(reg.Projects, i, i+1)
+		return true, nil
+	})
+}
+
+var envNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// IsEnvName reports whether s can be exported as an environment variable
+// by every dialect without quoting.
+func IsEnvName(s string) bool { return envNameRe.MatchString(s) }
+
+// RegSet changes what pm keeps about a project: pinned=true|false,
+// dialect=NAME and env.NAME=VALUE; an empty value clears the setting.
+func RegSet(name string, assigns []string) error {
+	return withRegistry(func(reg *Registry) (bool, error) {
+		p, err := reg.find(name)
+		if err != nil {
+			return false, err
+		}
+		e := &reg.Projects[reg.index(p.Name)]
+		for _, a := range assigns {
+			k, v, ok := strings.Cut(a, "=")
+			if !ok {
+				return false, fmt.Errorf("expected key=value, got %q", a)
+			}
+			switch {
+			case k == "pinned":
+				e.Pinned = v == "true" || v == "yes" || v == "1"
+			case k == "dialect":
+				e.Dialect = v
+			case strings.HasPrefix(k, "env."):
+				if !IsEnvName(k[len("env."):]) {
+					return false, fmt.Errorf("bad variable name in %q (letters, digits and _)", k)
+				}
+				if v == "" {
+					delete(e.Env, k[len("env."):])
+					continue
+				}
+				if e.Env == nil {
+					e.Env = map[string]string{}
+				}
+				e.Env[k[len("env."):]] = v
+			default:
+				return false, fmt.Errorf("unknown setting %q (pinned, dialect, env.NAME)", k)
+			}
+		}
+		if len(e.Env) == 0 {
+			e.Env = nil
+		}
+		return true, nil
+	})
+}
+
+// Entry returns the registry entry of the project called name.
+func Entry(name string) (RegProject, bool) {
+	reg, err := loadRegistry()
+	if err != nil {
+		return RegProject{}, false
 	}
-	fmt.Print("# pm: projects\n\n")
-	for _, p := range reg.Projects {
-		fmt.Printf("- %s\n  meta: %s\n  root: %s\n", p.Name, p.Meta, p.Root)
+	i := reg.index(name)
+	if i < 0 {
+		return RegProject{}, false
 	}
-	return nil
+	return reg.Projects[i], true
 }
 
-// ResolveProject resolves a project by name or meta file path.
+// ResolveProject resolves a project by name, meta file path or directory
+// ("." or a path containing a separator, see ResolveDir).
 func ResolveProject(nameOrPath string) (*ProjectMeta, string, error) {
 	cand := expand(nameOrPath)
 	if fileExists(cand) && (extEq(cand, ".yml") || extEq(cand, ".yaml")) {
-		meta, err := LoadProjectMeta(cand)
-		if err != nil {
-			return nil, "", err
-		}
-		root := abs(expand(meta.Info.Root))
-		return meta, root, nil
+		return loadMetaRoot(cand)
+	}
+	if isDirRef(nameOrPath) && isDir(cand) {
+		return ResolveDir(cand)
 	}
 	reg, err := loadRegistry()
 	if err != nil {
 		return nil, "", err
 	}
-	for _, p := range reg.Projects {
-		if p.Name == nameOrPath {
-			meta, err := LoadProjectMeta(p.Meta)
-			if err != nil {
-				return nil, "", err
-			}
-			return meta, abs(p.Root), nil
-		}
+	p, err := reg.find(nameOrPath)
+	if err != nil {
+		return nil, "", err
+	}
+	return LoadEntry(p)
+}
+
+// LoadEntry loads the meta of a registry entry and returns it with the
+// entry's root.
+func LoadEntry(p RegProject) (*ProjectMeta, string, error) {
+	meta, err := LoadProjectMeta(p.Meta)
+	if err != nil {
+		return nil, "", err
 	}
-	return nil, "", fmt.Errorf("project not found in registry or file does not exist: %s", nameOrPath)
+	return meta, abs(p.Root), nil
+}
+
+// find looks a project up by exact name, alias or unambiguous prefix.
+func (r *Registry) find(name string) (RegProject, error) {
+	cands := make([]match.Candidate, len(r.Projects))
+	for i, p := range r.Projects {
+		cands[i] = match.Candidate{Name: p.Name, Aliases: p.Aliases}
+	}
+	got, err := match.Find(name, cands)
+	var amb *match.AmbiguousError
+	var nf *match.NotFoundError
+	switch {
+	case errors.As(err, &amb):
+		return RegProject{}, fmt.Errorf("project %q is ambiguous: %s", name, strings.Join(amb.Matches, ", "))
+	case errors.As(err, &nf):
+		return RegProject{}, fmt.Errorf("project not found in registry or file does not exist: %s%s",
+			name, match.DidYouMean(nf.Suggestions, ""))
+	}
+	return r.Projects[r.index(got)], nil
 }
 
 // LoadProjectMeta loads project metadata from a YAML file.
@@ -174,6 +289,17 @@ func LoadProjectMeta(path string) (*ProjectMeta, error) {
 	return &m, nil
 }
 
+// WriteProjectMeta writes m as YAML to path.
+func WriteProjectMeta(path string, m *ProjectMeta) error {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(m); err != nil {
+		return err
+	}
+	return os.WriteFile(expand(path), buf.Bytes(), 0o644)
+}
+
 // LoadGlobal loads global configuration from the global config file.
 func LoadGlobal() (*GlobalConfig, error) {
 	path := globalFile()
@@ -199,6 +325,9 @@ func LoadGlobal() (*GlobalConfig, error) {
 	return &gc, nil
 }
 
+// Expand expands environment variables and a leading ~ in a path.
+func Expand(s string) string { return expand(s) }
+
 func expand(s string) string {
 	s = os.ExpandEnv(s)
 	if strings.HasPrefix(s, "~") {
@@ -215,6 +344,10 @@ func abs(p string) string {
 	}
 	return p
 }
+func isDir(p string) bool {
+	st, err := os.Stat(p)
+	return err == nil && st.IsDir()
+}
 func fileExists(p string) bool {
 	_, err := os.Stat(p)
 	return err == nil
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// RepairReport summarizes RegRepair.
+type RepairReport struct {
+	// the registry was readable and left as is
+	OK bool
+	// where the unreadable registry was moved, if anywhere
+	Corrupt string
+	// number of entries taken from registry.yml.bak
+	Restored int
+	// names of projects recovered from meta: lines of the unreadable file
+	Salvaged []string
+}
+
+var metaLineRe = regexp.MustCompile(`(?m)^\s*(?:-\s+)?meta:\s*(.+?)\s*$`)
+
+// RegRepair recovers an unreadable registry.yml: it is moved aside, the
+// entries of the backup are restored and every meta file still named in the
+// unreadable file is registered again.
+func RegRepair() (*RepairReport, error) {
+	unlock, err := lockRegistry()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	path := registryFile()
+	b, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		if _, perr := parseRegistry(b); perr == nil {
+			return &RepairReport{OK: true}, nil
+		}
+	} else if !fileExists(registryBackup()) {
+		return &RepairReport{OK: true}, nil
+	}
+
+	rep := &RepairReport{}
+	reg := newRegistry()
+	if bak, err := os.ReadFile(registryBackup()); err == nil {
+		if r, err := parseRegistry(bak); err == nil && migrateRegistry(r) == nil {
+			reg = r
+			rep.Restored = len(reg.Projects)
+		}
+	}
+	if len(b) > 0 {
+		for _, m := range metaLineRe.FindAllStringSubmatch(string(b), -1) {
+			meta := strings.Trim(m[1], `"'`)
+			e, err := regEntry(meta)
+			if err != nil || reg.index(e.Name) >= 0 {
+				continue
+			}
+			reg.Projects = append(reg.Projects, e)
+			rep.Salvaged = append(rep.Salvaged, e.Name)
+		}
+		rep.Corrupt = fmt.Sprintf("%s.corrupt-%s", path, time.Now().Format("20060102-150405"))
+		if err := os.Rename(path, rep.Corrupt); err != nil {
+			return nil, err
+		}
+	}
+	// registry.yml is gone now, so saveRegistry keeps the backup as is
+	return rep, saveRegistry(reg)
+}
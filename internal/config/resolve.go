@@ -0,0 +1,79 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ResolveDir finds the project that dir belongs to: walking up from dir,
+// the first directory that holds a .pm.meta.yml or is the root of a
+// registered project wins.
+func ResolveDir(dir string) (*ProjectMeta, string, error) {
+	dir = abs(expand(dir))
+	reg, err := loadRegistry()
+	if err != nil {
+		return nil, "", err
+	}
+	for d := dir; ; d = filepath.Dir(d) {
+		if p := filepath.Join(d, MetaFileName); fileExists(p) {
+			return loadMetaRoot(p)
+		}
+		for _, p := range reg.Projects {
+			if samePath(abs(p.Root), d) {
+				meta, err := LoadProjectMeta(p.Meta)
+				if err != nil {
+					return nil, "", err
+				}
+				return meta, abs(p.Root), nil
+			}
+		}
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+	return nil, "", fmt.Errorf("no %s in %s or its parents, and no registered project contains it", MetaFileName, dir)
+}
+
+// ResolveCwd is ResolveDir for the working directory.
+func ResolveCwd() (*ProjectMeta, string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, "", err
+	}
+	return ResolveDir(wd)
+}
+
+// loadMetaRoot loads a meta file and resolves its project root.
+func loadMetaRoot(metaPath string) (*ProjectMeta, string, error) {
+	meta, err := LoadProjectMeta(metaPath)
+	if err != nil {
+		return nil, "", err
+	}
+	return meta, metaRoot(metaPath, meta), nil
+}
+
+// metaRoot returns the absolute project root of meta; a relative info.root
+// is relative to the directory of the meta file.
+func metaRoot(metaPath string, meta *ProjectMeta) string {
+	root := expand(meta.Info.Root)
+	if !filepath.IsAbs(root) {
+		root = filepath.Join(filepath.Dir(abs(expand(metaPath))), root)
+	}
+	return abs(root)
+}
+
+// isDirRef reports whether a project reference is meant as a directory
+// rather than a registered name: ".", "..", or anything with a separator.
+func isDirRef(ref string) bool {
+	return ref == "." || ref == ".." || strings.ContainsRune(ref, '/') ||
+		strings.ContainsRune(ref, filepath.Separator) || strings.HasPrefix(ref, "~")
+}
+
+func samePath(a, b string) bool {
+	if filepath.Separator == '\\' {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
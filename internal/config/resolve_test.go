@@ -0,0 +1,97 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveDir_WalksUpToMeta(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("PM_CONFIGS", filepath.Join(td, "cfg"))
+	proj := filepath.Join(td, "api")
+	writeMeta(t, proj, "api")
+	sub := filepath.Join(proj, "src", "pkg")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	meta, root, err := ResolveDir(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Info.Name != "api" || root != proj {
+		t.Fatalf("got %s %s", meta.Info.Name, root)
+	}
+
+	meta, _, err = ResolveProject(sub)
+	if err != nil || meta.Info.Name != "api" {
+		t.Fatalf("ResolveProject(dir): %v", err)
+	}
+}
+
+func TestResolveDir_RegistryRoot(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("PM_CONFIGS", filepath.Join(td, "cfg"))
+	// meta kept outside the repo, root points into it
+	repo := filepath.Join(td, "repos", "web")
+	if err := os.MkdirAll(filepath.Join(repo, "app"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	metaPath := filepath.Join(td, "metas", "web.yml")
+	if err := os.MkdirAll(filepath.Dir(metaPath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(metaPath, []byte("info:\n  name: web\n  root: "+repo+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegAdd(metaPath); err != nil {
+		t.Fatal(err)
+	}
+
+	meta, root, err := ResolveDir(filepath.Join(repo, "app"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Info.Name != "web" || root != repo {
+		t.Fatalf("got %s %s", meta.Info.Name, root)
+	}
+
+	if _, _, err := ResolveDir(td); err == nil {
+		t.Fatal("expected error outside any project")
+	}
+}
+
+func TestResolveProject_PrefixAliasSuggest(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("PM_CONFIGS", filepath.Join(td, "cfg"))
+	for _, name := range []string{"api", "app", "frontend"} {
+		if err := RegAdd(writeMeta(t, filepath.Join(td, name), name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	web := filepath.Join(td, "web", MetaFileName)
+	if err := os.MkdirAll(filepath.Dir(web), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(web, []byte("info:\n  name: web\n  root: .\n  aliases: [w, site]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegAdd(web); err != nil {
+		t.Fatal(err)
+	}
+
+	for q, want := range map[string]string{"fr": "frontend", "site": "web", "api": "api"} {
+		meta, _, err := ResolveProject(q)
+		if err != nil || meta.Info.Name != want {
+			t.Errorf("ResolveProject(%q): %v", q, err)
+		}
+	}
+	if _, _, err := ResolveProject("ap"); err == nil || !strings.Contains(err.Error(), "ambiguous: api, app") {
+		t.Errorf("expected ambiguity, got %v", err)
+	}
+	if _, _, err := ResolveProject("frontedn"); err == nil || !strings.Contains(err.Error(), "did you mean frontend?") {
+		t.Errorf("expected suggestion, got %v", err)
+	}
+}
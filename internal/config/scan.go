@@ -0,0 +1,187 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// MetaFileName is the project meta file pm looks for.
+const MetaFileName = ".pm.meta.yml"
+
+// skipDirs are never descended into while scanning.
+var skipDirs = map[string]bool{
+	"node_modules": true, "vendor": true, "target": true, "build": true, "dist": true,
+}
+
+// Conflict is a project name claimed by more than one meta file.
+type Conflict struct {
+	Name  string
+	Metas []string
+}
+
+// ScanReport summarizes RegScan.
+type ScanReport struct {
+	Added     []RegProject
+	Updated   []RegProject
+	Unchanged []RegProject
+	Conflicts []Conflict
+	Invalid   map[string]error
+}
+
+// FindMetas walks root up to depth directory levels below it and returns
+// every .pm.meta.yml found. Hidden directories and dependency/build output
+// directories are skipped.
+func FindMetas(root string, depth int) ([]string, error) {
+	root = abs(expand(root))
+	base := strings.Count(root, string(filepath.Separator))
+	var out []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return nil // unreadable subdirectory
+		}
+		if d.IsDir() {
+			if path != root && (strings.HasPrefix(d.Name(), ".") || skipDirs[d.Name()]) {
+				return filepath.SkipDir
+			}
+			if strings.Count(path, string(filepath.Separator))-base > depth {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() == MetaFileName {
+			out = append(out, path)
+		}
+		return nil
+	})
+	sort.Strings(out)
+	return out, err
+}
+
+// RegScan registers or refreshes every project found under root. A name
+// claimed by several meta files (in the scan, or in the registry by a meta
+// file that still exists) is reported as a conflict and left untouched.
+func RegScan(root string, depth int) (*ScanReport, error) {
+	paths, err := FindMetas(root, depth)
+	if err != nil {
+		return nil, err
+	}
+	rep := &ScanReport{Invalid: map[string]error{}}
+	found := map[string][]RegProject{}
+	var names []string
+	for _, p := range paths {
+		e, err := regEntry(p)
+		if err != nil {
+			rep.Invalid[p] = err
+			continue
+		}
+		if _, ok := found[e.Name]; !ok {
+			names = append(names, e.Name)
+		}
+		found[e.Name] = append(found[e.Name], e)
+	}
+
+	err = withRegistry(func(reg *Registry) (bool, error) {
+		changed := false
+		for _, name := range names {
+			cands := found[name]
+			if len(cands) > 1 {
+				c := Conflict{Name: name}
+				for _, e := range cands {
+					c.Metas = append(c.Metas, e.Meta)
+				}
+				rep.Conflicts = append(rep.Conflicts, c)
+				continue
+			}
+			e := cands[0]
+			i := reg.index(name)
+			switch {
+			case i < 0:
+				reg.Projects = append(reg.Projects, e)
+				rep.Added = append(rep.Added, e)
+				changed = true
+			case reg.Projects[i].Meta != e.Meta && fileExists(reg.Projects[i].Meta):
+				rep.Conflicts = append(rep.Conflicts, Conflict{Name: name, Metas: []string{reg.Projects[i].Meta, e.Meta}})
+			case sameEntry(reg.Projects[i], e):
+				rep.Unchanged = append(rep.Unchanged, e)
+			default:
+				reg.Projects[i] = e.withState(reg.Projects[i])
+				rep.Updated = append(rep.Updated, e)
+				changed = true
+			}
+		}
+		return changed, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rep, nil
+}
+
+// RegPrune removes registry entries whose meta file no longer exists and
+// returns them. With dryRun the registry is left as is.
+func RegPrune(dryRun bool) ([]RegProject, error) {
+	var removed []RegProject
+	err := withRegistry(func(reg *Registry) (bool, error) {
+		out := make([]RegProject, 0, len(reg.Projects))
+		for _, p := range reg.Projects {
+			if fileExists(p.Meta) {
+				out = append(out, p)
+			} else {
+				removed = append(removed, p)
+			}
+		}
+		reg.Projects = out
+		return len(removed) > 0 && !dryRun, nil
+	})
+	return removed, err
+}
+
+// sameEntry reports whether a and b agree on everything copied from the
+// meta file.
+func sameEntry(a, b RegProject) bool {
+	return a.Name == b.Name && a.Meta == b.Meta && a.Root == b.Root && a.Description == b.Description &&
+		slices.Equal(a.Aliases, b.Aliases) && slices.Equal(a.Tags, b.Tags)
+}
+
+// withState returns e with the state pm keeps about old: when it was added
+// and what the user set with pm set.
+func (e RegProject) withState(old RegProject) RegProject {
+	e.Added = old.Added
+	e.Pinned = old.Pinned
+	e.Dialect = old.Dialect
+	e.Env = old.Env
+	return e
+}
+
+// regEntry loads a meta file into the registry entry it would produce.
+func regEntry(metaPath string) (RegProject, error) {
+	metaPath = expand(metaPath)
+	if _, err := os.Stat(metaPath); err != nil {
+		return RegProject{}, fmt.Errorf("meta file not found: %s", metaPath)
+	}
+	meta, err := LoadProjectMeta(metaPath)
+	if err != nil {
+		return RegProject{}, err
+	}
+	if meta.Info.Name == "" || meta.Info.Root == "" {
+		return RegProject{}, errors.New("meta.yml must contain info.name and info.root")
+	}
+	return RegProject{
+		Name:        meta.Info.Name,
+		Meta:        abs(metaPath),
+		Root:        metaRoot(metaPath, meta),
+		Aliases:     meta.Info.Aliases,
+		Tags:        meta.Info.Tags,
+		Description: meta.Info.Description,
+		Added:       now(),
+	}, nil
+}
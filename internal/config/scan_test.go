@@ -0,0 +1,163 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeMeta(t *testing.T, dir, name string) string {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(dir, MetaFileName)
+	meta := "info:\n  name: " + name + "\n  root: .\ncommands:\n  run:\n    cmd: echo ok\n"
+	if err := os.WriteFile(p, []byte(meta), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestFindMetas_DepthAndSkips(t *testing.T) {
+	td := t.TempDir()
+	writeMeta(t, filepath.Join(td, "a"), "a")
+	writeMeta(t, filepath.Join(td, "group", "b"), "b")
+	writeMeta(t, filepath.Join(td, "a", "node_modules", "x"), "x")
+	writeMeta(t, filepath.Join(td, ".hidden"), "h")
+
+	got, err := FindMetas(td, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || !strings.Contains(got[0], filepath.Join("a", MetaFileName)) {
+		t.Fatalf("depth 1: %v", got)
+	}
+	got, _ = FindMetas(td, 2)
+	if len(got) != 2 {
+		t.Fatalf("depth 2: %v", got)
+	}
+}
+
+func TestRegScan_AddUpdateConflict(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("PM_CONFIGS", filepath.Join(td, "cfg"))
+	repos := filepath.Join(td, "repos")
+	writeMeta(t, filepath.Join(repos, "a"), "a")
+	writeMeta(t, filepath.Join(repos, "b"), "dup")
+	writeMeta(t, filepath.Join(repos, "c"), "dup")
+
+	rep, err := RegScan(repos, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rep.Added) != 1 || rep.Added[0].Name != "a" {
+		t.Fatalf("added: %+v", rep.Added)
+	}
+	if rep.Added[0].Root != filepath.Join(repos, "a") {
+		t.Fatalf("relative root not resolved against meta: %s", rep.Added[0].Root)
+	}
+	if len(rep.Conflicts) != 1 || rep.Conflicts[0].Name != "dup" {
+		t.Fatalf("conflicts: %+v", rep.Conflicts)
+	}
+
+	// second scan changes nothing
+	rep, err = RegScan(repos, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rep.Added)+len(rep.Updated) != 0 || len(rep.Unchanged) != 1 {
+		t.Fatalf("rescan: %+v", rep)
+	}
+
+	// a moved project is updated once its old meta is gone
+	old := filepath.Join(repos, "a")
+	if err := os.Rename(old, filepath.Join(repos, "a2")); err != nil {
+		t.Fatal(err)
+	}
+	rep, err = RegScan(repos, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rep.Updated) != 1 || rep.Updated[0].Root != filepath.Join(repos, "a2") {
+		t.Fatalf("updated: %+v", rep.Updated)
+	}
+}
+
+func TestRegAdd_ConflictAndForce(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("PM_CONFIGS", filepath.Join(td, "cfg"))
+	m1 := writeMeta(t, filepath.Join(td, "one"), "same")
+	m2 := writeMeta(t, filepath.Join(td, "two"), "same")
+
+	if err := RegAdd(m1); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegAdd(m1); err != nil {
+		t.Fatalf("re-adding the same meta must succeed: %v", err)
+	}
+	if err := RegAdd(m2); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Fatalf("expected conflict error, got %v", err)
+	}
+	if err := RegAddForce(m2); err != nil {
+		t.Fatal(err)
+	}
+	_, root, err := ResolveProject("same")
+	if err != nil || root != filepath.Join(td, "two") {
+		t.Fatalf("resolve after force: %s %v", root, err)
+	}
+}
+
+// TestRegAdd_RelativeRoot: a relative info.root is taken from the meta
+// file's directory, not from where pm add runs.
+func TestRegAdd_RelativeRoot(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("PM_CONFIGS", filepath.Join(td, "cfg"))
+	m := writeMeta(t, filepath.Join(td, "proj"), "proj")
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(td); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	if err := RegAdd(m); err != nil {
+		t.Fatal(err)
+	}
+	_, root, err := ResolveProject("proj")
+	if err != nil || root != filepath.Join(td, "proj") {
+		t.Fatalf("root: %s %v", root, err)
+	}
+}
+
+func TestRegPrune(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("PM_CONFIGS", filepath.Join(td, "cfg"))
+	keep := writeMeta(t, filepath.Join(td, "keep"), "keep")
+	gone := writeMeta(t, filepath.Join(td, "gone"), "gone")
+	for _, m := range []string{keep, gone} {
+		if err := RegAdd(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Remove(gone); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := RegPrune(true)
+	if err != nil || len(removed) != 1 || removed[0].Name != "gone" {
+		t.Fatalf("dry run: %+v %v", removed, err)
+	}
+	if reg, _ := loadRegistry(); len(reg.Projects) != 2 {
+		t.Fatalf("dry run modified registry: %+v", reg.Projects)
+	}
+	if _, err := RegPrune(false); err != nil {
+		t.Fatal(err)
+	}
+	if reg, _ := loadRegistry(); len(reg.Projects) != 1 || reg.Projects[0].Name != "keep" {
+		t.Fatalf("after prune: %+v", reg.Projects)
+	}
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// IsSelection reports whether a project reference selects several
+// projects: a tag (@backend), a list (api,worker) or a glob ('*', 'svc-*').
+// Directory references (./a,b or ~/src/[x]) are paths, never selections.
+func IsSelection(ref string) bool {
+	if isDirRef(ref) {
+		return false
+	}
+	return strings.HasPrefix(ref, "@") || strings.Contains(ref, ",") || strings.ContainsAny(ref, "*?[")
+}
+
+// SelectProjects returns the registered projects matched by a selection.
+// Comma-separated terms are a tag, a glob over project names, or a name
+// (resolved like ResolveProject); each project appears once, in the order
+// the terms select it.
+func SelectProjects(sel string) ([]RegProject, error) {
+	reg, err := loadRegistry()
+	if err != nil {
+		return nil, err
+	}
+	var out []RegProject
+	seen := map[string]bool{}
+	hit := false
+	add := func(p RegProject) {
+		hit = true
+		if !seen[p.Name] {
+			seen[p.Name] = true
+			out = append(out, p)
+		}
+	}
+	for _, term := range strings.Split(sel, ",") {
+		term = strings.TrimSpace(term)
+		hit = false
+		switch {
+		case term == "":
+			continue
+		case strings.HasPrefix(term, "@"):
+			for _, p := range reg.Projects {
+				if slices.Contains(p.Tags, term[1:]) {
+					add(p)
+				}
+			}
+			if !hit {
+				return nil, fmt.Errorf("no projects tagged %s", term[1:])
+			}
+		case strings.ContainsAny(term, "*?["):
+			for _, p := range reg.Projects {
+				ok, err := path.Match(term, p.Name)
+				if err != nil {
+					return nil, fmt.Errorf("bad project pattern %q: %w", term, err)
+				}
+				if ok {
+					add(p)
+				}
+			}
+			if !hit {
+				return nil, fmt.Errorf("no projects match %s", term)
+			}
+		default:
+			p, err := reg.find(term)
+			if err != nil {
+				return nil, err
+			}
+			add(p)
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no projects selected by %q", sel)
+	}
+	return out, nil
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSelectProjects(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("PM_CONFIGS", filepath.Join(td, "cfg"))
+	metas := map[string]string{
+		"api":    "tags: [backend]",
+		"worker": "tags: [backend, jobs]",
+		"web":    "tags: [frontend]",
+	}
+	for _, name := range []string{"api", "worker", "web"} {
+		dir := filepath.Join(td, name)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		p := filepath.Join(dir, MetaFileName)
+		meta := "info:\n  name: " + name + "\n  root: .\n  " + metas[name] + "\n"
+		if err := os.WriteFile(p, []byte(meta), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := RegAdd(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := map[string]string{
+		"@backend":      "api,worker",
+		"web,api":       "web,api",
+		"*":             "api,worker,web",
+		"w*":            "worker,web",
+		"wo,@backend":   "worker,api", // no duplicates
+		"@jobs,we":      "worker,web", // prefix resolution for names
+		"api,@frontend": "api,web",
+	}
+	for sel, want := range cases {
+		got, err := SelectProjects(sel)
+		if err != nil {
+			t.Errorf("SelectProjects(%q): %v", sel, err)
+			continue
+		}
+		var names []string
+		for _, p := range got {
+			names = append(names, p.Name)
+		}
+		if strings.Join(names, ",") != want {
+			t.Errorf("SelectProjects(%q) = %v, want %s", sel, names, want)
+		}
+	}
+
+	for _, sel := range []string{"@nope", "x*", "api,nope"} {
+		if _, err := SelectProjects(sel); err == nil {
+			t.Errorf("SelectProjects(%q): expected error", sel)
+		}
+	}
+	if !IsSelection("@backend") || !IsSelection("a,b") || !IsSelection("*") || IsSelection("api") {
+		t.Error("IsSelection")
+	}
+	for _, dir := range []string{"./a,b", "~/src/[x]", "../svc-*", "/tmp/@x"} {
+		if IsSelection(dir) {
+			t.Errorf("IsSelection(%q): a directory is not a selection", dir)
+		}
+	}
+}
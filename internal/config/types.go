@@ -1,48 +1,119 @@
 package config
 
+import (
+	"encoding/json"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
 // Registry holds a list of registered projects.
 type Registry struct {
-	Projects []RegProject `yaml:"projects"`
+	// format of registry.yml, see RegistryVersion
+	Version  int          `yaml:"version" json:"version"`
+	Projects []RegProject `yaml:"projects" json:"projects"`
 }
 
 // RegProject represents a registered project entry.
 type RegProject struct {
-	Name string `yaml:"name"`
-	Meta string `yaml:"meta"`
-	Root string `yaml:"root"`
+	Name string `yaml:"name" json:"name"`
+	Meta string `yaml:"meta" json:"meta"`
+	Root string `yaml:"root" json:"root"`
+	// copied from info.aliases so that resolution needs no meta loading
+	Aliases []string `yaml:"aliases,omitempty" json:"aliases,omitempty"`
+	Tags    []string `yaml:"tags,omitempty" json:"tags,omitempty"`
+	// copied from info.description for pm ls
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+
+	// kept when the entry is refreshed from its meta file
+	Added  time.Time `yaml:"added,omitempty" json:"added"`
+	Pinned bool      `yaml:"pinned,omitempty" json:"pinned,omitempty"`
+	// filled from the history by ListProjects, never saved in the registry
+	LastUsed time.Time `yaml:"last_used,omitempty" json:"last_used"`
+	// the dialect pm-bin renders and pm run runs in when neither
+	// --dialect nor PM_DIALECT says otherwise
+	Dialect string `yaml:"dialect,omitempty" json:"dialect,omitempty"`
+	// exported before the project's commands run
+	Env map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
+}
+
+// MarshalJSON leaves out the times that are not set, which omitempty
+// does not do for a time.Time.
+func (p RegProject) MarshalJSON() ([]byte, error) {
+	type plain RegProject
+	out := struct {
+		plain
+		Added    *time.Time `json:"added,omitempty"`
+		LastUsed *time.Time `json:"last_used,omitempty"`
+	}{plain: plain(p)}
+	if !p.Added.IsZero() {
+		out.Added = &p.Added
+	}
+	if !p.LastUsed.IsZero() {
+		out.LastUsed = &p.LastUsed
+	}
+	return json.Marshal(out)
 }
 
 // ProjectMeta contains project metadata and configuration.
 type ProjectMeta struct {
-	Info struct {
-		Name        string `yaml:"name"`
-		Description string `yaml:"description"`
-		Root        string `yaml:"root"`
-	} `yaml:"info"`
-
-	Func     map[string]FuncDef    `yaml:"func"`
-	Commands map[string]CommandDef `yaml:"commands"`
-	Docker   DockerDef             `yaml:"docker"`
+	Info ProjectInfo `yaml:"info" json:"info"`
+
+	Func     map[string]FuncDef    `yaml:"func,omitempty" json:"func"`
+	Commands map[string]CommandDef `yaml:"commands" json:"commands"`
+	Docker   DockerDef             `yaml:"docker,omitempty" json:"docker"`
+	Hooks    HooksDef              `yaml:"hooks,omitempty" json:"hooks"`
+}
+
+// ProjectInfo is the info: section of a project meta file.
+type ProjectInfo struct {
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description" json:"description"`
+	Root        string `yaml:"root" json:"root"`
+	// alternative names for `pm NAME`
+	Aliases []string `yaml:"aliases,omitempty" json:"aliases,omitempty"`
+	// labels for selecting several projects: pm @backend :test
+	Tags []string `yaml:"tags,omitempty" json:"tags,omitempty"`
 }
 
-// ParamMeta defines metadata for function parameters.
+// ParamMeta defines metadata for function and command parameters.
 type ParamMeta struct {
-	Required bool   `yaml:"required"`
-	Default  string `yaml:"default,omitempty"`
+	Required    bool   `yaml:"required" json:"required"`
+	Default     string `yaml:"default,omitempty" json:"default,omitempty"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	// allowed values; empty means any
+	Enum []string `yaml:"enum,omitempty" json:"enum,omitempty"`
 }
 
 // FuncDef defines a function with parameters and script.
 type FuncDef struct {
-	Params map[string]ParamMeta `yaml:"params"`
+	Params map[string]ParamMeta `yaml:"params,omitempty" json:"params"`
 	// may be string or []string
-	Script any `yaml:"script"`
+	Script any `yaml:"script" json:"script"`
 }
 
 // CommandDef defines a command with description and command lines.
 type CommandDef struct {
-	Description string `yaml:"description"`
+	Description string `yaml:"description" json:"description"`
 	// may be string or []string
-	Cmd any `yaml:"cmd"`
+	Cmd any `yaml:"cmd" json:"cmd"`
+	// commands to run first, without args; each runs once per invocation
+	Deps []string `yaml:"deps,omitempty" json:"deps,omitempty"`
+	// alternative names for :name
+	Aliases []string `yaml:"aliases,omitempty" json:"aliases,omitempty"`
+	// heading the command is listed under in :help
+	Group string `yaml:"group,omitempty" json:"group,omitempty"`
+	// given as --name=value or --name value, used as @{name}
+	Params map[string]ParamMeta `yaml:"params,omitempty" json:"params,omitempty"`
+	// completion notification, overriding the global notify:
+	Notify *NotifySetting `yaml:"notify,omitempty" json:"notify,omitempty"`
+	// globs relative to the root: the command is skipped while the inputs
+	// are unchanged since its last successful run and the outputs exist
+	Inputs  []string `yaml:"inputs,omitempty" json:"inputs,omitempty"`
+	Outputs []string `yaml:"outputs,omitempty" json:"outputs,omitempty"`
+	// globs that re-run the command when they change: it always runs in
+	// watch mode
+	Watch []string `yaml:"watch,omitempty" json:"watch,omitempty"`
 }
 
 // AsLines converts the command to a slice of strings.
@@ -65,16 +136,53 @@ func (c CommandDef) AsLines() []string {
 	}
 }
 
+// HooksDef is the hooks: section of a project meta or global.yml: command
+// lines run around an invocation and around single commands. Each is a
+// string or a list, rendered like a command's cmd.
+type HooksDef struct {
+	// before the first and after the last command of an invocation
+	BeforeAll HookLines `yaml:"before_all,omitempty" json:"before_all,omitempty"`
+	AfterAll  HookLines `yaml:"after_all,omitempty" json:"after_all,omitempty"`
+	// command name -> lines around that command, also when it runs as a
+	// dependency
+	Before map[string]HookLines `yaml:"before,omitempty" json:"before,omitempty"`
+	After  map[string]HookLines `yaml:"after,omitempty" json:"after,omitempty"`
+	// when a command fails, before the failure ends the script
+	OnFailure HookLines `yaml:"on_failure,omitempty" json:"on_failure,omitempty"`
+}
+
+// HookLines is a hook's command lines, written as a string or a list.
+type HookLines []string
+
+func (h *HookLines) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*h = HookLines{node.Value}
+		return nil
+	}
+	var lines []string
+	if err := node.Decode(&lines); err != nil {
+		return err
+	}
+	*h = lines
+	return nil
+}
+
 // DockerDef defines Docker Compose configuration.
 type DockerDef struct {
-	ComposeFile string              `yaml:"compose_file"`
-	Groups      map[string][]string `yaml:"groups"`
+	ComposeFile string              `yaml:"compose_file,omitempty" json:"compose_file"`
+	Groups      map[string][]string `yaml:"groups,omitempty" json:"groups"`
 }
 
 // GlobalConfig defines global configuration settings.
 type GlobalConfig struct {
 	// allow same structure as ProjectMeta for func/global vars
-	Func map[string]FuncDef `yaml:"func"`
+	Func map[string]FuncDef `yaml:"func" json:"func"`
+	// named sets of projects, see WorkspaceDef
+	Workspaces map[string]WorkspaceDef `yaml:"workspaces,omitempty" json:"workspaces,omitempty"`
+	// completion notification for every command, e.g. notify: 30s
+	Notify *NotifySetting `yaml:"notify,omitempty" json:"notify,omitempty"`
+	// run around the commands of every project, see HooksDef
+	Hooks HooksDef `yaml:"hooks,omitempty" json:"hooks"`
 	// any other fields accessible via #{global.*} → we keep whole map
-	Raw map[string]any `yaml:"-"`
+	Raw map[string]any `yaml:"-" json:"-"`
 }
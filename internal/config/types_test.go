@@ -103,3 +103,24 @@ docker:
 		t.Errorf("expected 2 services in base group")
 	}
 }
+
+func TestHooksDef_YAML(t *testing.T) {
+	yml := `
+before_all: "sdk use java 21"
+after:
+  deploy:
+    - "echo deployed >> ~/deploy.log"
+    - "git tag -f deployed"
+on_failure: [rm -rf tmp]
+`
+	var h HooksDef
+	if err := yaml.Unmarshal([]byte(yml), &h); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(h.BeforeAll) != 1 || h.BeforeAll[0] != "sdk use java 21" {
+		t.Errorf("before_all = %q", h.BeforeAll)
+	}
+	if len(h.After["deploy"]) != 2 || h.OnFailure[0] != "rm -rf tmp" {
+		t.Errorf("hooks = %+v", h)
+	}
+}
@@ -0,0 +1,176 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// WorkspaceDef is a named set of projects with shared config, from the
+// workspaces: section of global.yml or of workspace.yml.
+type WorkspaceDef struct {
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	// directory for the workspace's own command lines (default: cwd)
+	Root string `yaml:"root,omitempty" json:"root,omitempty"`
+	// names, @tags or globs, as in `pm @tag`
+	Projects []string `yaml:"projects" json:"projects"`
+	// project -> projects that must come first
+	Deps map[string][]string `yaml:"deps,omitempty" json:"deps,omitempty"`
+	// shared with the members as #{global.vars.*} and _{global.name()}
+	Vars     map[string]any        `yaml:"vars,omitempty" json:"vars,omitempty"`
+	Func     map[string]FuncDef    `yaml:"func,omitempty" json:"func,omitempty"`
+	Commands map[string]CommandDef `yaml:"commands,omitempty" json:"commands,omitempty"`
+}
+
+// WorkspacePrefix marks a workspace reference: pm ws:platform :up
+const WorkspacePrefix = "ws:"
+
+func workspaceFile() string { return filepath.Join(Home(), "workspace.yml") }
+
+// LoadWorkspaces returns the workspaces of global.yml merged with those of
+// workspace.yml; the latter wins on a name clash.
+func LoadWorkspaces() (map[string]WorkspaceDef, error) {
+	gc, err := LoadGlobal()
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]WorkspaceDef{}
+	maps.Copy(out, gc.Workspaces)
+	b, err := os.ReadFile(workspaceFile())
+	if err != nil {
+		return out, nil
+	}
+	var wf struct {
+		Workspaces map[string]WorkspaceDef `yaml:"workspaces"`
+	}
+	if err := yaml.Unmarshal(b, &wf); err != nil {
+		return nil, fmt.Errorf("%s: %w", workspaceFile(), err)
+	}
+	maps.Copy(out, wf.Workspaces)
+	return out, nil
+}
+
+// LoadWorkspace returns the workspace called name.
+func LoadWorkspace(name string) (WorkspaceDef, error) {
+	all, err := LoadWorkspaces()
+	if err != nil {
+		return WorkspaceDef{}, err
+	}
+	ws, ok := all[name]
+	if !ok {
+		return WorkspaceDef{}, fmt.Errorf("workspace not found: %s", name)
+	}
+	return ws, nil
+}
+
+// WorkspacesOf returns the names of the workspaces project belongs to.
+func WorkspacesOf(project string) []string {
+	all, err := LoadWorkspaces()
+	if err != nil {
+		return nil
+	}
+	var out []string
+	for name, ws := range all {
+		members, err := ws.Members()
+		if err != nil {
+			continue
+		}
+		if slices.ContainsFunc(members, func(p RegProject) bool { return p.Name == project }) {
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// ProjectGlobal loads the global config for a run of project: a project
+// in exactly one workspace also sees its shared vars and funcs.
+func ProjectGlobal(project string) *GlobalConfig {
+	g, _ := LoadGlobal()
+	if wss := WorkspacesOf(project); len(wss) == 1 {
+		if ws, err := LoadWorkspace(wss[0]); err == nil {
+			g = g.WithWorkspace(wss[0], ws)
+		}
+	}
+	return g
+}
+
+// Members returns the workspace's projects so that every project comes
+// after the ones it depends on; otherwise the order of Projects is kept.
+func (ws WorkspaceDef) Members() ([]RegProject, error) {
+	projects, err := SelectProjects(strings.Join(ws.Projects, ","))
+	if err != nil {
+		return nil, err
+	}
+	index := map[string]bool{}
+	for _, p := range projects {
+		index[p.Name] = true
+	}
+	for name, deps := range ws.Deps {
+		for _, d := range append([]string{name}, deps...) {
+			if !index[d] {
+				return nil, fmt.Errorf("workspace deps: %s is not a member", d)
+			}
+		}
+	}
+	var out []RegProject
+	placed := map[string]bool{}
+	for len(out) < len(projects) {
+		progress := false
+		for _, p := range projects {
+			if placed[p.Name] || slices.ContainsFunc(ws.Deps[p.Name], func(d string) bool { return !placed[d] }) {
+				continue
+			}
+			out = append(out, p)
+			placed[p.Name] = true
+			progress = true
+			break
+		}
+		if !progress {
+			var left []string
+			for _, p := range projects {
+				if !placed[p.Name] {
+					left = append(left, p.Name)
+				}
+			}
+			return nil, fmt.Errorf("workspace deps form a cycle: %s", strings.Join(left, ", "))
+		}
+	}
+	return out, nil
+}
+
+// WithWorkspace returns a copy of g with the workspace's func and vars laid
+// over global.yml's, and #{global.workspace.name} set.
+func (g *GlobalConfig) WithWorkspace(name string, ws WorkspaceDef) *GlobalConfig {
+	if g == nil {
+		g = &GlobalConfig{}
+	}
+	out := &GlobalConfig{
+		Func:       maps.Clone(g.Func),
+		Raw:        maps.Clone(g.Raw),
+		Workspaces: g.Workspaces,
+		Notify:     g.Notify,
+		Hooks:      g.Hooks,
+	}
+	if out.Func == nil {
+		out.Func = map[string]FuncDef{}
+	}
+	if out.Raw == nil {
+		out.Raw = map[string]any{}
+	}
+	maps.Copy(out.Func, ws.Func)
+	vars := map[string]any{}
+	if v, ok := out.Raw["vars"].(map[string]any); ok {
+		maps.Copy(vars, v)
+	}
+	maps.Copy(vars, ws.Vars)
+	out.Raw["vars"] = vars
+	out.Raw["workspace"] = map[string]any{"name": name, "description": ws.Description}
+	return out
+}
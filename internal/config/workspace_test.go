@@ -0,0 +1,122 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func setupWorkspaces(t *testing.T, global, wsFile string) {
+	t.Helper()
+	td := t.TempDir()
+	home := filepath.Join(td, "cfg")
+	t.Setenv("PM_CONFIGS", home)
+	for _, name := range []string{"web", "api", "db"} {
+		if err := RegAdd(writeMeta(t, filepath.Join(td, name), name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(home, "global.yml"), []byte(global), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if wsFile != "" {
+		if err := os.WriteFile(filepath.Join(home, "workspace.yml"), []byte(wsFile), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func memberNames(t *testing.T, ws WorkspaceDef) string {
+	t.Helper()
+	ms, err := ws.Members()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, p := range ms {
+		names = append(names, p.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestWorkspace_MembersOrder(t *testing.T) {
+	setupWorkspaces(t, "", "")
+	ws := WorkspaceDef{Projects: []string{"web", "api", "db"}}
+	if got := memberNames(t, ws); got != "web,api,db" {
+		t.Errorf("without deps got %s", got)
+	}
+	ws.Deps = map[string][]string{"web": {"api"}, "api": {"db"}}
+	if got := memberNames(t, ws); got != "db,api,web" {
+		t.Errorf("with deps got %s", got)
+	}
+
+	ws.Deps = map[string][]string{"web": {"api"}, "api": {"web"}}
+	if _, err := ws.Members(); err == nil || !strings.Contains(err.Error(), "cycle: web, api") {
+		t.Errorf("expected cycle error, got %v", err)
+	}
+	ws = WorkspaceDef{Projects: []string{"web"}, Deps: map[string][]string{"web": {"db"}}}
+	if _, err := ws.Members(); err == nil || !strings.Contains(err.Error(), "db is not a member") {
+		t.Errorf("expected non-member error, got %v", err)
+	}
+}
+
+func TestLoadWorkspaces_FileOverridesGlobal(t *testing.T) {
+	global := `workspaces:
+  platform:
+    projects: [web, api]
+  data:
+    projects: [db]
+`
+	wsFile := `workspaces:
+  platform:
+    projects: [web, api, db]
+`
+	setupWorkspaces(t, global, wsFile)
+	all, err := LoadWorkspaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 || len(all["platform"].Projects) != 3 {
+		t.Fatalf("unexpected workspaces: %+v", all)
+	}
+	if got := WorkspacesOf("db"); !reflect.DeepEqual(got, []string{"data", "platform"}) {
+		t.Errorf("WorkspacesOf(db) = %v", got)
+	}
+	if got := WorkspacesOf("nope"); got != nil {
+		t.Errorf("WorkspacesOf(nope) = %v", got)
+	}
+	if _, err := LoadWorkspace("missing"); err == nil {
+		t.Error("expected error for unknown workspace")
+	}
+}
+
+func TestGlobalConfig_WithWorkspace(t *testing.T) {
+	g := &GlobalConfig{
+		Func: map[string]FuncDef{"a": {}},
+		Raw:  map[string]any{"vars": map[string]any{"x": "1", "y": "1"}},
+	}
+	ws := WorkspaceDef{
+		Description: "dev",
+		Vars:        map[string]any{"y": "2"},
+		Func:        map[string]FuncDef{"b": {}},
+	}
+	out := g.WithWorkspace("platform", ws)
+	want := map[string]any{"x": "1", "y": "2"}
+	if !reflect.DeepEqual(out.Raw["vars"], want) {
+		t.Errorf("vars = %v", out.Raw["vars"])
+	}
+	if len(out.Func) != 2 || len(g.Func) != 1 {
+		t.Errorf("func overlay leaked: %v / %v", out.Func, g.Func)
+	}
+	if g.Raw["vars"].(map[string]any)["y"] != "1" {
+		t.Error("original vars modified")
+	}
+	if w := out.Raw["workspace"].(map[string]any); w["name"] != "platform" {
+		t.Errorf("workspace = %v", w)
+	}
+	if (*GlobalConfig)(nil).WithWorkspace("p", ws).Raw["vars"] == nil {
+		t.Error("nil global: vars not set")
+	}
+}
@@ -0,0 +1,39 @@
+package docker
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+
+	"pm/internal/config"
+)
+
+// ComposeFile is the compose file of the project in root, as used by :up.
+func ComposeFile(meta *config.ProjectMeta, root string) string {
+	f := meta.Docker.ComposeFile
+	if f == "" {
+		f = "docker-compose.yml"
+	}
+	if filepath.IsAbs(f) {
+		return f
+	}
+	return filepath.Join(root, f)
+}
+
+// Services returns the sorted service names of a compose file.
+func Services(path string) ([]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cf struct {
+		Services map[string]any `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(b, &cf); err != nil {
+		return nil, err
+	}
+	return slices.Sorted(maps.Keys(cf.Services)), nil
+}
@@ -1,6 +1,8 @@
 package docker
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -128,3 +130,19 @@ func TestDockerUp_DefaultComposeFile(t *testing.T) {
 		t.Errorf("expected docker-compose.yml as default: %s", cmds[0])
 	}
 }
+
+func TestServices(t *testing.T) {
+	dir := t.TempDir()
+	compose := "services:\n  web:\n    build: .\n  db:\n    image: postgres\n"
+	if err := os.WriteFile(filepath.Join(dir, "compose.yaml"), []byte(compose), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	meta := &config.ProjectMeta{Docker: config.DockerDef{ComposeFile: "compose.yaml"}}
+	got, err := Services(ComposeFile(meta, dir))
+	if err != nil || strings.Join(got, ",") != "db,web" {
+		t.Fatalf("Services = %v, %v", got, err)
+	}
+	if _, err := Services(ComposeFile(&config.ProjectMeta{}, dir)); err == nil {
+		t.Error("expected an error for a missing docker-compose.yml")
+	}
+}
@@ -1,10 +1,17 @@
 package e2e
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 
+	"pm/internal/builder"
+	"pm/internal/cache"
 	"pm/internal/config"
 	. "pm/internal/e2e/test_utils"
 )
@@ -208,3 +215,538 @@ func TestE2E_FuncWithoutRequiredParam(t *testing.T) {
 		t.Errorf("function should not execute without required param")
 	}
 }
+
+func TestE2E_CommandPlugin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test plugin is a shell script")
+	}
+	tc := TestCase{
+		Name:         "cmd_plugin",
+		MetaFile:     "cmd_plugin.meta.yml",
+		ExpectedFile: "cmd_plugin.expected",
+		Command:      "plugged :build :db-snapshot :help",
+		Dialect:      "bash",
+	}
+	projDir := SetupCase(t, tc)
+
+	pluginsDir := MustMkdir(t, filepath.Join(os.Getenv("PM_CONFIGS"), "plugins"))
+	src := LoadTestdata(t, "pm-cmd-db-snapshot")
+	if err := os.WriteFile(filepath.Join(pluginsDir, "pm-cmd-db-snapshot"), []byte(src), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	script := GenerateScript(t, tc.Command, tc.Dialect)
+	expected := strings.ReplaceAll(LoadTestdata(t, tc.ExpectedFile), "__PROJECT_DIR__", projDir)
+	for _, line := range strings.Split(strings.TrimSpace(expected), "\n") {
+		AssertContains(t, script, line)
+	}
+	AssertContains(t, script, ":db-snapshot  - Dump the dev database (plugin)")
+
+	// the picker's preview lists the plugin instead of running it
+	meta, root, err := config.ResolveProject("plugged")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &builder.Builder{Meta: meta, Root: root, PluginsDir: pluginsDir, Preview: true}
+	preview := fmt.Sprint(b.Build([]string{":build", ":db-snapshot"}).Ops)
+	if !strings.Contains(preview, "# pm: :db-snapshot (plugin)") || strings.Contains(preview, "pg_dump") {
+		t.Fatalf("preview ran the plugin: %s", preview)
+	}
+}
+
+func TestE2E_CommandDeps(t *testing.T) {
+	tc := TestCase{
+		Name:         "command_deps",
+		MetaFile:     "command_deps.meta.yml",
+		ExpectedFile: "command_deps.expected",
+		Command:      "deps :test :loop",
+		Dialect:      "bash",
+	}
+	RunTestCase(t, tc)
+
+	// every dependency runs once, before its dependents
+	script := GenerateScript(t, tc.Command, tc.Dialect)
+	if strings.Count(script, "go generate") != 1 {
+		t.Fatalf("gen should run once:\n%s", script)
+	}
+	if strings.Index(script, "go generate") > strings.Index(script, "go build") ||
+		strings.Index(script, "go build") > strings.Index(script, "go test") {
+		t.Fatalf("wrong order:\n%s", script)
+	}
+	if strings.Contains(script, "echo never") {
+		t.Fatalf("cyclic command should not run:\n%s", script)
+	}
+}
+
+func TestE2E_CommandMatch(t *testing.T) {
+	tc := TestCase{
+		Name:         "command_match",
+		MetaFile:     "command_match.meta.yml",
+		ExpectedFile: "command_match.expected",
+		// project alias, command alias, prefixes, an ambiguous prefix and a typo
+		Command: "mt :b -v :bui :te :bu :tset",
+		Dialect: "bash",
+	}
+	RunTestCase(t, tc)
+}
+
+func TestE2E_FanOut(t *testing.T) {
+	tc := TestCase{
+		Name:         "fanout",
+		MetaFile:     "fanout.meta.yml",
+		ExpectedFile: "fanout.expected",
+		Command:      "@backend :test -v",
+		Dialect:      "bash",
+	}
+	projDir := SetupCase(t, tc)
+	// two more projects: worker is tagged and fails, web is not tagged
+	for name, meta := range map[string]string{
+		"worker": "info:\n  name: worker\n  root: .\n  tags: [backend]\ncommands:\n  test:\n    cmd: \"exit 3\"\n",
+		"web":    "info:\n  name: web\n  root: .\ncommands:\n  test:\n    cmd: \"echo web-test\"\n",
+	} {
+		dir := MustMkdir(t, filepath.Join(os.Getenv("PM_CONFIGS"), name))
+		if err := config.RegAdd(WriteMeta(t, dir, meta)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	script := GenerateScript(t, tc.Command, tc.Dialect)
+	expected := strings.ReplaceAll(LoadTestdata(t, tc.ExpectedFile), "__PROJECT_DIR__", projDir)
+	for _, line := range strings.Split(strings.TrimSpace(expected), "\n") {
+		AssertContains(t, script, line)
+	}
+	if strings.Contains(script, "web-test") {
+		t.Fatalf("untagged project selected:\n%s", script)
+	}
+	if all := GenerateScript(t, "* :test", "bash"); !strings.Contains(all, "web-test") {
+		t.Fatalf("'*' should select every project:\n%s", all)
+	}
+
+	out, ok := runShell(t, "bash", "set -e\n"+script)
+	if !ok {
+		t.Skip("bash not available")
+	}
+	for _, want := range []string{"api-test -v", "# pm: ok: api", "# pm: failed: worker"} {
+		AssertContains(t, out, want)
+	}
+}
+
+func TestE2E_CrossProjectCall(t *testing.T) {
+	tc := TestCase{
+		Name:         "cross_project",
+		MetaFile:     "cross_project.meta.yml",
+		ExpectedFile: "cross_project.expected",
+		Command:      "front :dev dev :loop",
+		Dialect:      "bash",
+	}
+	projDir := SetupCase(t, tc)
+	// the called project, with its own func and docker groups
+	apiDir := MustMkdir(t, filepath.Join(os.Getenv("PM_CONFIGS"), "api"))
+	apiMeta := `
+info:
+  name: api
+  root: ` + apiDir + `
+func:
+  gradle:
+    params:
+      task:
+        required: true
+    script: "./gradlew @{task} -Dapi=#{info.name}"
+commands:
+  migrate:
+    cmd: "_{gradle(task=flywayMigrate @{args})}"
+  back:
+    cmd: ":front:loop"
+  push:
+    cmd: "docker push #{global.vars.registry}/api"
+docker:
+  groups:
+    base: [postgres, redis]
+`
+	if err := config.RegAdd(WriteMeta(t, apiDir, apiMeta)); err != nil {
+		t.Fatal(err)
+	}
+
+	script := GenerateScript(t, tc.Command, tc.Dialect)
+	expected := LoadTestdata(t, tc.ExpectedFile)
+	expected = strings.NewReplacer("__PROJECT_DIR__", projDir, "__API_DIR__", apiDir).Replace(expected)
+	for _, line := range strings.Split(strings.TrimSpace(expected), "\n") {
+		AssertContains(t, script, line)
+	}
+
+	// the callee renders with its own global config, without the vars of
+	// the caller's workspace
+	home := os.Getenv("PM_CONFIGS")
+	WriteGlobal(t, home, "vars:\n  registry: docker.io/me\nworkspaces:\n  web:\n    root: "+home+"\n    projects: [front]\n    vars:\n      registry: registry.local\n")
+	script = GenerateScript(t, "front :release", tc.Dialect)
+	AssertContains(t, script, "docker push docker.io/me/api\n")
+}
+
+func TestE2E_Workspace(t *testing.T) {
+	tc := TestCase{
+		Name:         "workspace",
+		MetaFile:     "workspace.meta.yml",
+		ExpectedFile: "workspace.expected",
+		Command:      "ws:platform :up :build",
+		Dialect:      "bash",
+	}
+	webDir := SetupCase(t, tc)
+	home := os.Getenv("PM_CONFIGS")
+	WriteGlobal(t, home, strings.ReplaceAll(LoadTestdata(t, "workspace.global.yml"), "__ROOT__", home))
+	dirs := map[string]string{"web": webDir}
+	for _, name := range []string{"api", "db"} {
+		dirs[name] = MustMkdir(t, filepath.Join(home, name))
+		meta := "info:\n  name: " + name + "\n  root: .\ndocker:\n  compose_file: " + name + ".yml\n"
+		if err := config.RegAdd(WriteMeta(t, dirs[name], meta)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// :up in every member, dependencies first; :build only where defined,
+	// with the workspace's vars over global.yml
+	script := GenerateScript(t, tc.Command, tc.Dialect)
+	want := []string{
+		"pushd " + dirs["db"] + " >/dev/null\n", "docker compose -f db.yml up -d\n", "popd >/dev/null\n",
+		"pushd " + dirs["api"] + " >/dev/null\n", "docker compose -f api.yml up -d\n", "popd >/dev/null\n",
+		"pushd " + dirs["web"] + " >/dev/null\n", "docker compose -f docker-compose.yml up -d\n", "popd >/dev/null\n",
+		"echo '# pm: db: no :build, skipped'",
+		"docker build -t registry.local/web .",
+	}
+	rest := script
+	for _, w := range want {
+		i := strings.Index(rest, w)
+		if i < 0 {
+			t.Fatalf("want %q in order in:\n%s", w, script)
+		}
+		rest = rest[i+len(w):]
+	}
+
+	// workspace-level command in the workspace root
+	script = GenerateScript(t, "ws:platform :bootstrap", "bash")
+	AssertContains(t, script, "pushd "+home+" >/dev/null")
+	AssertContains(t, script, "pushd "+dirs["db"]+" >/dev/null\n")
+	AssertContains(t, script, "docker compose -f db.yml up -d\n")
+	AssertContains(t, script, "echo seeded platform")
+
+	// a member run on its own still sees the workspace vars
+	if got := config.WorkspacesOf("web"); len(got) != 1 || got[0] != "platform" {
+		t.Fatalf("WorkspacesOf(web) = %v", got)
+	}
+	AssertContains(t, GenerateScript(t, "web :build", "bash"), "docker build -t registry.local/web .")
+	// and so it does in a fan-out
+	AssertContains(t, GenerateScript(t, "web,api :build", "bash"), "docker build -t registry.local/web .")
+}
+
+func TestE2E_Describe(t *testing.T) {
+	tc := TestCase{Name: "describe", MetaFile: "command_match.meta.yml"}
+	dir := SetupCase(t, tc)
+	meta, root, err := config.ResolveProject("mt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := (&builder.Builder{Meta: meta, Root: root}).Describe()
+	if d.Name != "matcher" || d.Root != dir || d.Docker != nil {
+		t.Fatalf("unexpected description: %+v", d)
+	}
+	var names []string
+	for _, c := range d.Commands {
+		names = append(names, c.Source+":"+c.Name)
+	}
+	want := "meta:bench meta:build meta:bundle meta:test builtin:help builtin:up"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("commands = %s, want %s", got, want)
+	}
+	if b := d.Commands[1]; b.Description != "Build" || b.Aliases[0] != "b" || b.Uses[0] != "args" {
+		t.Errorf("build = %+v", b)
+	}
+}
+
+func TestE2E_CommandParams(t *testing.T) {
+	RunTestCase(t, TestCase{
+		Name:         "command_params",
+		MetaFile:     "command_params.meta.yml",
+		ExpectedFile: "command_params.expected",
+		// --name=value, --name value, defaults, a missing and an invalid value
+		Command: "params :deploy --env=prod --dry-run --replicas 3 :deploy --env dev :deploy :deploy --env=qa",
+		Dialect: "bash",
+	})
+}
+
+func TestE2E_HelpRich(t *testing.T) {
+	RunTestCase(t, TestCase{
+		Name:         "help_rich",
+		MetaFile:     "help_rich.meta.yml",
+		ExpectedFile: "help_rich.expected",
+		// the overview, then one command expanded with its params
+		Command: "rich :help :help deploy --env=prod",
+		Dialect: "bash",
+	})
+}
+
+func TestE2E_StepTimings(t *testing.T) {
+	tc := TestCase{
+		Name:         "steps",
+		MetaFile:     "steps.meta.yml",
+		ExpectedFile: "steps.expected",
+		Command:      "steps :build fast :test",
+		Dialect:      "bash",
+	}
+	RunTestCase(t, tc)
+
+	out, ok := runShell(t, "bash", GenerateScript(t, tc.Command, "bash"))
+	if !ok {
+		t.Skip("bash is required to run the script")
+	}
+	for _, want := range []string{"building fast\n", "tests failed\n", "# pm: :build fast ", "# pm: :test ", "s  ok\n"} {
+		AssertContains(t, out, want)
+	}
+}
+
+// A failing step ends the wrappers' errexit scripts with its status, after
+// the table of the steps run so far.
+func TestE2E_StepFailsInWrapper(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the wrappers are shell scripts")
+	}
+	if testing.Short() {
+		t.Skip("builds pm-bin")
+	}
+	SetupCase(t, TestCase{Name: "failing", MetaFile: "step_fails.meta.yml"})
+	bin := filepath.Join(t.TempDir(), "pm-bin")
+	if out, err := exec.Command("go", "build", "-o", bin, "pm/cmd/pm-bin").CombinedOutput(); err != nil {
+		t.Fatalf("go build: %v\n%s", err, out)
+	}
+	for shell, wrapper := range map[string]string{"bash": "pm", "sh": "pm.sh"} {
+		t.Run(wrapper, func(t *testing.T) {
+			if _, err := exec.LookPath(shell); err != nil {
+				t.Skip(shell + " is required to run the wrapper")
+			}
+			cmd := exec.Command(shell, filepath.Join("..", "..", "shell", wrapper), "failing", ":build", ":test", ":deploy")
+			cmd.Env = append(os.Environ(), "PM_BIN="+bin)
+			out, err := cmd.Output()
+			var ee *exec.ExitError
+			if !errors.As(err, &ee) || ee.ExitCode() != 3 {
+				t.Fatalf("exit = %v, want status 3\n%s", err, out)
+			}
+			for _, want := range []string{"building\n", "testing\n", "# pm: :build ", "s  ok\n", "# pm: :test ", "s  exit 3\n"} {
+				AssertContains(t, string(out), want)
+			}
+			if s := string(out); strings.Contains(s, "tests passed") || strings.Contains(s, "deploying") {
+				t.Fatalf("ran past the failing line:\n%s", s)
+			}
+		})
+	}
+}
+
+func TestE2E_Notify(t *testing.T) {
+	tc := TestCase{
+		Name:         "notify",
+		MetaFile:     "notify.meta.yml",
+		GlobalFile:   "notify.global.yml",
+		ExpectedFile: "notify.expected",
+		Command:      "notify :build :deploy :lint",
+		Dialect:      "bash",
+	}
+	RunTestCase(t, tc)
+
+	// :lint is the only step without a notification
+	script := GenerateScript(t, tc.Command, "bash")
+	if got := strings.Count(script, "if [ $((__pm_e - __pm_t)) -ge "); got != 2 {
+		t.Fatalf("want 2 notifications, got %d:\n%s", got, script)
+	}
+}
+
+func TestE2E_Hooks(t *testing.T) {
+	tc := TestCase{
+		Name:         "hooks",
+		MetaFile:     "hooks.meta.yml",
+		GlobalFile:   "hooks.global.yml",
+		ExpectedFile: "hooks.expected",
+		Command:      "hooks :deploy --env=prod :build -v",
+		Dialect:      "bash",
+	}
+	RunTestCase(t, tc)
+
+	script := GenerateScript(t, tc.Command, "bash")
+	if got := strings.Count(script, "rm -rf tmp"); got != 2 {
+		t.Fatalf("want the failure hook in both steps, got %d:\n%s", got, script)
+	}
+	if strings.Index(script, "sdk env") > strings.Index(script, "__pm_t=") || strings.Index(script, "echo done") < strings.LastIndex(script, "__pm_steps=") {
+		t.Fatalf("before_all and after_all must wrap the steps:\n%s", script)
+	}
+}
+
+// TestE2E_HooksEveryMode: failure hooks run for a fan-out block, a raw
+// line and a workspace member, and a member's own hooks wrap its runs.
+func TestE2E_HooksEveryMode(t *testing.T) {
+	SetupCase(t, TestCase{Name: "hooks", MetaFile: "hooks.meta.yml"})
+	home := os.Getenv("PM_CONFIGS")
+	WriteGlobal(t, home, "hooks:\n  on_failure: [\"echo global-cleanup\"]\nworkspaces:\n  pair:\n    root: "+home+"\n    projects: [good, bad]\n")
+	dirs := map[string]string{}
+	for name, meta := range map[string]string{
+		"good": "info:\n  name: good\n  root: .\ncommands:\n  test:\n    cmd: \"echo good-test\"\n",
+		"bad":  "info:\n  name: bad\n  root: .\nhooks:\n  before_all: \"echo before-bad\"\n  on_failure: [\"echo cleanup\", \"pwd\"]\ncommands:\n  test:\n    cmd: \"false\"\n",
+	} {
+		dirs[name] = MustMkdir(t, filepath.Join(home, name))
+		if err := config.RegAdd(WriteMeta(t, dirs[name], meta)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		command string
+		want    []string
+	}{
+		{"good,bad :test", []string{"good-test\n", "cleanup\n" + dirs["bad"] + "\n", "global-cleanup\n", "# pm: failed: bad"}},
+		{"bad false", []string{"before-bad\n", "cleanup\n" + dirs["bad"] + "\n", "global-cleanup\n"}},
+		{"ws:pair :test", []string{"good-test\n", "before-bad\n", "cleanup\n" + dirs["bad"] + "\n", "global-cleanup\n"}},
+	} {
+		script := GenerateScript(t, tc.command, "bash")
+		out, ok := runShell(t, "bash", "set -e\n"+script)
+		if !ok {
+			t.Skip("bash not available")
+		}
+		rest := out
+		for _, w := range tc.want {
+			i := strings.Index(rest, w)
+			if i < 0 {
+				t.Fatalf("%s: want %q in order in:\n%s", tc.command, w, out)
+			}
+			rest = rest[i+len(w):]
+		}
+		if n := strings.Count(out, "\n"+dirs["bad"]+"\n"); n != 1 {
+			t.Fatalf("%s: want the failure hooks once, got %d:\n%s", tc.command, n, out)
+		}
+	}
+}
+
+// TestE2E_Inputs: a command whose inputs are unchanged since its last
+// successful run is left out of the plan until they or its args change,
+// its outputs are deleted, or --force is given.
+func TestE2E_Inputs(t *testing.T) {
+	dir := SetupCase(t, TestCase{Name: "inputs", MetaFile: "inputs.meta.yml"})
+	t.Setenv("PM_CACHE", t.TempDir())
+	for _, f := range []string{"proto/api/v1.proto", "src/main.go", "go.mod", "gen/api.pb.go"} {
+		path := filepath.Join(dir, filepath.FromSlash(f))
+		MustMkdir(t, filepath.Dir(path))
+		if err := os.WriteFile(path, []byte(f), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	meta, root, err := config.ResolveProject("inputs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const shell = 4242
+	// build returns the plan's ops as text; status is the script's, done
+	// the count of stamps it reported as finished
+	buildDone := func(force bool, status, done int, tail ...string) string {
+		t.Helper()
+		b := &builder.Builder{Meta: meta, Root: root, Cache: &cache.Cache{Force: force}}
+		pl := b.Build(tail)
+		if err := b.Cache.SavePending(shell); err != nil {
+			t.Fatal(err)
+		}
+		if err := cache.Commit(shell, status, done); err != nil {
+			t.Fatal(err)
+		}
+		return fmt.Sprint(pl.Ops)
+	}
+	build := func(force bool, status int, tail ...string) string {
+		t.Helper()
+		return buildDone(force, status, 0, tail...)
+	}
+	skipped := func(cmd string) string {
+		return "# pm: :" + cmd + " is up to date"
+	}
+
+	if got := build(false, 1, ":build", ":lint"); strings.Contains(got, skipped("build")) {
+		t.Fatalf("first run skipped :build: %s", got)
+	}
+	// the failed run stored nothing
+	if got := build(false, 0, ":build", ":lint"); !strings.Contains(got, "buf generate") || !strings.Contains(got, "go build") {
+		t.Fatalf("want every command after a failed run: %s", got)
+	}
+	got := build(false, 0, ":build", ":lint")
+	for _, want := range []string{skipped("codegen"), skipped("build"), "golangci-lint run"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("want %q in %s", want, got)
+		}
+	}
+	if strings.Contains(got, "go build") {
+		t.Fatalf(":build ran with unchanged inputs: %s", got)
+	}
+
+	if got := build(false, 0, ":build", "-race"); !strings.Contains(got, "go build -race") {
+		t.Fatalf("other args should run :build: %s", got)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "src", "main.go"), []byte("changed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := build(false, 0, ":build"); !strings.Contains(got, "go build") || !strings.Contains(got, skipped("codegen")) {
+		t.Fatalf("a changed input should run :build only: %s", got)
+	}
+	if err := os.RemoveAll(filepath.Join(dir, "gen")); err != nil {
+		t.Fatal(err)
+	}
+	if got := build(false, 0, ":codegen"); !strings.Contains(got, "buf generate") {
+		t.Fatalf("missing outputs should run :codegen: %s", got)
+	}
+	if got := build(true, 0, ":build"); strings.Contains(got, skipped("build")) {
+		t.Fatalf("--force skipped :build: %s", got)
+	}
+
+	// a failing :build keeps the stamp of the :codegen step before it
+	if err := os.RemoveAll(filepath.Join(dir, "gen")); err != nil {
+		t.Fatal(err)
+	}
+	MustMkdir(t, filepath.Join(dir, "gen"))
+	for f, content := range map[string]string{"gen/api.pb.go": "regenerated", "src/main.go": "broken"} {
+		if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(f)), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got = buildDone(true, 1, 1, ":codegen", ":build")
+	if !strings.Contains(got, "{"+cache.DoneVar+" 0}") || !strings.Contains(got, "{"+cache.DoneVar+" 1}]") {
+		t.Fatalf("want the steps counted in %s as their last op: %s", cache.DoneVar, got)
+	}
+	if got := build(false, 0, ":codegen", ":build"); !strings.Contains(got, skipped("codegen")) || !strings.Contains(got, "go build") {
+		t.Fatalf("want :codegen kept and :build run again: %s", got)
+	}
+
+	// what a command renders to is an input too
+	t.Setenv("GOFLAGS", "-mod=mod")
+	meta.Commands["lint"] = config.CommandDef{Inputs: []string{"go.mod"}, Cmd: "golangci-lint run ${GOFLAGS}"}
+	build(false, 0, ":lint")
+	if got := build(false, 0, ":lint"); !strings.Contains(got, skipped("lint")) {
+		t.Fatalf("unchanged :lint ran: %s", got)
+	}
+	t.Setenv("GOFLAGS", "-mod=vendor")
+	if got := build(false, 0, ":lint"); !strings.Contains(got, "-mod=vendor") {
+		t.Fatalf("a changed ${ENV} should run :lint: %s", got)
+	}
+}
+
+// TestE2E_WatchPatterns: watch mode watches the watch: or inputs: of the
+// typed commands and their dependencies, or every file when one declares
+// neither.
+func TestE2E_WatchPatterns(t *testing.T) {
+	SetupCase(t, TestCase{Name: "watch", MetaFile: "watch.meta.yml"})
+	meta, root, err := config.ResolveProject("watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &builder.Builder{Meta: meta, Root: root}
+	for _, tc := range []struct {
+		tail     string
+		patterns string
+	}{
+		{":build", "go.mod proto/**/*.proto src/**/*.go"},
+		{":tdd", "proto/**/*.proto src/**/*.go test/**"},
+		{":build :lint", ""},
+		{"go test", ""},
+	} {
+		if got := strings.Join(b.Watch(strings.Fields(tc.tail)), " "); got != tc.patterns {
+			t.Errorf("Watch(%s) = %q, want %q", tc.tail, got, tc.patterns)
+		}
+	}
+}
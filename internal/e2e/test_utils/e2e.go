@@ -3,11 +3,10 @@ package test_utils
 import (
 	"os"
 	"path/filepath"
+	"pm/internal/builder"
 	"pm/internal/config"
-	"pm/internal/dsl"
 	"pm/internal/plan"
 	"pm/internal/render"
-	"pm/internal/templ"
 	"strings"
 	"testing"
 )
@@ -27,6 +26,30 @@ type TestCase struct {
 func RunTestCase(t *testing.T, tc TestCase) {
 	t.Helper()
 
+	projDir := SetupCase(t, tc)
+
+	// Генерируем скрипт
+	script := GenerateScript(t, tc.Command, tc.Dialect)
+
+	// Читаем ожидаемый результат
+	expected := LoadTestdata(t, tc.ExpectedFile)
+	expected = strings.ReplaceAll(expected, "__PROJECT_DIR__", projDir)
+
+	// Проверяем, что все строки из expected присутствуют в script
+	for _, line := range strings.Split(strings.TrimSpace(expected), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		AssertContains(t, script, line)
+	}
+}
+
+// SetupCase готовит PM_CONFIGS, meta и global файлы сценария и регистрирует
+// проект; возвращает директорию проекта
+func SetupCase(t *testing.T, tc TestCase) string {
+	t.Helper()
+
 	td := t.TempDir()
 	t.Setenv("PM_CONFIGS", td)
 
@@ -53,22 +76,7 @@ func RunTestCase(t *testing.T, tc TestCase) {
 	if err := config.RegAdd(metaPath); err != nil {
 		t.Fatalf("RegAdd: %v", err)
 	}
-
-	// Генерируем скрипт
-	script := GenerateScript(t, tc.Command, tc.Dialect)
-
-	// Читаем ожидаемый результат
-	expected := LoadTestdata(t, tc.ExpectedFile)
-	expected = strings.ReplaceAll(expected, "__PROJECT_DIR__", projDir)
-
-	// Проверяем, что все строки из expected присутствуют в script
-	for _, line := range strings.Split(strings.TrimSpace(expected), "\n") {
-		line = strings.TrimSpace(line)
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-		AssertContains(t, script, line)
-	}
+	return projDir
 }
 
 // loadTestdata загружает содержимое файла из testdata
@@ -89,68 +97,45 @@ func GenerateScript(t *testing.T, command string, dialect string) string {
 	projectRef := tail[0]
 	tail = tail[1:]
 
-	meta, root, err := config.ResolveProject(projectRef)
-	if err != nil {
-		t.Fatalf("ResolveProject: %v", err)
-	}
-	global, _ := config.LoadGlobal()
+	// плагины ищем в PM_CONFIGS/plugins, как main по умолчанию в ~/.config/pm
+	pluginsDir := filepath.Join(os.Getenv("PM_CONFIGS"), "plugins")
 
-	chunks := dsl.SplitColonCommands(tail)
-	pl := plan.New()
-	pl.Pushd(root)
-
-	// raw режим
-	if len(chunks) == 1 && chunks[0].Name == "__RAW__" {
-		line := strings.Join(chunks[0].Args, " ")
-		if strings.TrimSpace(line) != "" {
-			pl.Run(line)
-		}
-		pl.Popd()
-		out, err := render.Render(pl, dialect, "")
+	var pl *plan.Plan
+	if name, ok := strings.CutPrefix(projectRef, config.WorkspacePrefix); ok {
+		ws, err := config.LoadWorkspace(name)
 		if err != nil {
-			t.Fatalf("render: %v", err)
+			t.Fatalf("LoadWorkspace: %v", err)
 		}
-		return out
-	}
-
-	// именованные :команды
-	for _, ch := range chunks {
-		if ch.Name == "help" {
-			pl.Echo("# pm: project commands:")
-			for k, v := range meta.Commands {
-				desc := strings.TrimSpace(v.Description)
-				pl.Echo("  :" + k + "  - " + desc)
-			}
-			pl.Echo("# pm: built-ins: :up (docker compose up -d ...)")
-			continue
-		}
-		if ch.Name == "up" {
-			cmds := plan.DockerUp(meta, ch.Args)
-			for _, c := range cmds {
-				pl.Run(c)
-			}
-			continue
+		global, _ := config.LoadGlobal()
+		self, members, err := builder.NewWorkspace(name, ws, global, pluginsDir)
+		if err != nil {
+			t.Fatalf("NewWorkspace: %v", err)
 		}
-		cmd, ok := meta.Commands[ch.Name]
-		if !ok {
-			pl.Echo("# pm: unknown command :" + ch.Name)
-			continue
+		pl = builder.Workspace(self, members, tail)
+	} else if config.IsSelection(projectRef) {
+		// fan-out: @tag, a,b, '*'
+		projects, err := config.SelectProjects(projectRef)
+		if err != nil {
+			t.Fatalf("SelectProjects: %v", err)
 		}
-		params := map[string]string{"args": strings.Join(ch.Args, " ")}
-		for _, raw := range cmd.AsLines() {
-			rendered, err := templ.RenderString(raw, params, meta, global, nil)
+		var bs []*builder.Builder
+		for _, p := range projects {
+			meta, root, err := config.LoadEntry(p)
 			if err != nil {
-				pl.Echo("# pm: template error: " + err.Error())
-				continue
-			}
-			if strings.TrimSpace(rendered) != "" {
-				pl.Run(rendered)
+				t.Fatalf("LoadEntry: %v", err)
 			}
+			bs = append(bs, &builder.Builder{Meta: meta, Root: root, Global: config.ProjectGlobal(p.Name), PluginsDir: pluginsDir})
 		}
+		pl = builder.FanOut(bs, tail)
+	} else {
+		meta, root, err := config.ResolveProject(projectRef)
+		if err != nil {
+			t.Fatalf("ResolveProject: %v", err)
+		}
+		b := &builder.Builder{Meta: meta, Root: root, Global: config.ProjectGlobal(meta.Info.Name), PluginsDir: pluginsDir}
+		pl = b.Build(tail)
 	}
-
-	pl.Popd()
-	out, err := render.Render(pl, dialect, "")
+	out, err := render.Render(pl, dialect, pluginsDir)
 	if err != nil {
 		t.Fatalf("render: %v", err)
 	}
@@ -0,0 +1,94 @@
+package e2e
+
+import (
+	"os/exec"
+	"sort"
+	"strings"
+	"testing"
+
+	. "pm/internal/e2e/test_utils"
+)
+
+// zshParityCases reuses the bash scenarios; runnable ones only call echo and
+// are safe to execute when both shells are installed.
+var zshParityCases = []struct {
+	tc       TestCase
+	runnable bool
+}{
+	{TestCase{Name: "raw_command", MetaFile: "raw_command.meta.yml", Command: "rawproj docker compose ps"}, false},
+	{TestCase{Name: "command_sequence", MetaFile: "command_sequence.meta.yml", Command: "subzero :build -Pprod :run"}, false},
+	{TestCase{Name: "docker_up_groups", MetaFile: "docker_up_groups.meta.yml", Command: "dock :up @base api"}, false},
+	{TestCase{Name: "multiple_commands", MetaFile: "multiple_commands.meta.yml", Command: "multi :clean dist :build -j4 :test ./..."}, false},
+	{TestCase{Name: "global_funcs", MetaFile: "global_funcs.meta.yml", GlobalFile: "global_funcs.global.yml", Command: "gproj :hello"}, true},
+	{TestCase{Name: "global_vars_funcs", MetaFile: "global_vars_funcs.meta.yml", GlobalFile: "global_vars_funcs.global.yml", Command: "global :welcome"}, true},
+	{TestCase{Name: "help_echo", MetaFile: "help_echo.meta.yml", Command: "helpme :help"}, true},
+	{TestCase{Name: "env_substitution", MetaFile: "env_substitution.meta.yml", Command: "envtest :show", EnvVars: map[string]string{"TEST_VAR": "hello_world"}}, true},
+	{TestCase{Name: "config_path_substitution", MetaFile: "config_path_substitution.meta.yml", Command: "cfgtest :info"}, true},
+	{TestCase{Name: "multiline_func", MetaFile: "multiline_func.meta.yml", Command: "multiline :deploy"}, true},
+	{TestCase{Name: "unknown_command", MetaFile: "unknown_command.meta.yml", Command: "unknown :nonexistent"}, true},
+}
+
+func TestE2E_ZshMatchesBash(t *testing.T) {
+	for _, c := range zshParityCases {
+		t.Run(c.tc.Name, func(t *testing.T) {
+			SetupCase(t, c.tc)
+			bash := GenerateScript(t, c.tc.Command, "bash")
+			zsh := GenerateScript(t, c.tc.Command, "zsh")
+
+			// every command line of the bash script appears in the zsh one
+			// (:help lists commands in map order, so order isn't compared)
+			for _, line := range userLines(bash) {
+				if strings.HasPrefix(line, "echo '") {
+					line = "print -r -- " + strings.TrimPrefix(line, "echo ")
+				}
+				AssertContains(t, zsh, line+"\n")
+			}
+			AssertContains(t, zsh, "emulate -L zsh")
+			AssertContains(t, zsh, "setopt err_return local_options")
+
+			if !c.runnable {
+				return
+			}
+			bashOut, okB := runShell(t, "bash", bash)
+			zshOut, okZ := runShell(t, "zsh", zsh)
+			if !okB || !okZ {
+				t.Skip("bash and zsh are required to compare execution")
+			}
+			if sortedLines(bashOut) != sortedLines(zshOut) {
+				t.Fatalf("output differs\n--- bash ---\n%s\n--- zsh ---\n%s", bashOut, zshOut)
+			}
+		})
+	}
+}
+
+// userLines drops the renderer's own scaffolding (begin/end markers,
+// directory changes and errexit toggles) and keeps what came from the plan's run/echo ops.
+func userLines(script string) []string {
+	var out []string
+	for _, l := range strings.Split(script, "\n") {
+		switch {
+		case l == "", strings.HasPrefix(l, "# pm "),
+			strings.HasPrefix(l, "pushd "), strings.HasPrefix(l, "popd"),
+			strings.HasPrefix(l, "__pm_o="), strings.HasPrefix(l, "case $__pm_o "):
+			continue
+		}
+		out = append(out, l)
+	}
+	return out
+}
+
+func sortedLines(s string) string {
+	lines := strings.Split(s, "\n")
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+func runShell(t *testing.T, shell, script string) (string, bool) {
+	t.Helper()
+	if _, err := exec.LookPath(shell); err != nil {
+		return "", false
+	}
+	// only stdout is compared; shells word their diagnostics differently
+	out, _ := exec.Command(shell, "-c", script).Output()
+	return string(out), true
+}
@@ -0,0 +1,250 @@
+package export
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"pm/internal/builder"
+	"pm/internal/config"
+	"pm/internal/plan"
+)
+
+// Formats lists the supported --to values.
+var Formats = []string{"make", "just", "taskfile"}
+
+// FileName is the conventional file name for a format.
+func FileName(format string) string {
+	switch format {
+	case "make":
+		return "Makefile"
+	case "just":
+		return "justfile"
+	case "taskfile":
+		return "Taskfile.yml"
+	default:
+		return ""
+	}
+}
+
+// argsMark stands in for @{args} while commands go through the plan
+// pipeline; each format swaps it for its own variable syntax.
+const argsMark = "\x00ARGS\x00"
+
+// task is one exported target, format independent.
+type task struct {
+	Name    string
+	Desc    string
+	Lines   []string
+	UseArgs bool
+}
+
+// Generate converts the project's commands (with function calls expanded),
+// dependencies and the docker :up built-in into a file for format.
+// Dependencies are expanded at the start of the recipe rather than mapped
+// to the format's own: they run in order and without the caller's args,
+// as in pm, where make -j or task would run them in parallel and make
+// would pass them $(ARGS).
+func Generate(b *builder.Builder, format string) (string, error) {
+	tasks, err := collect(b)
+	if err != nil {
+		return "", err
+	}
+	switch format {
+	case "make":
+		return makefile(b.Meta, tasks), nil
+	case "just":
+		return justfile(b.Meta, tasks), nil
+	case "taskfile":
+		return taskfile(b.Meta, tasks)
+	default:
+		return "", fmt.Errorf("unknown export format %q (want %s)", format, strings.Join(Formats, "|"))
+	}
+}
+
+func collect(b *builder.Builder) ([]task, error) {
+	keep := *b
+	keep.KeepEnv = true
+
+	names := make([]string, 0, len(b.Meta.Commands))
+	for k := range b.Meta.Commands {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var tasks []task
+	for _, name := range names {
+		if name == "up" || name == "help" {
+			// shadowed by built-ins in pm as well
+			continue
+		}
+		cmd := b.Meta.Commands[name]
+		pl := plan.New()
+		if err := keep.AddDeps(pl, name); err != nil {
+			return nil, err
+		}
+		keep.AddCommand(pl, cmd, []string{argsMark})
+		t := task{Name: name, Desc: strings.TrimSpace(cmd.Description)}
+		// calls into other projects push their root; task runners start
+		// in ours, so pushd/popd become plain cd's
+		dirs := []string{b.Root}
+		for _, op := range pl.Ops {
+			switch v := op.(type) {
+			case plan.OpPushd:
+				dirs = append(dirs, v.Dir)
+				t.Lines = append(t.Lines, "cd "+quote(v.Dir))
+				continue
+			case plan.OpPopd:
+				dirs = dirs[:len(dirs)-1]
+				t.Lines = append(t.Lines, "cd "+quote(dirs[len(dirs)-1]))
+				continue
+			}
+			line := opLine(op)
+			if line == "" {
+				continue
+			}
+			if strings.Contains(line, argsMark) {
+				t.UseArgs = true
+			}
+			t.Lines = append(t.Lines, line)
+		}
+		tasks = append(tasks, t)
+	}
+	tasks = append(tasks, dockerTasks(b.Meta)...)
+	return tasks, nil
+}
+
+// dockerTasks mirrors the :up built-in: `up` takes services/@groups as args,
+// and every group gets its own up-<group> target.
+func dockerTasks(meta *config.ProjectMeta) []task {
+	d := meta.Docker
+	if strings.TrimSpace(d.ComposeFile) == "" && len(d.Groups) == 0 {
+		return nil
+	}
+	up := plan.DockerUp(meta, nil)[0]
+	tasks := []task{{Name: "up", Desc: "docker compose up -d [services]", Lines: []string{up + " " + argsMark}, UseArgs: true}}
+	groups := make([]string, 0, len(d.Groups))
+	for g := range d.Groups {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+	for _, g := range groups {
+		tasks = append(tasks, task{
+			Name:  "up-" + g,
+			Desc:  "docker compose up -d @" + g,
+			Lines: plan.DockerUp(meta, []string{"@" + g}),
+		})
+	}
+	return tasks
+}
+
+func opLine(op plan.Op) string {
+	switch v := op.(type) {
+	case plan.OpRun:
+		return v.Line
+	case plan.OpEcho:
+		return "echo " + quote(v.Line)
+	case plan.OpEnv:
+		return fmt.Sprintf("export %s=%s", v.Name, quote(v.Value))
+	default:
+		return ""
+	}
+}
+
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func header(meta *config.ProjectMeta, comment string) string {
+	return fmt.Sprintf("%s Generated by `pm export %s`; edit .pm.meta.yml instead.\n", comment, meta.Info.Name)
+}
+
+func makefile(meta *config.ProjectMeta, tasks []task) string {
+	var buf strings.Builder
+	buf.WriteString(header(meta, "#"))
+	buf.WriteString("\n# Pass extra arguments with ARGS, e.g. make build ARGS=\"-x test\"\n")
+	buf.WriteString("ARGS ?=\n\n")
+	// one shell per recipe, like a pm command
+	buf.WriteString(".ONESHELL:\n")
+	names := make([]string, len(tasks))
+	for i, t := range tasks {
+		names[i] = t.Name
+	}
+	fmt.Fprintf(&buf, ".PHONY: %s\n", strings.Join(names, " "))
+	for _, t := range tasks {
+		buf.WriteString("\n")
+		if t.Desc != "" {
+			fmt.Fprintf(&buf, "## %s\n", t.Desc)
+		}
+		fmt.Fprintf(&buf, "%s:\n", t.Name)
+		for _, l := range t.Lines {
+			l = strings.ReplaceAll(l, "$", "$$")
+			l = strings.ReplaceAll(l, argsMark, "$(ARGS)")
+			fmt.Fprintf(&buf, "\t%s\n", strings.TrimRight(l, " "))
+		}
+	}
+	return buf.String()
+}
+
+func justfile(meta *config.ProjectMeta, tasks []task) string {
+	var buf strings.Builder
+	buf.WriteString(header(meta, "#"))
+	for _, t := range tasks {
+		buf.WriteString("\n")
+		if t.Desc != "" {
+			fmt.Fprintf(&buf, "# %s\n", t.Desc)
+		}
+		buf.WriteString(t.Name)
+		if t.UseArgs {
+			buf.WriteString(" *args")
+		}
+		buf.WriteString(":\n")
+		for i, l := range t.Lines {
+			l = strings.ReplaceAll(l, "{{", "{{{{")
+			l = strings.TrimRight(strings.ReplaceAll(l, argsMark, "{{args}}"), " ")
+			if i < len(t.Lines)-1 {
+				// keep the recipe in one shell, like a pm command
+				l += " && \\"
+			}
+			fmt.Fprintf(&buf, "    %s\n", l)
+		}
+	}
+	return buf.String()
+}
+
+type taskfileDoc struct {
+	Version string                   `yaml:"version"`
+	Tasks   map[string]taskfileEntry `yaml:"tasks"`
+}
+
+type taskfileEntry struct {
+	Desc string   `yaml:"desc,omitempty"`
+	Cmds []string `yaml:"cmds"`
+}
+
+func taskfile(meta *config.ProjectMeta, tasks []task) (string, error) {
+	doc := taskfileDoc{Version: "3", Tasks: map[string]taskfileEntry{}}
+	for _, t := range tasks {
+		lines := make([]string, len(t.Lines))
+		for i, l := range t.Lines {
+			l = strings.ReplaceAll(l, "{{", `{{"{{"}}`)
+			lines[i] = strings.TrimRight(strings.ReplaceAll(l, argsMark, "{{.CLI_ARGS}}"), " ")
+		}
+		e := taskfileEntry{Desc: t.Desc}
+		if len(lines) > 0 {
+			// one script, like a pm command
+			e.Cmds = []string{strings.Join(lines, "\n")}
+		}
+		doc.Tasks[t.Name] = e
+	}
+	var buf strings.Builder
+	buf.WriteString(header(meta, "#") + "\n")
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,128 @@
+package export
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"pm/internal/builder"
+	"pm/internal/config"
+)
+
+const metaYAML = `
+info:
+  name: exp
+  root: /proj
+func:
+  use-java:
+    params:
+      version:
+        default: "21"
+    script: "sdk use java @{version}"
+commands:
+  build:
+    description: Build app
+    cmd:
+      - "_{use-java()}"
+      - "./gradlew build @{args}"
+  test:
+    description: Test
+    deps: [build]
+    cmd: "echo ${HOME} && ./gradlew test @{args}"
+docker:
+  compose_file: compose.yml
+  groups:
+    base: [db, redis]
+`
+
+func testBuilder(t *testing.T) *builder.Builder {
+	t.Helper()
+	var meta config.ProjectMeta
+	if err := yaml.Unmarshal([]byte(metaYAML), &meta); err != nil {
+		t.Fatal(err)
+	}
+	return &builder.Builder{Meta: &meta, Root: "/proj", Global: &config.GlobalConfig{}}
+}
+
+func assertLines(t *testing.T, out string, want ...string) {
+	t.Helper()
+	for _, w := range want {
+		if !strings.Contains(out, w+"\n") {
+			t.Fatalf("want line %q in:\n%s", w, out)
+		}
+	}
+}
+
+func TestGenerate_Make(t *testing.T) {
+	out, err := Generate(testBuilder(t), "make")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertLines(t, out,
+		".ONESHELL:",
+		".PHONY: build test up up-base",
+		"build:",
+		"\tsdk use java 21",
+		"\t./gradlew build $(ARGS)",
+		"test:",
+		// the dependency runs first, without the caller's args
+		"\tsdk use java 21\n\t./gradlew build\n\techo $${HOME} && ./gradlew test $(ARGS)",
+		"\tdocker compose -f compose.yml up -d $(ARGS)",
+		"up-base:",
+		"\tdocker compose -f compose.yml up -d db redis",
+	)
+}
+
+func TestGenerate_Just(t *testing.T) {
+	out, err := Generate(testBuilder(t), "just")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertLines(t, out,
+		"# Build app",
+		"build *args:",
+		"    sdk use java 21 && \\",
+		"    ./gradlew build {{args}}",
+		"test *args:",
+		"    sdk use java 21 && \\\n    ./gradlew build && \\\n    echo ${HOME} && ./gradlew test {{args}}",
+		"up-base:",
+	)
+}
+
+func TestGenerate_Taskfile(t *testing.T) {
+	out, err := Generate(testBuilder(t), "taskfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc taskfileDoc
+	if err := yaml.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("invalid yaml: %v\n%s", err, out)
+	}
+	if doc.Version != "3" {
+		t.Fatalf("version = %q", doc.Version)
+	}
+	build := doc.Tasks["build"]
+	if build.Desc != "Build app" || len(build.Cmds) != 1 ||
+		build.Cmds[0] != "sdk use java 21\n./gradlew build {{.CLI_ARGS}}" {
+		t.Fatalf("build task = %+v", build)
+	}
+	if test := doc.Tasks["test"]; len(test.Cmds) != 1 ||
+		test.Cmds[0] != "sdk use java 21\n./gradlew build\necho ${HOME} && ./gradlew test {{.CLI_ARGS}}" {
+		t.Fatalf("test task = %+v", test)
+	}
+	if _, ok := doc.Tasks["up-base"]; !ok {
+		t.Fatal("missing up-base task")
+	}
+}
+
+func TestGenerate_Errors(t *testing.T) {
+	b := testBuilder(t)
+	if _, err := Generate(b, "ant"); err == nil {
+		t.Fatal("expected unknown format error")
+	}
+	b.Meta.Commands["lint"] = config.CommandDef{Cmd: "golint", Deps: []string{"nope"}}
+	if _, err := Generate(b, "make"); err == nil {
+		t.Fatal("expected unknown dependency error")
+	}
+}
@@ -0,0 +1,136 @@
+// Package fileset expands the file globs commands declare (inputs:,
+// outputs:): slash-separated patterns relative to the project root where
+// * and ? match within a path element and ** any number of elements. A
+// pattern matching a directory stands for every file under it.
+package fileset
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// skipDirs are never descended into.
+var skipDirs = []string{".git", ".hg", ".svn"}
+
+// Glob returns the files under root matching any of patterns, as sorted
+// slash-separated paths relative to root.
+func Glob(root string, patterns []string) ([]string, error) {
+	return glob(root, patterns, false)
+}
+
+// Tracked is Glob without the files and directories the .gitignore files
+// under root exclude.
+func Tracked(root string, patterns []string) ([]string, error) {
+	return glob(root, patterns, true)
+}
+
+func glob(root string, patterns []string, useIgnore bool) ([]string, error) {
+	seen := map[string]bool{}
+	for _, p := range patterns {
+		p = strings.TrimPrefix(path.Clean(filepath.ToSlash(p)), "./")
+		if _, err := path.Match(strings.ReplaceAll(p, "**", "*"), ""); err != nil {
+			return nil, fmt.Errorf("bad pattern %q: %w", p, err)
+		}
+		base := literalPrefix(p)
+		// the rules of the directories a walk starting below root is in
+		var rules map[string]gitignore
+		if useIgnore {
+			rules = map[string]gitignore{}
+			var g gitignore
+			if base != "" {
+				g = g.read(root, "")
+				dir := ""
+				for _, e := range strings.Split(parent(base), "/") {
+					if e == "" {
+						break
+					}
+					dir = path.Join(dir, e)
+					g = g.read(filepath.Join(root, filepath.FromSlash(dir)), dir)
+				}
+			}
+			rules[parent(base)] = g
+		}
+		err := filepath.WalkDir(filepath.Join(root, filepath.FromSlash(base)), func(abs string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			rel, err := filepath.Rel(root, abs)
+			if err != nil {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+			if rel == "." {
+				rel = ""
+			}
+			if d.IsDir() && slices.Contains(skipDirs, d.Name()) {
+				return filepath.SkipDir
+			}
+			if useIgnore {
+				if rel != "" && rules[parent(rel)].ignored(rel, d.IsDir()) {
+					if d.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if d.IsDir() {
+					rules[rel] = rules[parent(rel)].read(abs, rel)
+				}
+			}
+			// the files under a matching directory match too
+			if !d.IsDir() && Match(p+"/**", rel) {
+				seen[rel] = true
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return slices.Sorted(maps.Keys(seen)), nil
+}
+
+// Match reports whether the slash-separated name matches pattern.
+func Match(pattern, name string) bool {
+	return matchElems(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchElems(pat, name []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchElems(pat[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], name[0]); !ok {
+			return false
+		}
+		pat, name = pat[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// literalPrefix returns the leading path elements of pattern without
+// wildcards: the directory a walk for it starts in.
+func literalPrefix(pattern string) string {
+	elems := strings.Split(pattern, "/")
+	i := 0
+	for i < len(elems)-1 && !strings.ContainsAny(elems[i], `*?[\`) {
+		i++
+	}
+	return path.Join(elems[:i]...)
+}
@@ -0,0 +1,90 @@
+package fileset
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/main.go", false},
+		{"src/**/*.go", "src/main.go", true},
+		{"src/**/*.go", "src/a/b/main.go", true},
+		{"src/**/*.go", "test/main.go", false},
+		{"**", "a/b/c", true},
+		{"**/*_test.go", "x_test.go", true},
+		{"go.mod", "go.sum", false},
+	} {
+		if got := Match(tc.pattern, tc.name); got != tc.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tc.pattern, tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestGlob(t *testing.T) {
+	root := t.TempDir()
+	for _, f := range []string{"go.mod", "main.go", "src/a.go", "src/b/c.go", "src/b/c.txt", "gen/x.pb", ".git/HEAD"} {
+		path := filepath.Join(root, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(f), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := Glob(root, []string{"src/**/*.go", "./go.mod", "gen", "missing/**", "**/HEAD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"gen/x.pb", "go.mod", "src/a.go", "src/b/c.go"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("Glob = %v, want %v", got, want)
+	}
+	if _, err := Glob(root, []string{"src/[.go"}); err == nil {
+		t.Fatal("want an error for a malformed pattern")
+	}
+}
+
+func TestTracked(t *testing.T) {
+	root := t.TempDir()
+	for f, content := range map[string]string{
+		".gitignore":          "node_modules/\n*.log\n/build\n!keep.log\n",
+		"src/.gitignore":      "gen/\n",
+		"src/a.go":            "",
+		"src/debug.log":       "",
+		"src/keep.log":        "",
+		"src/gen/x.go":        "",
+		"src/pkg/build/b.go":  "",
+		"build/out":           "",
+		"node_modules/m/i.js": "",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, tc := range []struct {
+		patterns []string
+		want     []string
+	}{
+		{[]string{"**"}, []string{".gitignore", "src/.gitignore", "src/a.go", "src/keep.log", "src/pkg/build/b.go"}},
+		{[]string{"src/**/*.go"}, []string{"src/a.go", "src/pkg/build/b.go"}},
+	} {
+		got, err := Tracked(root, tc.patterns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("Tracked(%v) = %v, want %v", tc.patterns, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package fileset
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreRule is one pattern line of a .gitignore.
+type ignoreRule struct {
+	// dir is the slash path of the .gitignore's directory, "" at the root
+	dir     string
+	pattern string
+	negate  bool
+	dirOnly bool
+}
+
+// gitignore holds the rules of the .gitignore files read so far, outer
+// files first: the last rule matching a path decides.
+type gitignore []ignoreRule
+
+// read adds the rules of dir/.gitignore; rel is dir relative to the root.
+func (g gitignore) read(dir, rel string) gitignore {
+	f, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		return g
+	}
+	defer f.Close()
+	out := g[:len(g):len(g)]
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		r := ignoreRule{dir: rel}
+		if r.negate = strings.HasPrefix(line, "!"); r.negate {
+			line = line[1:]
+		}
+		line = strings.TrimPrefix(line, `\`)
+		if r.dirOnly = strings.HasSuffix(line, "/"); r.dirOnly {
+			line = strings.TrimSuffix(line, "/")
+		}
+		// a pattern without an inner slash matches at any depth
+		if !strings.Contains(line, "/") {
+			line = "**/" + line
+		}
+		r.pattern = strings.TrimPrefix(line, "/")
+		out = append(out, r)
+	}
+	return out
+}
+
+// ignored reports whether the slash path rel relative to the root is
+// excluded.
+func (g gitignore) ignored(rel string, isDir bool) bool {
+	ignored := false
+	for _, r := range g {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		name := rel
+		if r.dir != "" {
+			var ok bool
+			if name, ok = strings.CutPrefix(rel, r.dir+"/"); !ok {
+				continue
+			}
+		}
+		if Match(r.pattern, name) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// parent returns the slash directory of rel, "" for the root's entries.
+func parent(rel string) string {
+	if d := path.Dir(rel); d != "." {
+		return d
+	}
+	return ""
+}
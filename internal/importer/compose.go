@@ -0,0 +1,34 @@
+package importer
+
+import (
+	"pm/internal/config"
+)
+
+type composeService struct {
+	Image string `yaml:"image"`
+	Build any    `yaml:"build"`
+}
+
+// composeDocker seeds docker.groups: services built from the repo go to
+// "app", image-only services (databases, brokers, ...) to "base", and "all"
+// has both.
+func composeDocker(file string, services map[string]composeService) config.DockerDef {
+	d := config.DockerDef{ComposeFile: file, Groups: map[string][]string{}}
+	var base, app, all []string
+	for _, name := range sortedKeys(services) {
+		all = append(all, name)
+		if services[name].Build != nil {
+			app = append(app, name)
+		} else {
+			base = append(base, name)
+		}
+	}
+	if len(base) > 0 && len(app) > 0 {
+		d.Groups["base"] = base
+		d.Groups["app"] = app
+	}
+	if len(all) > 0 {
+		d.Groups["all"] = all
+	}
+	return d
+}
@@ -0,0 +1,161 @@
+package importer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"pm/internal/config"
+)
+
+// Command is a task found in one of the repository's existing definitions.
+type Command struct {
+	Name        string
+	Description string
+	Cmd         string
+}
+
+// Source reads one kind of task definition from a directory. Found is
+// false when the directory has no such file.
+type Source struct {
+	Name string
+	Read func(dir string) (cmds []Command, found bool, err error)
+}
+
+// Sources are tried in order; on a name clash the earlier source keeps the
+// plain name and later ones get a prefix.
+var Sources = []Source{
+	{Name: "make", Read: readMakefile},
+	{Name: "just", Read: readJustfile},
+	{Name: "task", Read: readTaskfile},
+	{Name: "npm", Read: readPackageJSON},
+	{Name: "gradle", Read: readGradle},
+}
+
+// Result is an imported meta plus the sources it was built from.
+type Result struct {
+	Meta    *config.ProjectMeta
+	Sources []string
+}
+
+// Import builds a project meta for dir from its Makefile, justfile,
+// Taskfile, package.json scripts, gradle tasks and compose services.
+func Import(dir string) (*Result, error) {
+	meta, err := NewMeta(dir)
+	if err != nil {
+		return nil, err
+	}
+	dir = expandRoot(meta.Info.Root)
+	res := &Result{Meta: meta}
+
+	for _, src := range Sources {
+		cmds, found, err := src.Read(dir)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", src.Name, err)
+		}
+		if !found {
+			continue
+		}
+		res.Sources = append(res.Sources, src.Name)
+		for _, c := range cmds {
+			name := sanitize(c.Name)
+			if name == "" {
+				continue
+			}
+			if _, taken := meta.Commands[name]; taken || reserved[name] {
+				name = src.Name + "-" + name
+				if _, taken := meta.Commands[name]; taken {
+					continue
+				}
+			}
+			meta.Commands[name] = config.CommandDef{Description: c.Description, Cmd: c.Cmd}
+		}
+	}
+
+	docker, found, err := Compose(dir)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		res.Sources = append(res.Sources, "compose")
+		meta.Docker = docker
+	}
+	return res, nil
+}
+
+// Compose reads the compose file in dir into a docker section with seeded
+// groups.
+func Compose(dir string) (config.DockerDef, bool, error) {
+	compose, services, err := readCompose(dir)
+	if err != nil {
+		return config.DockerDef{}, true, fmt.Errorf("compose: %w", err)
+	}
+	if compose == "" {
+		return config.DockerDef{}, false, nil
+	}
+	return composeDocker(compose, services), true, nil
+}
+
+// NewMeta returns an empty meta whose info is derived from dir: the
+// directory name and its path (relative to ~ when possible).
+func NewMeta(dir string) (*config.ProjectMeta, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	meta := &config.ProjectMeta{Commands: map[string]config.CommandDef{}}
+	meta.Info.Name = sanitize(filepath.Base(dir))
+	meta.Info.Root = homeRel(dir)
+	return meta, nil
+}
+
+// reserved names are pm built-ins that a command can't shadow usefully.
+var reserved = map[string]bool{"help": true, "up": true}
+
+var nameRe = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// sanitize turns a task name into something usable after ':' on the command
+// line (npm's "build:prod" becomes "build-prod").
+func sanitize(s string) string {
+	s = nameRe.ReplaceAllString(strings.TrimSpace(s), "-")
+	return strings.Trim(s, "-.")
+}
+
+func homeRel(dir string) string {
+	home, _ := os.UserHomeDir()
+	if home != "" && (dir == home || strings.HasPrefix(dir, home+string(filepath.Separator))) {
+		return "~" + filepath.ToSlash(strings.TrimPrefix(dir, home))
+	}
+	return dir
+}
+
+func expandRoot(root string) string {
+	if strings.HasPrefix(root, "~") {
+		home, _ := os.UserHomeDir()
+		return filepath.Join(home, root[1:])
+	}
+	return root
+}
+
+// firstExisting returns the first of names that exists in dir.
+func firstExisting(dir string, names ...string) string {
+	for _, n := range names {
+		p := filepath.Join(dir, n)
+		if st, err := os.Stat(p); err == nil && !st.IsDir() {
+			return p
+		}
+	}
+	return ""
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,106 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestImport_AllSources(t *testing.T) {
+	res, err := Import(filepath.Join("testdata", "repo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := res.Meta
+	if m.Info.Name != "repo" {
+		t.Fatalf("name = %q", m.Info.Name)
+	}
+	want := map[string][2]string{
+		// name: {cmd, description}
+		"build":      {"make build @{args}", "Build the binary"},
+		"test":       {"make test @{args}", "Run tests"},
+		"lint":       {"make lint @{args}", ""},
+		"vet":        {"make vet @{args}", ""},
+		"just-build": {"just build @{args}", "Build with just"},
+		"deploy":     {"just deploy @{args}", "Deploy somewhere"},
+		"release":    {"task release -- @{args}", "Cut a release"},
+		"dev":        {"pnpm run dev @{args}", "vite"},
+		"npm-build":  {"pnpm run build @{args}", "vite build"},
+		"build-prod": {"pnpm run build:prod @{args}", "vite build --mode prod"},
+	}
+	for name, w := range want {
+		c, ok := m.Commands[name]
+		if !ok {
+			t.Errorf("missing command %s", name)
+			continue
+		}
+		if c.Cmd != w[0] || c.Description != w[1] {
+			t.Errorf("%s = {%v, %q}, want {%s, %q}", name, c.Cmd, c.Description, w[0], w[1])
+		}
+	}
+	for _, name := range []string{"_private", "helper", "prebuild", "npm-prebuild", "o"} {
+		if _, ok := m.Commands[name]; ok {
+			t.Errorf("unexpected command %s", name)
+		}
+	}
+	if len(m.Commands) != len(want) {
+		t.Errorf("got %d commands, want %d: %v", len(m.Commands), len(want), m.Commands)
+	}
+
+	if m.Docker.ComposeFile != "compose.yaml" {
+		t.Fatalf("compose file = %q", m.Docker.ComposeFile)
+	}
+	g := m.Docker.Groups
+	if len(g["base"]) != 1 || g["base"][0] != "db" || len(g["app"]) != 1 || g["app"][0] != "api" || len(g["all"]) != 2 {
+		t.Fatalf("groups = %v", g)
+	}
+}
+
+func TestImport_Empty(t *testing.T) {
+	res, err := Import(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Sources) != 0 || len(res.Meta.Commands) != 0 {
+		t.Fatalf("expected nothing imported, got %+v", res)
+	}
+}
+
+func TestReadGradle_WithoutWrapper(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "build.gradle.kts"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cmds, found, err := readGradle(dir)
+	if err != nil || !found {
+		t.Fatalf("found=%v err=%v", found, err)
+	}
+	if len(cmds) != 3 || cmds[0].Cmd != "gradle build @{args}" {
+		t.Fatalf("cmds = %+v", cmds)
+	}
+}
+
+func TestParseGradleTasks(t *testing.T) {
+	out := `
+Application tasks
+-----------------
+bootRun - Runs this project as a Spring Boot application.
+
+Build tasks
+-----------
+assemble - Assembles the outputs of this project.
+build - Assembles and tests this project.
+
+Help tasks
+----------
+dependencies - Displays all dependencies declared in root project 'demo'.
+`
+	cmds := ParseGradleTasks(out, "./gradlew")
+	if len(cmds) != 3 {
+		t.Fatalf("cmds = %+v", cmds)
+	}
+	if cmds[0].Name != "bootRun" || cmds[0].Cmd != "./gradlew bootRun @{args}" ||
+		cmds[0].Description != "Runs this project as a Spring Boot application." {
+		t.Fatalf("cmds[0] = %+v", cmds[0])
+	}
+}
@@ -0,0 +1,252 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var makeTargetRe = regexp.MustCompile(`^([A-Za-z0-9_][A-Za-z0-9_./ -]*?)\s*:([^=:]|$)`)
+
+// readMakefile takes explicit targets; a description comes from a trailing
+// "## text" or from "#" comment lines right above the target.
+func readMakefile(dir string) ([]Command, bool, error) {
+	path := firstExisting(dir, "GNUmakefile", "makefile", "Makefile")
+	if path == "" {
+		return nil, false, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, true, err
+	}
+	var out []Command
+	seen := map[string]bool{}
+	var comment []string
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(line, "#") {
+			comment = append(comment, strings.TrimSpace(strings.TrimLeft(line, "#")))
+			continue
+		}
+		m := makeTargetRe.FindStringSubmatch(line)
+		if m == nil || strings.HasPrefix(line, "\t") {
+			comment = nil
+			continue
+		}
+		desc := strings.Join(comment, " ")
+		comment = nil
+		if i := strings.Index(line, "##"); i >= 0 {
+			desc = strings.TrimSpace(line[i+2:])
+		}
+		for _, target := range strings.Fields(strings.SplitN(line, ":", 2)[0]) {
+			if seen[target] || strings.ContainsAny(target, "%$") {
+				continue
+			}
+			seen[target] = true
+			out = append(out, Command{Name: target, Description: desc, Cmd: "make " + target + " @{args}"})
+		}
+	}
+	return out, true, sc.Err()
+}
+
+var justRecipeRe = regexp.MustCompile(`^@?([A-Za-z_][A-Za-z0-9_-]*)(\s+[^:]*)?:([^=]|$)`)
+
+// readJustfile takes recipes with the "#" doc comment above them.
+func readJustfile(dir string) ([]Command, bool, error) {
+	path := firstExisting(dir, "justfile", "Justfile", ".justfile")
+	if path == "" {
+		return nil, false, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, true, err
+	}
+	var out []Command
+	var comment string
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.HasPrefix(line, "#"):
+			comment = strings.TrimSpace(strings.TrimLeft(line, "#"))
+			continue
+		case strings.HasPrefix(line, "set ") || strings.HasPrefix(line, "alias ") ||
+			strings.HasPrefix(line, "import ") || strings.HasPrefix(line, "mod "):
+		default:
+			if m := justRecipeRe.FindStringSubmatch(line); m != nil && !strings.HasPrefix(m[1], "_") {
+				out = append(out, Command{Name: m[1], Description: comment, Cmd: "just " + m[1] + " @{args}"})
+			}
+		}
+		comment = ""
+	}
+	return out, true, sc.Err()
+}
+
+// readTaskfile takes go-task tasks with their desc (or summary).
+func readTaskfile(dir string) ([]Command, bool, error) {
+	path := firstExisting(dir, "Taskfile.yml", "Taskfile.yaml", "taskfile.yml", "taskfile.yaml")
+	if path == "" {
+		return nil, false, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, true, err
+	}
+	var doc struct {
+		Tasks map[string]struct {
+			Desc     string `yaml:"desc"`
+			Summary  string `yaml:"summary"`
+			Internal bool   `yaml:"internal"`
+		} `yaml:"tasks"`
+	}
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, true, err
+	}
+	var out []Command
+	for _, name := range sortedKeys(doc.Tasks) {
+		t := doc.Tasks[name]
+		if t.Internal {
+			continue
+		}
+		desc := t.Desc
+		if desc == "" {
+			desc = firstLine(t.Summary)
+		}
+		out = append(out, Command{Name: name, Description: desc, Cmd: "task " + name + " -- @{args}"})
+	}
+	return out, true, nil
+}
+
+// readPackageJSON takes npm scripts, run with the package manager whose
+// lock file is present. The script body doubles as the description.
+func readPackageJSON(dir string) ([]Command, bool, error) {
+	path := firstExisting(dir, "package.json")
+	if path == "" {
+		return nil, false, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, true, err
+	}
+	var pkg struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	if err := json.Unmarshal(b, &pkg); err != nil {
+		return nil, true, err
+	}
+	runner := "npm run %s -- @{args}"
+	switch {
+	case firstExisting(dir, "pnpm-lock.yaml") != "":
+		runner = "pnpm run %s @{args}"
+	case firstExisting(dir, "yarn.lock") != "":
+		runner = "yarn run %s @{args}"
+	case firstExisting(dir, "bun.lockb", "bun.lock") != "":
+		runner = "bun run %s @{args}"
+	}
+	var out []Command
+	for _, name := range sortedKeys(pkg.Scripts) {
+		// npm lifecycle hooks run on their own
+		if strings.HasPrefix(name, "pre") || strings.HasPrefix(name, "post") {
+			if _, ok := pkg.Scripts[strings.TrimPrefix(strings.TrimPrefix(name, "pre"), "post")]; ok {
+				continue
+			}
+		}
+		out = append(out, Command{Name: name, Description: pkg.Scripts[name], Cmd: strings.Replace(runner, "%s", name, 1)})
+	}
+	return out, true, nil
+}
+
+// GradleTimeout bounds `gradlew tasks`.
+var GradleTimeout = 2 * time.Minute
+
+// readGradle asks the gradle wrapper for its task list; without a wrapper,
+// or when it fails, the standard lifecycle tasks are used.
+func readGradle(dir string) ([]Command, bool, error) {
+	if firstExisting(dir, "build.gradle", "build.gradle.kts", "settings.gradle", "settings.gradle.kts") == "" {
+		return nil, false, nil
+	}
+	gradlew := "./gradlew"
+	if wrapper := firstExisting(dir, "gradlew"); wrapper != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), GradleTimeout)
+		defer cancel()
+		cmd := exec.CommandContext(ctx, wrapper, "tasks", "--console=plain", "-q")
+		cmd.Dir = dir
+		if out, err := cmd.Output(); err == nil {
+			if cmds := ParseGradleTasks(string(out), gradlew); len(cmds) > 0 {
+				return cmds, true, nil
+			}
+		}
+	} else {
+		gradlew = "gradle"
+	}
+	var out []Command
+	for _, t := range [][2]string{
+		{"build", "Assembles and tests this project."},
+		{"test", "Runs the test suite."},
+		{"clean", "Deletes the build directory."},
+	} {
+		out = append(out, Command{Name: t[0], Description: t[1], Cmd: gradlew + " " + t[0] + " @{args}"})
+	}
+	return out, true, nil
+}
+
+var gradleTaskRe = regexp.MustCompile(`^([a-z][A-Za-z0-9]*) - (.+)$`)
+
+// ParseGradleTasks reads `gradle tasks` output ("name - description" lines
+// under group headers). Help-group tasks are skipped.
+func ParseGradleTasks(out, gradlew string) []Command {
+	var cmds []Command
+	group := ""
+	lines := strings.Split(out, "\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if i+1 < len(lines) && strings.HasPrefix(lines[i+1], "---") {
+			group = strings.ToLower(line)
+			continue
+		}
+		if strings.HasPrefix(group, "help tasks") || strings.HasPrefix(group, "build setup tasks") {
+			continue
+		}
+		if m := gradleTaskRe.FindStringSubmatch(line); m != nil {
+			cmds = append(cmds, Command{Name: m[1], Description: m[2], Cmd: gradlew + " " + m[1] + " @{args}"})
+		}
+	}
+	return cmds
+}
+
+// readCompose returns the compose file name and its services.
+func readCompose(dir string) (string, map[string]composeService, error) {
+	path := firstExisting(dir, "compose.yaml", "compose.yml", "docker-compose.yml", "docker-compose.yaml")
+	if path == "" {
+		return "", nil, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, err
+	}
+	var doc struct {
+		Services map[string]composeService `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return "", nil, err
+	}
+	return filepath.Base(path), doc.Services, nil
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return strings.TrimSpace(s[:i])
+	}
+	return s
+}
@@ -0,0 +1,66 @@
+package match
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Fuzzy reports whether the letters of query appear in text in order,
+// ignoring case, and scores the match: consecutive letters, letters at the
+// start of a word and a match at the very start score higher.
+func Fuzzy(query, text string) (int, bool) {
+	q := []rune(strings.ToLower(query))
+	t := []rune(strings.ToLower(text))
+	score, qi, last := 0, 0, -2
+	for i := 0; i < len(t) && qi < len(q); i++ {
+		if t[i] != q[qi] {
+			continue
+		}
+		switch {
+		case i == 0:
+			score += 8
+		case !unicode.IsLetter(t[i-1]) && !unicode.IsDigit(t[i-1]):
+			score += 4
+		}
+		if i == last+1 {
+			score += 5
+		}
+		score++
+		last = i
+		qi++
+	}
+	if qi < len(q) {
+		return 0, false
+	}
+	return score - len(t)/8, true
+}
+
+// Rank returns the indexes of the texts query fuzzily matches, best first;
+// equal scores keep their order. An empty query keeps every text in order.
+func Rank(query string, texts []string) []int {
+	if query == "" {
+		all := make([]int, len(texts))
+		for i := range all {
+			all[i] = i
+		}
+		return all
+	}
+	var idx, scores []int
+	for i, s := range texts {
+		if sc, ok := Fuzzy(query, s); ok {
+			idx = append(idx, i)
+			scores = append(scores, sc)
+		}
+	}
+	order := make([]int, len(idx))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+	out := make([]int, len(order))
+	for i, o := range order {
+		out[i] = idx[o]
+	}
+	return out
+}
@@ -0,0 +1,149 @@
+package match
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Candidate is a name that can also be reached through its aliases.
+type Candidate struct {
+	Name    string
+	Aliases []string
+}
+
+// NotFoundError is returned by Find when nothing matches the query.
+type NotFoundError struct {
+	Query       string
+	Suggestions []string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("no match for %q%s", e.Query, DidYouMean(e.Suggestions, ""))
+}
+
+// AmbiguousError is returned by Find when a prefix or alias matches
+// several names.
+type AmbiguousError struct {
+	Query   string
+	Matches []string
+}
+
+func (e *AmbiguousError) Error() string {
+	return fmt.Sprintf("%q is ambiguous: %s", e.Query, strings.Join(e.Matches, ", "))
+}
+
+// Find resolves query to a candidate name: an exact name wins, then an
+// exact alias, then an unambiguous prefix of a name or alias.
+func Find(query string, cands []Candidate) (string, error) {
+	for _, c := range cands {
+		if c.Name == query {
+			return c.Name, nil
+		}
+	}
+	hits := collect(cands, func(s string) bool { return s == query }, false)
+	if len(hits) == 0 && query != "" {
+		hits = collect(cands, func(s string) bool { return strings.HasPrefix(s, query) }, true)
+	}
+	switch len(hits) {
+	case 1:
+		return hits[0], nil
+	case 0:
+		return "", &NotFoundError{Query: query, Suggestions: Suggest(query, cands)}
+	default:
+		return "", &AmbiguousError{Query: query, Matches: hits}
+	}
+}
+
+// collect returns the sorted names whose aliases (and, with names, the
+// name itself) satisfy ok.
+func collect(cands []Candidate, ok func(string) bool, names bool) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, c := range cands {
+		hit := names && ok(c.Name)
+		for _, a := range c.Aliases {
+			hit = hit || ok(a)
+		}
+		if hit && !seen[c.Name] {
+			seen[c.Name] = true
+			out = append(out, c.Name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Suggest returns up to three candidate names close to query by edit
+// distance of the name or one of its aliases, closest first.
+func Suggest(query string, cands []Candidate) []string {
+	limit := len(query) / 2
+	if limit < 1 {
+		limit = 1
+	}
+	if limit > 3 {
+		limit = 3
+	}
+	type scored struct {
+		name string
+		d    int
+	}
+	var found []scored
+	for _, c := range cands {
+		best := Distance(query, c.Name)
+		for _, a := range c.Aliases {
+			if d := Distance(query, a); d < best {
+				best = d
+			}
+		}
+		if best <= limit {
+			found = append(found, scored{c.Name, best})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].d != found[j].d {
+			return found[i].d < found[j].d
+		}
+		return found[i].name < found[j].name
+	})
+	var out []string
+	for i := 0; i < len(found) && i < 3; i++ {
+		out = append(out, found[i].name)
+	}
+	return out
+}
+
+// DidYouMean formats suggestions as " (did you mean a or b?)", each name
+// preceded by prefix; it is empty without suggestions.
+func DidYouMean(suggestions []string, prefix string) string {
+	if len(suggestions) == 0 {
+		return ""
+	}
+	quoted := make([]string, len(suggestions))
+	for i, s := range suggestions {
+		quoted[i] = prefix + s
+	}
+	return " (did you mean " + strings.Join(quoted, " or ") + "?)"
+}
+
+// Distance is the Levenshtein distance between a and b.
+func Distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
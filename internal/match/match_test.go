@@ -0,0 +1,101 @@
+package match
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+var cands = []Candidate{
+	{Name: "build", Aliases: []string{"b"}},
+	{Name: "bench"},
+	{Name: "test", Aliases: []string{"t"}},
+	{Name: "up"},
+	{Name: "upload"},
+}
+
+func TestFind(t *testing.T) {
+	cases := map[string]string{
+		"build": "build", // exact
+		"b":     "build", // alias beats prefix of bench
+		"bu":    "build", // unique prefix
+		"te":    "test",
+		"up":    "up", // exact beats prefix of upload
+		"upl":   "upload",
+	}
+	for q, want := range cases {
+		got, err := Find(q, cands)
+		if err != nil || got != want {
+			t.Errorf("Find(%q) = %q, %v; want %q", q, got, err, want)
+		}
+	}
+}
+
+func TestFind_Ambiguous(t *testing.T) {
+	cs := []Candidate{{Name: "api"}, {Name: "app"}}
+	_, err := Find("ap", cs)
+	var amb *AmbiguousError
+	if !errors.As(err, &amb) || !reflect.DeepEqual(amb.Matches, []string{"api", "app"}) {
+		t.Fatalf("expected ambiguity, got %v", err)
+	}
+}
+
+func TestFind_NotFoundSuggests(t *testing.T) {
+	_, err := Find("biuld", cands)
+	var nf *NotFoundError
+	if !errors.As(err, &nf) || !reflect.DeepEqual(nf.Suggestions, []string{"build"}) {
+		t.Fatalf("expected suggestion, got %v", err)
+	}
+	if got := DidYouMean(nf.Suggestions, ":"); got != " (did you mean :build?)" {
+		t.Fatalf("DidYouMean = %q", got)
+	}
+	_, err = Find("deploy", cands)
+	if !errors.As(err, &nf) || len(nf.Suggestions) != 0 {
+		t.Fatalf("unexpected suggestions: %v", err)
+	}
+}
+
+func TestDistance(t *testing.T) {
+	cases := []struct {
+		a, b string
+		d    int
+	}{
+		{"", "", 0}, {"abc", "", 3}, {"kitten", "sitting", 3}, {"build", "bild", 1},
+	}
+	for _, c := range cases {
+		if got := Distance(c.a, c.b); got != c.d {
+			t.Errorf("Distance(%q,%q) = %d, want %d", c.a, c.b, got, c.d)
+		}
+	}
+}
+
+func TestFuzzy(t *testing.T) {
+	for _, c := range []struct {
+		query, text string
+		ok          bool
+	}{
+		{"", "anything", true},
+		{"sbz", "subzero", true},
+		{"SUB", "subzero", true},
+		{"zs", "subzero", false},
+		{"dep", ":deploy - Deploy to k8s", true},
+	} {
+		if _, ok := Fuzzy(c.query, c.text); ok != c.ok {
+			t.Errorf("Fuzzy(%q, %q) = %v, want %v", c.query, c.text, ok, c.ok)
+		}
+	}
+}
+
+func TestRank(t *testing.T) {
+	texts := []string{"web-api", "api", "payments", "mapi"}
+	got := Rank("api", texts)
+	// exact start first, then a word start, then inside a word; payments
+	// has the letters out of order
+	want := []int{1, 0, 3}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Rank = %v, want %v", got, want)
+	}
+	if got := Rank("", texts); !reflect.DeepEqual(got, []int{0, 1, 2, 3}) {
+		t.Errorf("empty query should keep every text in order, got %v", got)
+	}
+}
@@ -2,7 +2,9 @@ package plan
 
 import (
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
 	"pm/internal/config"
 )
@@ -12,11 +14,58 @@ type OpPushd struct{ Dir string }
 type OpPopd struct{}
 type OpRun struct{ Line string }
 type OpEcho struct{ Line string }
+type OpEnv struct{ Name, Value string }
 
-func (OpPushd) isOp() {}
-func (OpPopd) isOp()  {}
-func (OpRun) isOp()   {}
-func (OpEcho) isOp()  {}
+// OpBlock runs Ops as one unit: a failure stops the rest of the block but
+// not the plan, and the outcome is recorded under Name for OpSummary.
+type OpBlock struct {
+	Name string
+	Ops  []Op
+	// run after the block when it failed, isolated like the block
+	OnFailure []Op
+}
+
+// OpSummary reports which blocks succeeded and which failed; the script
+// exits non-zero when any block failed.
+type OpSummary struct{}
+
+// OpStep is what one typed command expanded to, e.g. ":up @app": the ops
+// run as usual and the step's duration and exit status are recorded for
+// OpTimings.
+type OpStep struct {
+	Name string
+	Ops  []Op
+	// sent when the step ends, if set
+	Notify *Notify
+	// run when the step fails, before the failure ends the plan
+	OnFailure []Op
+	// set by Timings: a failing step prints the table before the failure
+	// ends the plan
+	Table bool
+}
+
+// Notify is a completion notification for a step, titled Title and
+// sent through Via (desktop, bell or osc9) when the step ran at least
+// After. It says whether the step succeeded.
+type Notify struct {
+	Title string
+	After time.Duration
+	Via   string
+}
+
+// OpTimings prints a table of the steps run so far with their duration
+// and exit status.
+type OpTimings struct{}
+
+func (OpPushd) isOp()   {}
+func (OpPopd) isOp()    {}
+func (OpRun) isOp()     {}
+func (OpEcho) isOp()    {}
+func (OpEnv) isOp()     {}
+func (OpBlock) isOp()   {}
+func (OpSummary) isOp() {}
+func (OpStep) isOp()    {}
+func (OpTimings) isOp() {}
 
 type Plan struct {
 	Ops []Op
@@ -28,6 +77,113 @@ func (p *Plan) Pushd(dir string) { p.Ops = append(p.Ops, OpPushd{Dir: dir}) }
 func (p *Plan) Popd()            { p.Ops = append(p.Ops, OpPopd{}) }
 func (p *Plan) Run(line string)  { p.Ops = append(p.Ops, OpRun{Line: line}) }
 func (p *Plan) Echo(line string) { p.Ops = append(p.Ops, OpEcho{Line: line}) }
+func (p *Plan) Env(name, value string) {
+	p.Ops = append(p.Ops, OpEnv{Name: name, Value: value})
+}
+func (p *Plan) Block(name string, ops []Op) {
+	p.Ops = append(p.Ops, OpBlock{Name: name, Ops: ops})
+}
+func (p *Plan) Summary() { p.Ops = append(p.Ops, OpSummary{}) }
+func (p *Plan) Step(name string, ops []Op, notify *Notify) {
+	p.Ops = append(p.Ops, OpStep{Name: name, Ops: ops, Notify: notify})
+}
+
+// Timings appends the table of steps and marks the steps before it, so
+// that the table is printed even when one of them ends the plan.
+func (p *Plan) Timings() {
+	for i, op := range p.Ops {
+		if s, ok := op.(OpStep); ok {
+			s.Table = true
+			p.Ops[i] = s
+		}
+	}
+	p.Ops = append(p.Ops, OpTimings{})
+}
+
+// Flatten replaces steps with their ops and drops timings, for blocks
+// whose failure handling would not see inside a step; notifications and
+// failure hooks go with the steps, the block has its own OnFailure.
+func Flatten(ops []Op) []Op {
+	var out []Op
+	for _, op := range ops {
+		switch v := op.(type) {
+		case OpStep:
+			out = append(out, Flatten(v.Ops)...)
+		case OpTimings:
+		default:
+			out = append(out, op)
+		}
+	}
+	return out
+}
+
+// Hooks are ops spliced around a plan's commands, see Splice.
+type Hooks struct {
+	BeforeAll, AfterAll []Op
+	// run when a step fails
+	OnFailure []Op
+}
+
+// Splice adds hooks to a plan that runs something: BeforeAll after the
+// plan has entered the project (its leading pushd and env ops), AfterAll
+// after the last command, before the table of timings, and OnFailure to
+// every step.
+func (p *Plan) Splice(h Hooks) {
+	if !Runs(p.Ops) {
+		return
+	}
+	start := len(p.Entry())
+	end := len(p.Ops)
+	if _, ok := p.Ops[end-1].(OpTimings); ok {
+		end--
+	}
+	var ops []Op
+	ops = append(ops, p.Ops[:start]...)
+	ops = append(ops, h.BeforeAll...)
+	for _, op := range p.Ops[start:end] {
+		if s, ok := op.(OpStep); ok && len(h.OnFailure) > 0 {
+			s.OnFailure = append(slices.Clone(s.OnFailure), h.OnFailure...)
+			op = s
+		}
+		ops = append(ops, op)
+	}
+	ops = append(ops, h.AfterAll...)
+	p.Ops = append(ops, p.Ops[end:]...)
+}
+
+// Entry returns the leading ops that enter the project: its pushd and
+// env ops.
+func (p *Plan) Entry() []Op {
+	n := 0
+	for n < len(p.Ops) {
+		switch p.Ops[n].(type) {
+		case OpPushd, OpEnv:
+			n++
+			continue
+		}
+		break
+	}
+	return p.Ops[:n]
+}
+
+// Runs reports whether ops run anything, directly or in a block or step.
+func Runs(ops []Op) bool {
+	for _, op := range ops {
+		switch v := op.(type) {
+		case OpRun:
+			return true
+		case OpBlock:
+			if Runs(v.Ops) {
+				return true
+			}
+		case OpStep:
+			if Runs(v.Ops) {
+				return true
+			}
+		}
+	}
+	return false
+}
 
 // DockerUp returns shell lines for docker compose up -d with groups
 func DockerUp(meta *config.ProjectMeta, args []string) []string {
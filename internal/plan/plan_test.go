@@ -33,3 +33,53 @@ func TestDockerUp_Basic(t *testing.T) {
 		}
 	}
 }
+
+func TestSplice(t *testing.T) {
+	p := New()
+	p.Pushd("/api")
+	p.Env("APP_ENV", "dev")
+	p.Step(":build", []Op{OpRun{Line: "make"}}, nil)
+	p.Step(":test", []Op{OpRun{Line: "make test"}}, nil)
+	p.Timings()
+	p.Splice(Hooks{
+		BeforeAll: []Op{OpRun{Line: "sdk use java 21"}},
+		AfterAll:  []Op{OpRun{Line: "echo done"}},
+		OnFailure: []Op{OpRun{Line: "rm -rf tmp"}},
+	})
+
+	var got []string
+	for _, op := range p.Ops {
+		switch v := op.(type) {
+		case OpPushd:
+			got = append(got, "pushd")
+		case OpEnv:
+			got = append(got, "env")
+		case OpRun:
+			got = append(got, v.Line)
+		case OpStep:
+			got = append(got, v.Name)
+			if len(v.OnFailure) != 1 {
+				t.Errorf("%s: on failure = %v", v.Name, v.OnFailure)
+			}
+			if !v.Table {
+				t.Errorf("%s: not marked for the table", v.Name)
+			}
+		case OpTimings:
+			got = append(got, "timings")
+		}
+	}
+	want := "pushd|env|sdk use java 21|:build|:test|echo done|timings"
+	if strings.Join(got, "|") != want {
+		t.Fatalf("ops = %q", got)
+	}
+}
+
+func TestSplice_NothingRuns(t *testing.T) {
+	p := New()
+	p.Pushd("/api")
+	p.Echo("# pm: help")
+	p.Splice(Hooks{BeforeAll: []Op{OpRun{Line: "sdk use java 21"}}})
+	if len(p.Ops) != 2 {
+		t.Fatalf("hooks spliced into a plan that runs nothing: %v", p.Ops)
+	}
+}
@@ -0,0 +1,48 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"pm/internal/config"
+	"pm/internal/plan"
+)
+
+// CommandRequest is sent on stdin to `pm-cmd-<name> --run`.
+type CommandRequest struct {
+	ProtocolVersion int                 `json:"protocol_version"`
+	Command         string              `json:"command"`
+	Args            []string            `json:"args"`
+	Root            string              `json:"root"`
+	Project         *config.ProjectMeta `json:"project"`
+	Global          map[string]any      `json:"global"`
+}
+
+type commandResponse struct {
+	Ops []Op `json:"ops"`
+}
+
+// RunCommand asks a command plugin for the ops implementing :name.
+func RunCommand(info Info, req CommandRequest) ([]plan.Op, error) {
+	req.ProtocolVersion = ProtocolVersion
+	if req.Args == nil {
+		req.Args = []string{}
+	}
+	b, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	out, err := Call(info, b, "--run")
+	if err != nil {
+		return nil, err
+	}
+	var resp commandResponse
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return nil, fmt.Errorf("plugin %s: invalid response: %w", info.Name, err)
+	}
+	ops, err := DecodeOps(resp.Ops)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %w", info.Name, err)
+	}
+	return ops, nil
+}
@@ -0,0 +1,129 @@
+package plugin
+
+import (
+	"fmt"
+
+	"pm/internal/config"
+	"pm/internal/plan"
+)
+
+// OpKinds lists every op kind a plan can contain, in protocol spelling.
+var OpKinds = []string{"pushd", "popd", "run", "echo", "env", "block", "summary", "step", "timings"}
+
+// LegacyOps are the op kinds a renderer without --describe gets.
+var LegacyOps = []string{"pushd", "popd", "run", "echo", "env"}
+
+// Op is the JSON form of a plan.Op.
+type Op struct {
+	Kind  string `json:"kind"`
+	Line  string `json:"line,omitempty"`
+	Dir   string `json:"dir,omitempty"`
+	Msg   string `json:"msg,omitempty"`
+	Name  string `json:"name,omitempty"`
+	Value string `json:"value,omitempty"`
+	Ops   []Op   `json:"ops,omitempty"`
+	// of a step; on_failure also of a block
+	Notify    *Notify `json:"notify,omitempty"`
+	OnFailure []Op    `json:"on_failure,omitempty"`
+}
+
+// Notify is the JSON form of a plan.Notify.
+type Notify struct {
+	Title   string `json:"title"`
+	AfterMs int64  `json:"after_ms,omitempty"`
+	Via     string `json:"via,omitempty"`
+}
+
+// EncodeOp converts a plan op to its JSON form.
+func EncodeOp(op plan.Op) (Op, bool) {
+	switch v := op.(type) {
+	case plan.OpPushd:
+		return Op{Kind: "pushd", Dir: v.Dir}, true
+	case plan.OpPopd:
+		return Op{Kind: "popd"}, true
+	case plan.OpEcho:
+		return Op{Kind: "echo", Msg: v.Line}, true
+	case plan.OpRun:
+		return Op{Kind: "run", Line: v.Line}, true
+	case plan.OpEnv:
+		return Op{Kind: "env", Name: v.Name, Value: v.Value}, true
+	case plan.OpBlock:
+		o := Op{Kind: "block", Name: v.Name}
+		for _, c := range v.Ops {
+			if eo, ok := EncodeOp(c); ok {
+				o.Ops = append(o.Ops, eo)
+			}
+		}
+		for _, c := range v.OnFailure {
+			if eo, ok := EncodeOp(c); ok {
+				o.OnFailure = append(o.OnFailure, eo)
+			}
+		}
+		return o, true
+	case plan.OpSummary:
+		return Op{Kind: "summary"}, true
+	case plan.OpStep:
+		o := Op{Kind: "step", Name: v.Name}
+		if n := v.Notify; n != nil {
+			o.Notify = &Notify{Title: n.Title, AfterMs: n.After.Milliseconds(), Via: n.Via}
+		}
+		for _, c := range v.Ops {
+			if eo, ok := EncodeOp(c); ok {
+				o.Ops = append(o.Ops, eo)
+			}
+		}
+		for _, c := range v.OnFailure {
+			if eo, ok := EncodeOp(c); ok {
+				o.OnFailure = append(o.OnFailure, eo)
+			}
+		}
+		return o, true
+	case plan.OpTimings:
+		return Op{Kind: "timings"}, true
+	default:
+		return Op{}, false
+	}
+}
+
+// DecodeOps converts ops returned by a command plugin into plan ops: run,
+// echo, pushd, popd and env. Pushd and popd must balance so the spliced
+// ops leave the directory unchanged.
+func DecodeOps(ops []Op) ([]plan.Op, error) {
+	out := make([]plan.Op, 0, len(ops))
+	depth := 0
+	for i, o := range ops {
+		switch o.Kind {
+		case "pushd":
+			if o.Dir == "" {
+				return nil, fmt.Errorf("op #%d: pushd without dir", i)
+			}
+			depth++
+			out = append(out, plan.OpPushd{Dir: o.Dir})
+		case "popd":
+			if depth == 0 {
+				return nil, fmt.Errorf("op #%d: popd without matching pushd", i)
+			}
+			depth--
+			out = append(out, plan.OpPopd{})
+		case "run":
+			out = append(out, plan.OpRun{Line: o.Line})
+		case "echo":
+			out = append(out, plan.OpEcho{Line: o.Msg})
+		case "env":
+			if !config.IsEnvName(o.Name) {
+				return nil, fmt.Errorf("op #%d: bad env name %q", i, o.Name)
+			}
+			out = append(out, plan.OpEnv{Name: o.Name, Value: o.Value})
+		case "block", "summary", "step", "timings":
+			// spliced into the builder's own step, they would clobber its
+			// timing and status
+			return nil, fmt.Errorf("op #%d: kind %q is not allowed in a command, only run, echo, pushd, popd and env", i, o.Kind)
+		default:
+			return nil, fmt.Errorf("op #%d: unknown kind %q", i, o.Kind)
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("%d pushd op(s) without popd", depth)
+	}
+	return out, nil
+}
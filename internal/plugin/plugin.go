@@ -0,0 +1,235 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ProtocolVersion is the plugin protocol spoken by this pm-bin.
+// See doc/PLUGINS.md.
+const ProtocolVersion = 1
+
+// Timeout bounds every call to a plugin.
+var Timeout = 10 * time.Second
+
+// Kind distinguishes renderer plugins (pm-render-*) from command plugins
+// (pm-cmd-*).
+type Kind string
+
+const (
+	KindRender  Kind = "render"
+	KindCommand Kind = "cmd"
+)
+
+// Prefix is the executable name prefix for plugins of this kind.
+func (k Kind) Prefix() string { return "pm-" + string(k) + "-" }
+
+// Info is what a plugin reports for --describe.
+type Info struct {
+	Name            string   `json:"name"`
+	Version         string   `json:"version"`
+	ProtocolVersion int      `json:"protocol_version"`
+	Ops             []string `json:"ops,omitempty"`
+	Description     string   `json:"description,omitempty"`
+
+	Kind Kind   `json:"-"`
+	Path string `json:"-"`
+	// Legacy is set for renderer plugins that don't implement --describe;
+	// they are assumed to handle the LegacyOps.
+	Legacy bool `json:"-"`
+}
+
+// Supports reports whether a renderer plugin can render ops of the given kind.
+func (pi Info) Supports(kind string) bool {
+	if pi.Legacy {
+		return slices.Contains(LegacyOps, kind)
+	}
+	return slices.Contains(pi.Ops, kind)
+}
+
+// Validate checks a --describe answer against this pm-bin.
+func (pi Info) Validate() error {
+	if pi.Legacy {
+		return nil
+	}
+	if strings.TrimSpace(pi.Name) == "" {
+		return errors.New("describe: empty name")
+	}
+	if pi.ProtocolVersion < 1 || pi.ProtocolVersion > ProtocolVersion {
+		return fmt.Errorf("describe: unsupported protocol_version %d (pm-bin speaks %d)", pi.ProtocolVersion, ProtocolVersion)
+	}
+	if pi.Kind == KindRender && !pi.Supports("run") {
+		return errors.New(`describe: plugin must support the "run" op`)
+	}
+	for _, k := range pi.Ops {
+		if !slices.Contains(OpKinds, k) {
+			return fmt.Errorf("describe: unknown op kind %q", k)
+		}
+	}
+	return nil
+}
+
+// Error is the structured error a plugin may print on stdout when it exits
+// non-zero.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Op      *int   `json:"op,omitempty"`
+}
+
+func (e *Error) Error() string {
+	msg := e.Message
+	if e.Code != "" {
+		msg = e.Code + ": " + msg
+	}
+	if e.Op != nil {
+		msg += fmt.Sprintf(" (op #%d)", *e.Op)
+	}
+	return msg
+}
+
+// Lookup returns the plugin executable for name, or "" when there is none.
+func Lookup(dir string, kind Kind, name string) string {
+	exe := filepath.Join(Expand(dir), kind.Prefix()+name)
+	if fileExists(exe) {
+		return exe
+	}
+	if fileExists(exe + ".exe") {
+		return exe + ".exe"
+	}
+	return ""
+}
+
+// Describe runs `<plugin> --describe`. A renderer that fails to answer with
+// JSON is treated as a legacy (protocol 0) plugin; command plugins must
+// describe themselves.
+func Describe(kind Kind, exe string) (Info, error) {
+	name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(exe), kind.Prefix()), ".exe")
+	out, _, err := run(exe, nil, "--describe")
+	var info Info
+	if err != nil || json.Unmarshal(out, &info) != nil || info.ProtocolVersion == 0 {
+		if kind == KindRender && !errors.Is(err, context.DeadlineExceeded) {
+			return Info{Name: name, Kind: kind, Path: exe, Legacy: true}, nil
+		}
+		if err == nil {
+			err = errors.New("describe: no valid JSON on stdout")
+		}
+		return Info{Name: name, Kind: kind, Path: exe}, err
+	}
+	info.Kind = kind
+	info.Path = exe
+	return info, info.Validate()
+}
+
+// Discover describes every pm-render-* and pm-cmd-* executable in dir.
+// Plugins that fail validation are returned alongside their error.
+func Discover(dir string) ([]Info, []error, error) {
+	dir = Expand(dir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	var infos []Info
+	var errs []error
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		for _, kind := range []Kind{KindRender, KindCommand} {
+			if !strings.HasPrefix(e.Name(), kind.Prefix()) {
+				continue
+			}
+			info, err := Describe(kind, filepath.Join(dir, e.Name()))
+			infos = append(infos, info)
+			errs = append(errs, err)
+		}
+	}
+	return infos, errs, nil
+}
+
+// Names lists the plugin names of kind in dir without running them.
+func Names(dir string, kind Kind) []string {
+	entries, err := os.ReadDir(Expand(dir))
+	if err != nil {
+		return nil
+	}
+	var out []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), kind.Prefix()) {
+			out = append(out, strings.TrimSuffix(strings.TrimPrefix(e.Name(), kind.Prefix()), ".exe"))
+		}
+	}
+	return out
+}
+
+// Call runs the plugin with args and stdin and returns its stdout. On a
+// non-zero exit a structured {"error": ...} on stdout becomes the returned
+// *Error; otherwise the exit status and stderr are reported. The plugin's
+// stderr is forwarded on success.
+func Call(info Info, stdin []byte, args ...string) ([]byte, error) {
+	out, stderr, err := run(info.Path, stdin, args...)
+	if err != nil {
+		var pe struct {
+			Error *Error `json:"error"`
+		}
+		if json.Unmarshal(out, &pe) == nil && pe.Error != nil {
+			return nil, fmt.Errorf("plugin %s: %w", info.Name, pe.Error)
+		}
+		if msg := strings.TrimSpace(stderr); msg != "" {
+			return nil, fmt.Errorf("plugin %s: %w: %s", info.Name, err, msg)
+		}
+		return nil, fmt.Errorf("plugin %s: %w", info.Name, err)
+	}
+	if stderr != "" {
+		fmt.Fprint(os.Stderr, stderr)
+	}
+	return out, nil
+}
+
+// run executes a plugin with Timeout, returning stdout and stderr.
+func run(exe string, stdin []byte, args ...string) ([]byte, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, exe, args...)
+	// don't wait forever for grandchildren that inherited the pipes
+	cmd.WaitDelay = time.Second
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var out, errb bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &errb
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return out.Bytes(), errb.String(), fmt.Errorf("timed out after %s: %w", Timeout, ctx.Err())
+	}
+	return out.Bytes(), errb.String(), err
+}
+
+// Expand resolves env variables and a leading ~ in a plugins dir.
+func Expand(s string) string {
+	s = os.ExpandEnv(s)
+	if strings.HasPrefix(s, "~") {
+		if home, _ := os.UserHomeDir(); home != "" {
+			return filepath.Join(home, s[1:])
+		}
+	}
+	return s
+}
+
+func fileExists(p string) bool {
+	st, err := os.Stat(p)
+	return err == nil && !st.IsDir()
+}
@@ -0,0 +1,166 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"pm/internal/config"
+	"pm/internal/plan"
+)
+
+// writePlugin creates an executable shell script plugin in dir.
+func writePlugin(t *testing.T, dir, file, body string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script plugins need a POSIX shell")
+	}
+	path := filepath.Join(dir, file)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const deployPlugin = `if [ "$1" = "--describe" ]; then
+  echo '{"name":"deploy","version":"1.0.0","protocol_version":1,"description":"Deploy to k8s"}'
+  exit 0
+fi
+input=$(cat)
+case "$input" in
+  *'"args":["prod"]'*) env=prod ;;
+  *) env=dev ;;
+esac
+echo '{"ops":[{"kind":"env","name":"KUBE_ENV","value":"'$env'"},{"kind":"pushd","dir":"k8s"},{"kind":"run","line":"kubectl apply -f ."},{"kind":"popd"}]}'
+`
+
+func TestDiscover_ValidatesPlugins(t *testing.T) {
+	dir := t.TempDir()
+	writePlugin(t, dir, "pm-render-runonly", `echo '{"name":"runonly","version":"0.1.0","protocol_version":1,"ops":["run"]}'`+"\n")
+	writePlugin(t, dir, "pm-render-future", `echo '{"name":"future","version":"9","protocol_version":99,"ops":["run"]}'`+"\n")
+	writePlugin(t, dir, "pm-cmd-deploy", deployPlugin)
+	writePlugin(t, dir, "pm-cmd-mute", "exit 0\n")
+	if err := os.WriteFile(filepath.Join(dir, "README"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	infos, errs, err := Discover(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 4 {
+		t.Fatalf("expected 4 plugins, got %+v", infos)
+	}
+	byName := map[string]error{}
+	for i, pi := range infos {
+		byName[string(pi.Kind)+"/"+pi.Name] = errs[i]
+	}
+	if byName["render/runonly"] != nil || byName["cmd/deploy"] != nil {
+		t.Fatalf("valid plugins rejected: %v", byName)
+	}
+	if e := byName["render/future"]; e == nil || !strings.Contains(e.Error(), "protocol_version 99") {
+		t.Fatalf("future should fail validation, got %v", e)
+	}
+	// command plugins have no legacy mode
+	if byName["cmd/mute"] == nil {
+		t.Fatal("cmd plugin without --describe should be invalid")
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	dir := t.TempDir()
+	writePlugin(t, dir, "pm-cmd-deploy", deployPlugin)
+
+	exe := Lookup(dir, KindCommand, "deploy")
+	if exe == "" {
+		t.Fatal("Lookup did not find pm-cmd-deploy")
+	}
+	info, err := Describe(KindCommand, exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops, err := RunCommand(info, CommandRequest{
+		Command: "deploy",
+		Args:    []string{"prod"},
+		Root:    "/proj",
+		Project: &config.ProjectMeta{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []plan.Op{
+		plan.OpEnv{Name: "KUBE_ENV", Value: "prod"},
+		plan.OpPushd{Dir: "k8s"},
+		plan.OpRun{Line: "kubectl apply -f ."},
+		plan.OpPopd{},
+	}
+	if len(ops) != len(want) {
+		t.Fatalf("got %#v", ops)
+	}
+	for i := range want {
+		if ops[i] != want[i] {
+			t.Fatalf("op %d: got %#v want %#v", i, ops[i], want[i])
+		}
+	}
+}
+
+func TestDecodeOps_Unbalanced(t *testing.T) {
+	if _, err := DecodeOps([]Op{{Kind: "popd"}}); err == nil {
+		t.Fatal("expected error for popd without pushd")
+	}
+	if _, err := DecodeOps([]Op{{Kind: "pushd", Dir: "x"}}); err == nil {
+		t.Fatal("expected error for unclosed pushd")
+	}
+	if _, err := DecodeOps([]Op{{Kind: "rm -rf"}}); err == nil {
+		t.Fatal("expected error for unknown kind")
+	}
+}
+
+// TestDecodeOps_EnvName: renderers export env names unquoted.
+func TestDecodeOps_EnvName(t *testing.T) {
+	for _, name := range []string{"", "A B", "X=1; rm -rf ~; Y", "1X", "$(id)"} {
+		if _, err := DecodeOps([]Op{{Kind: "env", Name: name, Value: "v"}}); err == nil {
+			t.Errorf("expected error for env name %q", name)
+		}
+	}
+	if _, err := DecodeOps([]Op{{Kind: "env", Name: "_KUBE_ENV2", Value: "v"}}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestDecodeOps_Structural: a command's ops end up inside the builder's
+// step, so they cannot bring steps, blocks or tables of their own.
+func TestDecodeOps_Structural(t *testing.T) {
+	step, _ := EncodeOp(plan.OpStep{Name: ":test", Ops: []plan.Op{plan.OpRun{Line: "make test"}}})
+	for _, o := range []Op{step, {Kind: "block", Name: "api"}, {Kind: "summary"}, {Kind: "timings"}} {
+		if _, err := DecodeOps([]Op{o}); err == nil || !strings.Contains(err.Error(), "not allowed") {
+			t.Errorf("%s: err = %v", o.Kind, err)
+		}
+	}
+}
+
+func TestEncodeOp_Step(t *testing.T) {
+	eo, ok := EncodeOp(plan.OpStep{
+		Name:      ":test",
+		Ops:       []plan.Op{plan.OpRun{Line: "make test"}},
+		Notify:    &plan.Notify{Title: "pm: api", After: 30 * time.Second, Via: "bell"},
+		OnFailure: []plan.Op{plan.OpRun{Line: "rm -rf tmp"}},
+	})
+	if !ok || eo.Kind != "step" || len(eo.Ops) != 1 || eo.Notify == nil || eo.Notify.AfterMs != 30000 || len(eo.OnFailure) != 1 {
+		t.Fatalf("encoded %+v", eo)
+	}
+}
+
+func TestEncodeOp_BlockOnFailure(t *testing.T) {
+	eo, ok := EncodeOp(plan.OpBlock{
+		Name:      "api",
+		Ops:       []plan.Op{plan.OpRun{Line: "make test"}},
+		OnFailure: []plan.Op{plan.OpRun{Line: "rm -rf tmp"}},
+	})
+	if !ok || eo.Kind != "block" || len(eo.Ops) != 1 || len(eo.OnFailure) != 1 || eo.OnFailure[0].Line != "rm -rf tmp" {
+		t.Fatalf("encoded %+v", eo)
+	}
+}
@@ -31,13 +31,139 @@ func (b bashRenderer) RenderOp(op plan.Op) []string {
 		return []string{"popd >/dev/null"}
 	case plan.OpEcho:
 		return []string{fmt.Sprintf("echo %s", sh(v.Line))}
+	case plan.OpEnv:
+		return []string{fmt.Sprintf("export %s=%s", v.Name, sh(v.Value))}
 	case plan.OpRun:
 		return []string{v.Line}
+	case plan.OpBlock:
+		return posixBlock(b, v, posixQuote(v.Name))
+	case plan.OpSummary:
+		return posixSummary("echo")
+	case plan.OpStep:
+		return posixStep(b, v, posixErrexit)
+	case plan.OpTimings:
+		return posixTimings()
 	default:
 		return nil
 	}
 }
 
+// posixBlock runs the block in a `set -e` subshell with errexit switched
+// off around it, so that a failure ends the block but not the script; its
+// failure hooks run in a subshell of their own. Shared by the bash and sh
+// renderers.
+func posixBlock(r Renderer, v plan.OpBlock, name string) []string {
+	out := []string{"__pm_o=$-; set +e", "(", "set -e"}
+	out = append(out, renderOps(r, v.Ops)...)
+	out = append(out, ")", "__pm_rc=$?")
+	if len(v.OnFailure) > 0 {
+		out = append(out, `if [ "$__pm_rc" -ne 0 ]; then`, "(")
+		out = append(out, renderOps(r, v.OnFailure)...)
+		out = append(out, ")", "fi")
+	}
+	return append(out,
+		"case $__pm_o in *e*) set -e;; esac",
+		fmt.Sprintf(`if [ "$__pm_rc" -eq 0 ]; then __pm_ok="${__pm_ok:-} "%s; else __pm_failed="${__pm_failed:-} "%s; fi`, name, name),
+	)
+}
+
+// posixSummary prints the block outcomes with echo (echo or print -r --)
+// and leaves a failing status when any block failed.
+func posixSummary(echo string) []string {
+	return []string{
+		fmt.Sprintf(`[ -z "${__pm_ok:-}" ] || %s "# pm: ok:$__pm_ok"`, echo),
+		fmt.Sprintf(`__pm_rc=0; [ -z "${__pm_failed:-}" ] || { %s "# pm: failed:$__pm_failed"; __pm_rc=1; }`, echo),
+		"unset __pm_ok __pm_failed",
+		`[ "$__pm_rc" -eq 0 ]`,
+	}
+}
+
+// posixClock sets a variable to the time in microseconds: EPOCHREALTIME
+// in bash 5 and zsh/datetime, whole seconds from date elsewhere.
+func posixClock(name string) string {
+	return fmt.Sprintf("%s=${EPOCHREALTIME:-$(date +%%s).000000}; %s=${%s%%[.,]*}${%s#*[.,]}", name, name, name, name)
+}
+
+// posixErrexit switches errexit off and back on if it was set; its last
+// format runs a line only when it was, that is when the script stops on
+// a failing step.
+var posixErrexit = [3]string{"__pm_o=$-; set +e", "case $__pm_o in *e*) set -e;; esac", "case $__pm_o in *e*) %s;; esac"}
+
+// posixStep runs the step's ops and appends "name, duration, status" to
+// __pm_steps for posixTimings. Shared by bash, sh and zsh.
+//
+// errexit (errexit: the lines that switch it off and on, and the format of
+// a line run only when it is on) would end the script before the step is
+// recorded, notified, cleaned up after and the table printed, so the ops
+// run with it off, each only while the step has not failed, and the
+// step's status is raised again after them. The table is printed early
+// only when that stops the script; otherwise the plan's own prints it,
+// failed step included.
+func posixStep(r Renderer, v plan.OpStep, errexit [3]string) []string {
+	out := []string{posixClock("__pm_t"), errexit[0], "__pm_rc=0"}
+	for _, op := range v.Ops {
+		out = append(out, `if [ "$__pm_rc" -eq 0 ]; then`)
+		out = append(out, r.RenderOp(op)...)
+		out = append(out, "__pm_rc=$?", "fi")
+	}
+	out = append(out,
+		posixClock("__pm_e"),
+		fmt.Sprintf(`__pm_steps="${__pm_steps:-}"%s"	$((__pm_e - __pm_t))	$__pm_rc`, posixQuote(v.Name)),
+		`"`,
+	)
+	if v.Notify != nil {
+		out = append(out, posixNotify(v.Notify, v.Name)...)
+	}
+	if len(v.OnFailure) > 0 || v.Table {
+		out = append(out, `if [ "$__pm_rc" -ne 0 ]; then`)
+		out = append(out, renderOps(r, v.OnFailure)...)
+		if v.Table {
+			out = append(out, fmt.Sprintf(errexit[2], posixTimings()[0]))
+		}
+		out = append(out, "fi")
+	}
+	return append(out, errexit[1], `(exit "$__pm_rc")`)
+}
+
+// appleNotify is the osascript arguments showing a notification titled by
+// the first argument that follows, with the second as its text.
+const appleNotify = `osascript -e 'on run a' -e 'display notification (item 2 of a) with title (item 1 of a)' -e 'end run'`
+
+// posixNotify sends the step's notification when it ran long enough.
+func posixNotify(n *plan.Notify, step string) []string {
+	title := posixQuote(n.Title)
+	out := []string{
+		fmt.Sprintf("if [ $((__pm_e - __pm_t)) -ge %d ]; then", n.After.Microseconds()),
+		fmt.Sprintf(`if [ "$__pm_rc" -eq 0 ]; then __pm_m=%s; else __pm_m=%s"$__pm_rc"')'; fi`,
+			posixQuote(step+": ok"), posixQuote(step+": failed (exit ")),
+	}
+	switch n.Via {
+	case "bell":
+		out = append(out, `printf '\a' >&2`)
+	case "osc9":
+		out = append(out, fmt.Sprintf(`printf '\033]9;%%s: %%s\a' %s "$__pm_m" >&2`, title))
+	default:
+		out = append(out,
+			fmt.Sprintf(`if command -v notify-send >/dev/null 2>&1; then notify-send %s "$__pm_m"`, title),
+			fmt.Sprintf(`elif command -v osascript >/dev/null 2>&1; then %s %s "$__pm_m" >/dev/null`, appleNotify, title),
+			`else printf '\a' >&2`,
+			"fi",
+		)
+	}
+	return append(out, "fi")
+}
+
+// timingsAwk formats "name<TAB>microseconds<TAB>status" lines as the table
+// of steps.
+const timingsAwk = `awk -F'\t' 'NF == 3 { printf "# pm: %-28s %7.1fs  %s\n", $1, $2 / 1000000, ($3 == 0 ? "ok" : "exit " $3) }'`
+
+func posixTimings() []string {
+	return []string{
+		`printf '%s' "${__pm_steps:-}" | ` + timingsAwk,
+		"unset __pm_steps",
+	}
+}
+
 func sh(s string) string {
 	if strings.ContainsAny(s, " \t\"'") {
 		return "'" + strings.ReplaceAll(s, "'", "'\"'\"'") + "'"
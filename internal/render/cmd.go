@@ -0,0 +1,164 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"pm/internal/plan"
+)
+
+// cmdRenderer emits a Windows cmd.exe batch script. The wrappers call it,
+// so a failing step leaves it with exit /b and its status.
+type cmdRenderer struct{}
+
+func (c cmdRenderer) Name() string { return "cmd" }
+
+func (c cmdRenderer) Begin(root string) []string {
+	return []string{
+		"@echo off",
+		"rem pm begin",
+		`set "__pm_start=%CD%"`,
+		fmt.Sprintf("pushd %s", cmdQuote(root)),
+	}
+}
+func (c cmdRenderer) End() []string {
+	// %ERRORLEVEL% is expanded before popd runs
+	return []string{
+		"popd & exit /b %ERRORLEVEL%",
+		"rem pm end",
+	}
+}
+func (c cmdRenderer) RenderOp(op plan.Op) []string {
+	switch v := op.(type) {
+	case plan.OpPushd:
+		return []string{fmt.Sprintf("pushd %s", cmdQuote(v.Dir))}
+	case plan.OpPopd:
+		return []string{"popd"}
+	case plan.OpEcho:
+		if strings.TrimSpace(v.Line) == "" {
+			return []string{"echo."}
+		}
+		return []string{"echo " + cmdEscape(v.Line)}
+	case plan.OpEnv:
+		return []string{fmt.Sprintf(`set "%s=%s"`, v.Name, strings.ReplaceAll(v.Value, "%", "%%"))}
+	case plan.OpRun:
+		return []string{v.Line}
+	case plan.OpBlock:
+		// every line runs only while the block has not failed; (call ) resets
+		// ERRORLEVEL and the directory is restored after the block, then
+		// again after the failure hooks
+		out := []string{`set "__pm_rc=0"`, `set "__pm_here=%CD%"`, "(call )"}
+		for _, op := range v.Ops {
+			for _, l := range c.RenderOp(op) {
+				out = append(out, `if "%__pm_rc%"=="0" `+l)
+			}
+			out = append(out, `if errorlevel 1 set "__pm_rc=1"`)
+		}
+		out = append(out, `cd /d "%__pm_here%"`)
+		if len(v.OnFailure) > 0 {
+			for _, l := range append(renderOps(c, v.OnFailure), `cd /d "%__pm_here%"`) {
+				out = append(out, `if not "%__pm_rc%"=="0" `+l)
+			}
+		}
+		name := strings.ReplaceAll(v.Name, "%", "%%")
+		return append(out,
+			fmt.Sprintf(`if "%%__pm_rc%%"=="0" (set "__pm_ok=%%__pm_ok%% %s") else (set "__pm_failed=%%__pm_failed%% %s")`, name, name),
+		)
+	case plan.OpSummary:
+		return []string{
+			"if defined __pm_ok echo # pm: ok:%__pm_ok%",
+			`set "__pm_rc=0"`,
+			`if defined __pm_failed (echo # pm: failed:%__pm_failed%& set "__pm_rc=1")`,
+			`set "__pm_ok=" & set "__pm_failed="`,
+			`if "%__pm_rc%"=="1" (call)`,
+		}
+	case plan.OpStep:
+		// the lines are guarded as in a block; the step's status is the
+		// first failing one
+		out := []string{cmdClock("__pm_t"), `set "__pm_src=0"`, "(call )"}
+		for _, op := range v.Ops {
+			for _, l := range c.RenderOp(op) {
+				out = append(out, `if "%__pm_src%"=="0" `+l)
+			}
+			out = append(out, `if "%__pm_src%"=="0" if errorlevel 1 set "__pm_src=%ERRORLEVEL%"`)
+		}
+		name := strings.ReplaceAll(v.Name, "%", "%%")
+		out = append(out,
+			cmdClock("__pm_e"),
+			`set /a "__pm_d=__pm_e-__pm_t, __pm_n+=1"`,
+			`if %__pm_d% lss 0 set /a "__pm_d+=8640000"`,
+			`set /a "__pm_sec=__pm_d/100, __pm_cs=100+__pm_d%%100"`,
+			`if "%__pm_src%"=="0" (set "__pm_st=ok") else (set "__pm_st=exit %__pm_src%")`,
+			fmt.Sprintf(`set "__pm_s%%__pm_n%%=%s  %%__pm_sec%%.%%__pm_cs:~-2%%s  %%__pm_st%%"`, name),
+		)
+		if v.Notify != nil {
+			out = append(out, cmdNotify(v.Notify, name)...)
+		}
+		// a failed step stops the script after its hooks and the table,
+		// back in the directory it started in
+		stop := renderOps(c, v.OnFailure)
+		if v.Table {
+			stop = append(stop, c.RenderOp(plan.OpTimings{})...)
+		}
+		stop = append(stop, `cd /d "%__pm_start%"`, "exit /b %__pm_src%")
+		for _, line := range stop {
+			out = append(out, `if not "%__pm_src%"=="0" `+line)
+		}
+		return out
+	case plan.OpTimings:
+		return []string{
+			`if defined __pm_n for /l %%i in (1,1,%__pm_n%) do call echo # pm: %%__pm_s%%i%%& set "__pm_s%%i="`,
+			`set "__pm_n="`,
+		}
+	default:
+		return nil
+	}
+}
+
+// cmdNotify sends the step's notification when it ran long enough; the
+// desktop one goes through PowerShell's BurntToast, which reads the title
+// and text from the environment.
+func cmdNotify(n *plan.Notify, step string) []string {
+	cond := fmt.Sprintf("if %%__pm_d%% geq %d ", n.After.Milliseconds()/10)
+	out := []string{
+		fmt.Sprintf(`set "__pm_title=%s"`, strings.ReplaceAll(n.Title, "%", "%%")),
+		fmt.Sprintf(`set "__pm_m=%s: ok"`, step),
+		fmt.Sprintf(`if not "%%__pm_src%%"=="0" set "__pm_m=%s: failed (exit %%__pm_src%%)"`, step),
+	}
+	switch n.Via {
+	case "bell":
+		return append(out, cond+"echo \a")
+	case "osc9":
+		return append(out, cond+"echo \x1b]9;%__pm_title%: %__pm_m%\a")
+	}
+	return append(out, cond+`powershell -NoProfile -Command "if (Get-Command New-BurntToastNotification -ErrorAction SilentlyContinue) { New-BurntToastNotification -Text $env:__pm_title, $env:__pm_m } else { [Console]::Beep() }"`)
+}
+
+// cmdClock sets a variable to %TIME% in centiseconds; the 1xx-100 keeps
+// 08 and 09 from being read as octal.
+func cmdClock(name string) string {
+	return fmt.Sprintf(`for /f "tokens=1-4 delims=:.," %%%%a in ("%%TIME: =0%%") do set /a "%s=((1%%%%a-100)*3600+(1%%%%b-100)*60+(1%%%%c-100))*100+(1%%%%d-100)"`, name)
+}
+
+// cmdQuote wraps a path argument in double quotes; '"' cannot appear in
+// Windows paths, % still has to be doubled inside a batch file.
+func cmdQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, "%", "%%") + `"`
+}
+
+// cmdEscape escapes cmd.exe metacharacters for unquoted echo text.
+func cmdEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '^', '&', '|', '<', '>', '(', ')':
+			b.WriteRune('^')
+			b.WriteRune(r)
+		case '%':
+			b.WriteString("%%")
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,142 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"pm/internal/plan"
+)
+
+// fishRenderer emits fish code. The script is the body of a function,
+// which erases itself when called, so that a failing step can return from
+// it with its status the way errexit stops the other shells.
+type fishRenderer struct{}
+
+func (f fishRenderer) Name() string { return "fish" }
+
+func (f fishRenderer) Begin(root string) []string {
+	return []string{
+		"# pm begin",
+		"function __pm_script",
+		"functions -e __pm_script",
+		"set -l __pm_base (count $dirstack)",
+		fmt.Sprintf("pushd %s", fishQuote(root)),
+	}
+}
+func (f fishRenderer) End() []string {
+	return []string{
+		"set -l __pm_s $status",
+		"popd",
+		"return $__pm_s",
+		"end",
+		"__pm_script",
+		"# pm end",
+	}
+}
+func (f fishRenderer) RenderOp(op plan.Op) []string {
+	switch v := op.(type) {
+	case plan.OpPushd:
+		return []string{fmt.Sprintf("pushd %s", fishQuote(v.Dir))}
+	case plan.OpPopd:
+		return []string{"popd"}
+	case plan.OpEcho:
+		return []string{fmt.Sprintf("echo %s", fishQuote(v.Line))}
+	case plan.OpEnv:
+		return []string{fmt.Sprintf("set -gx %s %s", v.Name, fishQuote(v.Value))}
+	case plan.OpRun:
+		return []string{v.Line}
+	case plan.OpBlock:
+		// fish has no subshell or errexit: every op runs only while the
+		// block has not failed, and the directory stack is unwound after,
+		// then again after the failure hooks.
+		out := []string{"set -g __pm_rc 0", "set -g __pm_depth (count $dirstack)"}
+		for _, op := range v.Ops {
+			out = append(out, "if test $__pm_rc -eq 0")
+			out = append(out, f.RenderOp(op)...)
+			out = append(out, "or set -g __pm_rc $status", "end")
+		}
+		out = append(out, "while test (count $dirstack) -gt $__pm_depth; popd; end")
+		if len(v.OnFailure) > 0 {
+			out = append(out, "if test $__pm_rc -ne 0")
+			out = append(out, renderOps(f, v.OnFailure)...)
+			out = append(out, "while test (count $dirstack) -gt $__pm_depth; popd; end", "end")
+		}
+		name := fishQuote(v.Name)
+		return append(out,
+			fmt.Sprintf("if test $__pm_rc -eq 0; set -ga __pm_ok %s; else; set -ga __pm_failed %s; end", name, name),
+		)
+	case plan.OpSummary:
+		return []string{
+			`set -q __pm_ok[1]; and echo "# pm: ok: $__pm_ok"`,
+			`set -g __pm_rc 0; if set -q __pm_failed[1]; echo "# pm: failed: $__pm_failed"; set -g __pm_rc 1; end`,
+			"set -e __pm_ok; set -e __pm_failed",
+			"test $__pm_rc -eq 0",
+		}
+	case plan.OpStep:
+		// fish has no clock of its own: whole seconds from date
+		// fish has no errexit either: the ops are guarded as in a block
+		// and the step's status is the first failing one
+		out := []string{`set -g __pm_t (math (date +%s) \* 1000000)`, "set -g __pm_rc 0"}
+		for _, op := range v.Ops {
+			out = append(out, "if test $__pm_rc -eq 0")
+			out = append(out, f.RenderOp(op)...)
+			out = append(out, "or set -g __pm_rc $status", "end")
+		}
+		out = append(out,
+			`set -g __pm_d (math (date +%s) \* 1000000 - $__pm_t)`,
+			fmt.Sprintf(`set -ga __pm_steps (printf '%%s\t%%s\t%%s' %s $__pm_d $__pm_rc)`, fishQuote(v.Name)),
+		)
+		if v.Notify != nil {
+			out = append(out, fishNotify(v.Notify, v.Name)...)
+		}
+		// a failed step stops the script after its hooks and the table,
+		// back in the directory it started in
+		out = append(out, "if test $__pm_rc -ne 0")
+		out = append(out, renderOps(f, v.OnFailure)...)
+		if v.Table {
+			out = append(out, f.RenderOp(plan.OpTimings{})...)
+		}
+		return append(out,
+			"while test (count $dirstack) -gt $__pm_base; popd; end",
+			"return $__pm_rc",
+			"end",
+		)
+	case plan.OpTimings:
+		return []string{
+			`printf '%s\n' $__pm_steps | ` + timingsAwk,
+			"set -e __pm_steps",
+		}
+	default:
+		return nil
+	}
+}
+
+// fishQuote single-quotes s; inside fish single quotes only \ and ' are special.
+func fishQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n\"'\\$*?~#(){}[]<>&|;") {
+		return s
+	}
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `'`, `\'`)
+	return "'" + s + "'"
+}
+
+// fishNotify sends the step's notification when it ran long enough.
+func fishNotify(n *plan.Notify, step string) []string {
+	title := fishQuote(n.Title)
+	out := []string{
+		fmt.Sprintf("if test $__pm_d -ge %d", n.After.Microseconds()),
+		fmt.Sprintf("if test $__pm_rc -eq 0; set -g __pm_m %s; else; set -g __pm_m %s$__pm_rc')'; end",
+			fishQuote(step+": ok"), fishQuote(step+": failed (exit ")),
+	}
+	switch n.Via {
+	case "bell":
+		out = append(out, `printf '\a' >&2`)
+	case "osc9":
+		out = append(out, fmt.Sprintf(`printf '\e]9;%%s: %%s\a' %s $__pm_m >&2`, title))
+	default:
+		out = append(out, fmt.Sprintf(`if command -q notify-send; notify-send %s $__pm_m; else if command -q osascript; %s %s $__pm_m >/dev/null; else; printf '\a' >&2; end`,
+			title, appleNotify, title))
+	}
+	return append(out, "end")
+}
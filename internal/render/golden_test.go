@@ -0,0 +1,133 @@
+package render
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"pm/internal/plan"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata/")
+
+func goldenPlan() *plan.Plan {
+	p := plan.New()
+	p.Pushd("/tmp/my project")
+	p.Echo("it's 50% done & <ok>")
+	p.Run("make build")
+	p.Env("APP_ENV", "it's prod")
+	p.Pushd("/tmp/sub")
+	p.Run("ls")
+	p.Popd()
+	return p
+}
+
+// fanoutPlan is what `pm api,web :build` produces, with an on_failure
+// hook in api.
+func fanoutPlan() *plan.Plan {
+	p := plan.New()
+	p.Ops = append(p.Ops, plan.OpBlock{Name: "api",
+		Ops:       []plan.Op{plan.OpPushd{Dir: "/tmp/api"}, plan.OpRun{Line: "make build"}, plan.OpPopd{}},
+		OnFailure: []plan.Op{plan.OpPushd{Dir: "/tmp/api"}, plan.OpRun{Line: "rm -rf tmp"}, plan.OpPopd{}}})
+	p.Block("web", []plan.Op{plan.OpPushd{Dir: "/tmp/web"}, plan.OpEcho{Line: "building"}, plan.OpPopd{}})
+	p.Summary()
+	return p
+}
+
+// stepsPlan is what `pm api :build :test :deploy` produces, with an
+// on_failure hook.
+func stepsPlan() *plan.Plan {
+	p := plan.New()
+	p.Pushd("/tmp/api")
+	p.Step(":build", []plan.Op{plan.OpRun{Line: "make build"}}, &plan.Notify{Title: "pm: api", Via: "osc9"})
+	p.Step(":test -v 100%", []plan.Op{plan.OpEnv{Name: "CI", Value: "1"}, plan.OpRun{Line: "make test"}}, &plan.Notify{Title: "pm: api", After: 30 * time.Second})
+	p.Ops = append(p.Ops, plan.OpStep{Name: ":deploy", Ops: []plan.Op{plan.OpRun{Line: "make deploy"}}, OnFailure: []plan.Op{plan.OpRun{Line: "rm -rf /tmp/api-*"}}})
+	p.Timings()
+	return p
+}
+
+// tablePlan is what `pm api :build :test` produces: plain steps whose
+// failure still prints the table.
+func tablePlan() *plan.Plan {
+	p := plan.New()
+	p.Pushd("/tmp/api")
+	p.Step(":build", []plan.Op{plan.OpRun{Line: "make build"}}, nil)
+	p.Step(":test", []plan.Op{plan.OpEnv{Name: "CI", Value: "1"}, plan.OpRun{Line: "make test"}}, nil)
+	p.Timings()
+	return p
+}
+
+func TestRender_Golden(t *testing.T) {
+	plans := map[string]func() *plan.Plan{"": goldenPlan, ".fanout": fanoutPlan, ".steps": stepsPlan, ".table": tablePlan}
+	for _, dialect := range []string{"bash", "zsh", "pwsh", "fish", "nu", "sh", "cmd"} {
+		for suffix, mk := range plans {
+			t.Run(dialect+suffix, func(t *testing.T) {
+				testGolden(t, mk(), dialect, filepath.Join("testdata", dialect+suffix+".golden"))
+			})
+		}
+	}
+}
+
+func testGolden(t *testing.T, pl *plan.Plan, dialect, path string) {
+	t.Helper()
+	got, err := Render(pl, dialect, "")
+	if err != nil {
+		t.Fatalf("render error: %v", err)
+	}
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden: %v", err)
+	}
+	if got != string(want) {
+		t.Fatalf("%s mismatch\n--- got ---\n%s\n--- want ---\n%s", path, got, want)
+	}
+}
+
+func TestQuote_Fish(t *testing.T) {
+	cases := map[string]string{
+		"plain":      "plain",
+		"a b":        "'a b'",
+		`it's`:       `'it\'s'`,
+		`back\slash`: `'back\\slash'`,
+	}
+	for in, want := range cases {
+		if got := fishQuote(in); got != want {
+			t.Errorf("fishQuote(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestQuote_Nu(t *testing.T) {
+	cases := map[string]string{
+		"a b":  "'a b'",
+		`it's`: `r#'it's'#`,
+		`x'#y`: `r##'x'#y'##`,
+	}
+	for in, want := range cases {
+		if got := nuQuote(in); got != want {
+			t.Errorf("nuQuote(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestQuote_Posix(t *testing.T) {
+	if got := posixQuote(`it's`); got != `'it'\''s'` {
+		t.Errorf("posixQuote = %q", got)
+	}
+}
+
+func TestQuote_Cmd(t *testing.T) {
+	if got := cmdEscape("a & b | c > 50%"); got != "a ^& b ^| c ^> 50%%" {
+		t.Errorf("cmdEscape = %q", got)
+	}
+	if got := cmdQuote(`C:\Program Files`); got != `"C:\Program Files"` {
+		t.Errorf("cmdQuote = %q", got)
+	}
+}
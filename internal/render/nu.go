@@ -0,0 +1,161 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"pm/internal/cache"
+	"pm/internal/plan"
+)
+
+// nuRenderer emits nushell code. Nushell has no pushd/popd, so the
+// directory stack is kept in a mutable list.
+type nuRenderer struct{}
+
+func (n nuRenderer) Name() string { return "nu" }
+
+func (n nuRenderer) Begin(root string) []string {
+	return []string{
+		"# pm begin",
+		"mut __pm_dirs = []",
+		nuPushd(root),
+	}
+}
+func (n nuRenderer) End() []string {
+	return []string{
+		nuPopd(),
+		"# pm end",
+	}
+}
+func (n nuRenderer) RenderOp(op plan.Op) []string {
+	switch v := op.(type) {
+	case plan.OpPushd:
+		return []string{nuPushd(v.Dir)}
+	case plan.OpPopd:
+		return []string{nuPopd()}
+	case plan.OpEcho:
+		return []string{fmt.Sprintf("print %s", nuQuote(v.Line))}
+	case plan.OpEnv:
+		out := []string{fmt.Sprintf("$env.%s = %s", v.Name, nuQuote(v.Value))}
+		if v.Name == cache.DoneVar {
+			out = append(out, fmt.Sprintf("if '%s' in $env { %s | save -f $env.%s }", cache.DoneFile, nuQuote(v.Value), cache.DoneFile))
+		}
+		return out
+	case plan.OpRun:
+		return []string{v.Line}
+	case plan.OpBlock:
+		// an error (including a failing external command) ends the try
+		// block; the working directory is restored after it and after the
+		// failure hooks, whose own failure does not stop the script
+		out := []string{"let __pm_here = $env.PWD", "let __pm_r = try {"}
+		out = append(out, renderOps(n, v.Ops)...)
+		out = append(out, "true", "} catch { false }", "cd $__pm_here")
+		if len(v.OnFailure) > 0 {
+			out = append(out, "if not $__pm_r {", "try {")
+			out = append(out, renderOps(n, v.OnFailure)...)
+			out = append(out, "}", "cd $__pm_here", "}")
+		}
+		name := nuQuote(v.Name)
+		return append(out,
+			fmt.Sprintf("$env.__pm_ok = ($env.__pm_ok? | default [] | append (if $__pm_r { [%s] } else { [] }))", name),
+			fmt.Sprintf("$env.__pm_failed = ($env.__pm_failed? | default [] | append (if $__pm_r { [] } else { [%s] }))", name),
+		)
+	case plan.OpSummary:
+		return []string{
+			`if ($env.__pm_ok? | default [] | is-not-empty) { print $"# pm: ok: ($env.__pm_ok | str join ' ')" }`,
+			"let __pm_failed = ($env.__pm_failed? | default [])",
+			`if ($__pm_failed | is-not-empty) { print $"# pm: failed: ($__pm_failed | str join ' ')" }`,
+			"hide-env -i __pm_ok __pm_failed",
+			"if ($__pm_failed | is-not-empty) { error make {msg: 'pm: some projects failed'} }",
+		}
+	case plan.OpStep:
+		out := []string{"let __pm_t = (date now)"}
+		if !guarded(v) {
+			out = append(out, renderOps(n, v.Ops)...)
+			out = append(out, "let __pm_rc = ($env.LAST_EXIT_CODE? | default 0)")
+		} else {
+			// a failing command would end the script before the
+			// notification, failure hooks and table: the step's error
+			// is raised again after them
+			out = append(out, "let __pm_rc = try {")
+			out = append(out, renderOps(n, v.Ops)...)
+			out = append(out, "0", "} catch { $env.LAST_EXIT_CODE? | default 1 }")
+		}
+		out = append(out,
+			"let __pm_d = ((date now) - $__pm_t)",
+			fmt.Sprintf("$env.__pm_steps = ($env.__pm_steps? | default [] | append {step: %s, ms: ($__pm_d / 1ms), status: $__pm_rc})", nuQuote(v.Name)),
+		)
+		if !guarded(v) {
+			return out
+		}
+		if v.Notify != nil {
+			out = append(out, nuNotify(v.Notify, v.Name)...)
+		}
+		if len(v.OnFailure) > 0 || v.Table {
+			out = append(out, "if $__pm_rc != 0 {")
+			out = append(out, renderOps(n, v.OnFailure)...)
+			if v.Table {
+				out = append(out, n.RenderOp(plan.OpTimings{})...)
+			}
+			out = append(out, "}")
+		}
+		return append(out, fmt.Sprintf("if $__pm_rc != 0 { error make {msg: %s} }", nuQuote("pm: "+v.Name+" failed")))
+	case plan.OpTimings:
+		return []string{
+			"for s in ($env.__pm_steps? | default []) {",
+			`print $"# pm: ($s.step | fill -w 28) (($s.ms / 1000) | math round --precision 1 | fill -a r -w 7)s  (if $s.status == 0 { 'ok' } else { $'exit ($s.status)' })"`,
+			"}",
+			"hide-env -i __pm_steps",
+		}
+	default:
+		return nil
+	}
+}
+
+func nuPushd(dir string) string {
+	return fmt.Sprintf("$__pm_dirs = ($__pm_dirs | append $env.PWD); cd %s", nuQuote(dir))
+}
+
+func nuPopd() string {
+	return "cd ($__pm_dirs | last); $__pm_dirs = ($__pm_dirs | drop)"
+}
+
+// guarded reports whether something runs after the step's ops even when
+// they fail: its notification, failure hooks or the table of steps.
+func guarded(v plan.OpStep) bool {
+	return v.Notify != nil || len(v.OnFailure) > 0 || v.Table
+}
+
+// nuQuote uses a single-quoted string (no escapes) and falls back to a raw
+// string r#'...'# when s itself contains a single quote.
+func nuQuote(s string) string {
+	if !strings.Contains(s, "'") {
+		return "'" + s + "'"
+	}
+	hashes := "#"
+	for strings.Contains(s, "'"+hashes) {
+		hashes += "#"
+	}
+	return "r" + hashes + "'" + s + "'" + hashes
+}
+
+// nuNotify sends the step's notification when it ran long enough.
+func nuNotify(n *plan.Notify, step string) []string {
+	title := nuQuote(n.Title)
+	out := []string{
+		fmt.Sprintf("if $__pm_d >= %dms {", n.After.Milliseconds()),
+		fmt.Sprintf("let __pm_m = if $__pm_rc == 0 { %s } else { %s + ($__pm_rc | into string) + ')' }",
+			nuQuote(step+": ok"), nuQuote(step+": failed (exit ")),
+	}
+	bell := "print -e -n (char -u '07')"
+	switch n.Via {
+	case "bell":
+		out = append(out, bell)
+	case "osc9":
+		out = append(out, fmt.Sprintf("print -e -n ((char -u '1b') + ']9;' + %s + ': ' + $__pm_m + (char -u '07'))", title))
+	default:
+		out = append(out, fmt.Sprintf("if (which notify-send | is-not-empty) { ^notify-send %s $__pm_m } else if (which osascript | is-not-empty) { ^%s %s $__pm_m | ignore } else { %s }",
+			title, appleNotify, title, bell))
+	}
+	return append(out, "}")
+}
@@ -5,11 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"os/exec"
-	"path/filepath"
-	"strings"
+	"slices"
 
 	"pm/internal/plan"
+	"pm/internal/plugin"
 )
 
 type Renderer interface {
@@ -20,20 +19,20 @@ type Renderer interface {
 }
 
 var builtins = map[string]Renderer{
-	"bash": bashRenderer{},
-	"zsh":  bashRenderer{}, // alias
-	"pwsh": pwshRenderer{},
+	"bash":    bashRenderer{},
+	"zsh":     zshRenderer{},
+	"pwsh":    pwshRenderer{},
+	"fish":    fishRenderer{},
+	"nu":      nuRenderer{},
+	"nushell": nuRenderer{}, // alias
+	"sh":      shRenderer{},
+	"cmd":     cmdRenderer{},
 }
 
 type externalPlan struct {
-	Root string       `json:"root"`
-	Ops  []externalOp `json:"ops"`
-}
-type externalOp struct {
-	Kind string `json:"kind"`
-	Line string `json:"line,omitempty"`
-	Dir  string `json:"dir,omitempty"`
-	Msg  string `json:"msg,omitempty"`
+	ProtocolVersion int         `json:"protocol_version"`
+	Root            string      `json:"root"`
+	Ops             []plugin.Op `json:"ops"`
 }
 
 func Render(pl *plan.Plan, dialect, pluginsDir string) (string, error) {
@@ -41,12 +40,15 @@ func Render(pl *plan.Plan, dialect, pluginsDir string) (string, error) {
 		return renderWith(r, pl), nil
 	}
 	// external plugin: executable pm-render-<dialect> in pluginsDir
-	pluginsDir = expand(pluginsDir)
-	exe := filepath.Join(pluginsDir, "pm-render-"+dialect)
-	if _, err := os.Stat(exe); err != nil {
-		return "", fmt.Errorf("renderer not found: %s (plugins dir %s)", dialect, pluginsDir)
+	exe := plugin.Lookup(pluginsDir, plugin.KindRender, dialect)
+	if exe == "" {
+		return "", fmt.Errorf("renderer not found: %s (plugins dir %s)", dialect, plugin.Expand(pluginsDir))
+	}
+	info, err := plugin.Describe(plugin.KindRender, exe)
+	if err != nil {
+		return "", fmt.Errorf("renderer %s: %w", dialect, err)
 	}
-	return renderExternal(exe, pl)
+	return renderExternal(info, pl)
 }
 
 func renderWith(r Renderer, pl *plan.Plan) string {
@@ -80,46 +82,112 @@ func renderWith(r Renderer, pl *plan.Plan) string {
 	return buf.String()
 }
 
-func renderExternal(exe string, pl *plan.Plan) (string, error) {
-	root := "."
-	var ops []externalOp
-	for _, op := range pl.Ops {
+// Ops renders ops in a builtin dialect without the script's begin and
+// end, for pm run, which hands the shell one step at a time.
+func Ops(ops []plan.Op, dialect string) ([]string, error) {
+	r, ok := builtins[dialect]
+	if !ok {
+		return nil, fmt.Errorf("renderer not found: %s", dialect)
+	}
+	return renderOps(r, ops), nil
+}
+
+// renderOps renders ops one after another, e.g. the body of an OpBlock.
+func renderOps(r Renderer, ops []plan.Op) []string {
+	var out []string
+	for _, op := range ops {
+		out = append(out, r.RenderOp(op)...)
+	}
+	return out
+}
+
+// structuralOps change where and with what the commands run: a renderer
+// that cannot render them cannot render the plan.
+var structuralOps = []string{"pushd", "popd", "env"}
+
+// externalOps encodes ops for a renderer plugin, dropping the cosmetic
+// kinds it does not support. A block it cannot render is inlined, losing
+// only the failure isolation.
+func externalOps(info plugin.Info, in []plan.Op) ([]plugin.Op, error) {
+	var ops []plugin.Op
+	for _, op := range in {
+		if v, ok := op.(plan.OpBlock); ok && !info.Supports("block") {
+			if len(v.OnFailure) > 0 {
+				fmt.Fprintf(os.Stderr, "# pm: renderer %s does not support op \"block\", on_failure hooks skipped\n", info.Name)
+			}
+			body, err := externalOps(info, v.Ops)
+			if err != nil {
+				return nil, err
+			}
+			ops = append(ops, body...)
+			continue
+		}
+		// timing is optional: without it steps run as plain ops
+		if v, ok := op.(plan.OpStep); ok && !info.Supports("step") {
+			if len(v.OnFailure) > 0 {
+				fmt.Fprintf(os.Stderr, "# pm: renderer %s does not support op \"step\", on_failure hooks skipped\n", info.Name)
+			}
+			body, err := externalOps(info, v.Ops)
+			if err != nil {
+				return nil, err
+			}
+			ops = append(ops, body...)
+			continue
+		}
+		if _, ok := op.(plan.OpTimings); ok && !info.Supports("timings") {
+			continue
+		}
+		eo, ok := plugin.EncodeOp(op)
+		if !ok {
+			continue
+		}
+		if !info.Supports(eo.Kind) {
+			if slices.Contains(structuralOps, eo.Kind) {
+				return nil, fmt.Errorf("renderer %s does not support op %q, which the plan needs", info.Name, eo.Kind)
+			}
+			fmt.Fprintf(os.Stderr, "# pm: renderer %s does not support op %q, skipped\n", info.Name, eo.Kind)
+			continue
+		}
+		// encode the body against the plugin's ops as well
+		var err error
 		switch v := op.(type) {
-		case plan.OpPushd:
-			if root == "." {
-				root = v.Dir
+		case plan.OpBlock:
+			if eo.Ops, err = externalOps(info, v.Ops); err == nil {
+				eo.OnFailure, err = externalOps(info, v.OnFailure)
 			}
-			ops = append(ops, externalOp{Kind: "pushd", Dir: v.Dir})
-		case plan.OpPopd:
-			ops = append(ops, externalOp{Kind: "popd"})
-		case plan.OpEcho:
-			ops = append(ops, externalOp{Kind: "echo", Msg: v.Line})
-		case plan.OpRun:
-			ops = append(ops, externalOp{Kind: "run", Line: v.Line})
+		case plan.OpStep:
+			if eo.Ops, err = externalOps(info, v.Ops); err == nil {
+				eo.OnFailure, err = externalOps(info, v.OnFailure)
+			}
+		}
+		if err != nil {
+			return nil, err
 		}
+		ops = append(ops, eo)
 	}
-	payload := externalPlan{Root: root, Ops: ops}
+	return ops, nil
+}
+
+func renderExternal(info plugin.Info, pl *plan.Plan) (string, error) {
+	root := "."
+	for _, op := range pl.Ops {
+		if v, ok := op.(plan.OpPushd); ok {
+			root = v.Dir
+			break
+		}
+	}
+	ops, err := externalOps(info, pl.Ops)
+	if err != nil {
+		return "", err
+	}
+	payload := externalPlan{ProtocolVersion: plugin.ProtocolVersion, Root: root, Ops: ops}
 	b, err := json.Marshal(payload)
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal plan: %w", err)
 	}
-	cmd := exec.Command(exe, "--render")
-	cmd.Stdin = bytes.NewReader(b)
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
+	out, err := plugin.Call(info, b, "--render")
+	if err != nil {
 		return "", err
 	}
-	return out.String(), nil
-}
-
-func expand(s string) string {
-	s = os.ExpandEnv(s)
-	if strings.HasPrefix(s, "~") {
-		if home, _ := os.UserHomeDir(); home != "" {
-			return filepath.Join(home, s[1:])
-		}
-	}
-	return s
+	return string(out), nil
 }
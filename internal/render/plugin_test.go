@@ -0,0 +1,140 @@
+package render
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"pm/internal/plugin"
+)
+
+// writePlugin creates an executable pm-render-<name> shell script in dir.
+func writePlugin(t *testing.T, dir, name, body string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script plugins need a POSIX shell")
+	}
+	path := filepath.Join(dir, plugin.KindRender.Prefix()+name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const describeRunOnly = `if [ "$1" = "--describe" ]; then
+  echo '{"name":"runonly","version":"0.1.0","protocol_version":1,"ops":["run"]}'
+  exit 0
+fi
+cat
+`
+
+// describeNoEcho renders everything a plan needs to run, but no echo.
+const describeNoEcho = `if [ "$1" = "--describe" ]; then
+  echo '{"name":"noecho","version":"0.1.0","protocol_version":1,"ops":["pushd","popd","env","run"]}'
+  exit 0
+fi
+cat
+`
+
+func TestRender_ExternalSkipsUnsupportedOps(t *testing.T) {
+	dir := t.TempDir()
+	writePlugin(t, dir, "noecho", describeNoEcho)
+
+	p := buildPlan()
+	p.Echo("hidden")
+	out, err := Render(p, "noecho", dir)
+	if err != nil {
+		t.Fatalf("render error: %v", err)
+	}
+	if !strings.Contains(out, `"protocol_version":1`) {
+		t.Fatalf("plan has no protocol_version: %s", out)
+	}
+	if strings.Contains(out, "hidden") {
+		t.Fatalf("unsupported ops were sent to the plugin: %s", out)
+	}
+	if !strings.Contains(out, `"line":"echo hello"`) || !strings.Contains(out, `"pushd"`) {
+		t.Fatalf("run or pushd op missing: %s", out)
+	}
+}
+
+// TestRender_ExternalNeedsStructuralOps: without pushd the commands would
+// run in the wrong directory, so the render fails instead.
+func TestRender_ExternalNeedsStructuralOps(t *testing.T) {
+	dir := t.TempDir()
+	writePlugin(t, dir, "runonly", describeRunOnly)
+
+	_, err := Render(buildPlan(), "runonly", dir)
+	if err == nil || !strings.Contains(err.Error(), `"pushd"`) {
+		t.Fatalf("want an error about pushd, got %v", err)
+	}
+}
+
+// TestRender_ExternalInlinesSteps: a renderer without step gets the step's
+// ops in its place and no timings, silently.
+func TestRender_ExternalInlinesSteps(t *testing.T) {
+	dir := t.TempDir()
+	writePlugin(t, dir, "noecho", describeNoEcho)
+
+	p := stepsPlan()
+	out, err := Render(p, "noecho", dir)
+	if err != nil {
+		t.Fatalf("render error: %v", err)
+	}
+	if strings.Contains(out, `"step"`) || strings.Contains(out, `"timings"`) {
+		t.Fatalf("steps were sent to the plugin: %s", out)
+	}
+	if !strings.Contains(out, `"line":"make build"`) || !strings.Contains(out, `"line":"make test"`) {
+		t.Fatalf("step ops missing: %s", out)
+	}
+}
+
+func TestRender_ExternalLegacyPlugin(t *testing.T) {
+	dir := t.TempDir()
+	// ignores its arguments and echoes the plan back
+	writePlugin(t, dir, "old", "cat\n")
+
+	out, err := Render(buildPlan(), "old", dir)
+	if err != nil {
+		t.Fatalf("render error: %v", err)
+	}
+	for _, kind := range []string{`"pushd"`, `"run"`, `"popd"`} {
+		if !strings.Contains(out, kind) {
+			t.Fatalf("legacy plugin should get every op, missing %s: %s", kind, out)
+		}
+	}
+}
+
+func TestRender_ExternalStructuredError(t *testing.T) {
+	dir := t.TempDir()
+	writePlugin(t, dir, "bad", `if [ "$1" = "--describe" ]; then
+  echo '{"name":"bad","version":"1","protocol_version":1,"ops":["run","echo","pushd","popd"]}'
+  exit 0
+fi
+echo '{"error":{"code":"syntax","message":"cannot quote line","op":2}}'
+exit 3
+`)
+	_, err := Render(buildPlan(), "bad", dir)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "syntax: cannot quote line (op #2)") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestRender_ExternalTimeout(t *testing.T) {
+	dir := t.TempDir()
+	writePlugin(t, dir, "slow", "exec sleep 5\n")
+
+	old := plugin.Timeout
+	plugin.Timeout = 100 * time.Millisecond
+	defer func() { plugin.Timeout = old }()
+
+	_, err := Render(buildPlan(), "slow", dir)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected timeout, got %v", err)
+	}
+}
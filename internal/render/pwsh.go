@@ -7,6 +7,9 @@ import (
 	"pm/internal/plan"
 )
 
+// pwshRenderer emits PowerShell code. The script is a dot-sourced script
+// block, so that a failing step can return from it, leaving its status in
+// $LASTEXITCODE, the way errexit stops the POSIX shells.
 type pwshRenderer struct{}
 
 func (p pwshRenderer) Name() string { return "pwsh" }
@@ -14,12 +17,15 @@ func (p pwshRenderer) Name() string { return "pwsh" }
 func (p pwshRenderer) Begin(root string) []string {
 	return []string{
 		"# pm begin",
+		". {",
+		"$__pm_base = @(Get-Location -Stack).Count",
 		fmt.Sprintf("Push-Location %s", pwshQuote(root)),
 	}
 }
 func (p pwshRenderer) End() []string {
 	return []string{
 		"Pop-Location",
+		"}",
 		"# pm end",
 	}
 }
@@ -31,8 +37,69 @@ func (p pwshRenderer) RenderOp(op plan.Op) []string {
 		return []string{"Pop-Location"}
 	case plan.OpEcho:
 		return []string{fmt.Sprintf("Write-Host %s", pwshQuote(v.Line))}
+	case plan.OpEnv:
+		return []string{fmt.Sprintf("$env:%s = %s", v.Name, pwshQuote(v.Value))}
 	case plan.OpRun:
 		return []string{v.Line}
+	case plan.OpBlock:
+		// every op runs only while the block has not failed; the location
+		// stack is unwound after, then again after the failure hooks
+		out := []string{"$__pm_rc = 0", "$__pm_depth = @(Get-Location -Stack).Count"}
+		for _, op := range v.Ops {
+			out = append(out, "if ($__pm_rc -eq 0) {")
+			out = append(out, p.RenderOp(op)...)
+			out = append(out, "if (-not $?) { $__pm_rc = 1 }", "}")
+		}
+		out = append(out, "while (@(Get-Location -Stack).Count -gt $__pm_depth) { Pop-Location }")
+		if len(v.OnFailure) > 0 {
+			out = append(out, "if ($__pm_rc -ne 0) {")
+			out = append(out, renderOps(p, v.OnFailure)...)
+			out = append(out, "while (@(Get-Location -Stack).Count -gt $__pm_depth) { Pop-Location }", "}")
+		}
+		name := pwshQuote(v.Name)
+		return append(out,
+			fmt.Sprintf("if ($__pm_rc -eq 0) { $__pm_ok += @(%s) } else { $__pm_failed += @(%s) }", name, name),
+		)
+	case plan.OpSummary:
+		return []string{
+			`if ($__pm_ok) { Write-Host "# pm: ok: $($__pm_ok -join ' ')" }`,
+			`$__pm_rc = 0; if ($__pm_failed) { Write-Host "# pm: failed: $($__pm_failed -join ' ')"; $__pm_rc = 1 }`,
+			"Remove-Variable __pm_ok, __pm_failed -ErrorAction SilentlyContinue",
+			"if ($__pm_rc -ne 0) { $global:LASTEXITCODE = 1 }",
+		}
+	case plan.OpStep:
+		// the ops are guarded as in a block; the step's status is the
+		// first failing one
+		out := []string{"$__pm_sw = [Diagnostics.Stopwatch]::StartNew()", "$__pm_rc = 0"}
+		for _, op := range v.Ops {
+			out = append(out, "if ($__pm_rc -eq 0) {")
+			out = append(out, p.RenderOp(op)...)
+			out = append(out, "if (-not $?) { $__pm_rc = if ($LASTEXITCODE) { $LASTEXITCODE } else { 1 } }", "}")
+		}
+		out = append(out,
+			fmt.Sprintf("$__pm_steps += ,[pscustomobject]@{ Step = %s; Ms = $__pm_sw.ElapsedMilliseconds; Status = $__pm_rc }", pwshQuote(v.Name)),
+		)
+		if v.Notify != nil {
+			out = append(out, pwshNotify(v.Notify, v.Name)...)
+		}
+		// a failed step stops the script after its hooks and the table,
+		// back in the location it started in
+		out = append(out, "if ($__pm_rc -ne 0) {")
+		out = append(out, renderOps(p, v.OnFailure)...)
+		if v.Table {
+			out = append(out, p.RenderOp(plan.OpTimings{})...)
+		}
+		return append(out,
+			"while (@(Get-Location -Stack).Count -gt $__pm_base) { Pop-Location }",
+			"$global:LASTEXITCODE = $__pm_rc",
+			"return",
+			"}",
+		)
+	case plan.OpTimings:
+		return []string{
+			`$__pm_steps | ForEach-Object { Write-Host ('# pm: {0,-28} {1,7:N1}s  {2}' -f $_.Step, ($_.Ms / 1000), $(if ($_.Status -eq 0) { 'ok' } else { "exit $($_.Status)" })) }`,
+			"Remove-Variable __pm_steps -ErrorAction SilentlyContinue",
+		}
 	default:
 		return nil
 	}
@@ -42,3 +109,29 @@ func pwshQuote(s string) string {
 	// single-quote with escaping
 	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
 }
+
+// pwshNotify sends the step's notification when it ran long enough:
+// BurntToast on Windows, notify-send or osascript elsewhere.
+func pwshNotify(n *plan.Notify, step string) []string {
+	title := pwshQuote(n.Title)
+	out := []string{
+		fmt.Sprintf("if ($__pm_sw.ElapsedMilliseconds -ge %d) {", n.After.Milliseconds()),
+		fmt.Sprintf("$__pm_m = if ($__pm_rc -eq 0) { %s } else { %s + $__pm_rc + ')' }",
+			pwshQuote(step+": ok"), pwshQuote(step+": failed (exit ")),
+	}
+	bell := `[Console]::Error.Write("` + "`" + `a")`
+	switch n.Via {
+	case "bell":
+		out = append(out, bell)
+	case "osc9":
+		out = append(out, fmt.Sprintf(`[Console]::Error.Write([char]27 + ']9;' + %s + ': ' + $__pm_m + [char]7)`, title))
+	default:
+		out = append(out,
+			fmt.Sprintf("if (Get-Command New-BurntToastNotification -ErrorAction SilentlyContinue) { New-BurntToastNotification -Text %s, $__pm_m }", title),
+			fmt.Sprintf("elseif (Get-Command notify-send -ErrorAction SilentlyContinue) { notify-send %s $__pm_m }", title),
+			fmt.Sprintf("elseif (Get-Command osascript -ErrorAction SilentlyContinue) { %s %s $__pm_m | Out-Null }", appleNotify, title),
+			"else { "+bell+" }",
+		)
+	}
+	return append(out, "}")
+}
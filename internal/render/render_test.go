@@ -1,9 +1,13 @@
 package render
 
 import (
+	"errors"
+	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"pm/internal/cache"
 	"pm/internal/plan"
 )
 
@@ -38,3 +42,222 @@ func TestRender_Pwsh(t *testing.T) {
 		}
 	}
 }
+
+// TestRender_BlockRuns executes a fan-out plan: a failing block stops
+// itself but not the following ones, and the summary fails the script.
+func TestRender_BlockRuns(t *testing.T) {
+	dir := t.TempDir()
+	p := plan.New()
+	p.Block("bad", []plan.Op{plan.OpPushd{Dir: dir}, plan.OpRun{Line: "false"}, plan.OpRun{Line: "echo unreachable"}, plan.OpPopd{}})
+	p.Block("good", []plan.Op{plan.OpPushd{Dir: dir}, plan.OpRun{Line: "echo reached"}, plan.OpPopd{}})
+	p.Block("missing", []plan.Op{plan.OpPushd{Dir: filepath.Join(dir, "nope")}, plan.OpPopd{}})
+	p.Summary()
+
+	for dialect, shell := range map[string]string{"bash": "bash", "sh": "sh"} {
+		if _, err := exec.LookPath(shell); err != nil {
+			continue
+		}
+		script, err := Render(p, dialect, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// like the wrapper: errexit on, eval, then more commands
+		out, err := exec.Command(shell, "-c", `set -e; eval "$1"; echo after`, "pm", script).Output()
+		got := string(out)
+		if err == nil || strings.Contains(got, "after") {
+			t.Errorf("%s: script should fail after the summary:\n%s", dialect, got)
+		}
+		for _, want := range []string{"reached", "# pm: ok: good", "# pm: failed: bad missing"} {
+			if !strings.Contains(got, want) {
+				t.Errorf("%s: want %q in output:\n%s", dialect, want, got)
+			}
+		}
+		if strings.Contains(got, "unreachable") {
+			t.Errorf("%s: failed block kept running:\n%s", dialect, got)
+		}
+	}
+}
+
+// TestRender_StepTimings executes a plan of steps: each one's duration and
+// status ends up in the table.
+func TestRender_StepTimings(t *testing.T) {
+	p := plan.New()
+	p.Step(":build", []plan.Op{plan.OpRun{Line: "echo building"}}, nil)
+	p.Step(":test it's", []plan.Op{plan.OpRun{Line: "sh -c 'exit 3' || true; sh -c 'exit 3'"}}, nil)
+	p.Timings()
+
+	for dialect, shell := range map[string]string{"bash": "bash", "sh": "sh"} {
+		if _, err := exec.LookPath(shell); err != nil {
+			continue
+		}
+		script, err := Render(p, dialect, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := exec.Command(shell, "-c", `set -u; eval "$1"; echo "after${__pm_steps:-}"`, "pm", script).Output()
+		if err != nil {
+			t.Fatalf("%s: %v\n%s", dialect, err, out)
+		}
+		got := string(out)
+		for _, want := range []string{"building", "# pm: :build ", "s  ok\n", "# pm: :test it's ", "s  exit 3\n", "after\n"} {
+			if !strings.Contains(got, want) {
+				t.Errorf("%s: want %q in output:\n%s", dialect, want, got)
+			}
+		}
+	}
+}
+
+// TestRender_StepTableOnce executes a plan whose middle step fails: with
+// errexit the table is printed before the script stops, without it once
+// at the end, with the failed step and the ones after it.
+func TestRender_StepTableOnce(t *testing.T) {
+	p := plan.New()
+	p.Step(":build", []plan.Op{plan.OpRun{Line: "echo building"}}, nil)
+	p.Step(":test", []plan.Op{plan.OpRun{Line: "sh -c 'exit 3'"}}, nil)
+	p.Step(":lint", []plan.Op{plan.OpRun{Line: "echo linting"}}, nil)
+	p.Timings()
+
+	for dialect, shell := range map[string]string{"bash": "bash", "sh": "sh"} {
+		if _, err := exec.LookPath(shell); err != nil {
+			continue
+		}
+		script, err := Render(p, dialect, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tc := range []struct {
+			opts string
+			want []string
+		}{
+			{"set -e", []string{"building\n", "# pm: :build ", "s  exit 3\n"}},
+			{"set +e", []string{"building\n", "linting\n", "# pm: :build ", "s  exit 3\n", "# pm: :lint "}},
+		} {
+			out, _ := exec.Command(shell, "-c", tc.opts+`; eval "$1"`, "pm", script).Output()
+			got := string(out)
+			if n := strings.Count(got, "# pm: :build "); n != 1 {
+				t.Errorf("%s, %s: table printed %d times:\n%s", dialect, tc.opts, n, got)
+			}
+			rest := got
+			for _, want := range tc.want {
+				i := strings.Index(rest, want)
+				if i < 0 {
+					t.Errorf("%s, %s: want %q in order in output:\n%s", dialect, tc.opts, want, got)
+					break
+				}
+				rest = rest[i+len(want):]
+			}
+		}
+	}
+}
+
+// TestRender_StepDone executes a plan whose second step fails: only the
+// first one's count of finished steps is exported, with errexit or not,
+// and errexit ends the script with the failing status.
+func TestRender_StepDone(t *testing.T) {
+	p := plan.New()
+	p.Env(cache.DoneVar, "0")
+	p.Step(":codegen", []plan.Op{plan.OpRun{Line: "true"}, plan.OpEnv{Name: cache.DoneVar, Value: "1"}}, nil)
+	p.Step(":build", []plan.Op{plan.OpRun{Line: "sh -c 'exit 3'"}, plan.OpEnv{Name: cache.DoneVar, Value: "2"}}, nil)
+
+	for dialect, shell := range map[string]string{"bash": "bash", "sh": "sh"} {
+		if _, err := exec.LookPath(shell); err != nil {
+			continue
+		}
+		script, err := Render(p, dialect, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, opts := range []string{"set -e", "set +e"} {
+			out, err := exec.Command(shell, "-c", opts+`; trap 'echo "done=$PM_STEPS_DONE"' EXIT; eval "$1"`, "pm", script).Output()
+			var ee *exec.ExitError
+			if opts == "set -e" && (!errors.As(err, &ee) || ee.ExitCode() != 3) {
+				t.Errorf("%s, %s: want exit status 3, got %v", dialect, opts, err)
+			}
+			if got := string(out); got != "done=1\n" {
+				t.Errorf("%s, %s: output = %q, want the first step counted only", dialect, opts, got)
+			}
+		}
+	}
+}
+
+// TestRender_StepNotify executes notifying steps under errexit: the
+// notification says whether the step failed, which still ends the script
+// with its status.
+func TestRender_StepNotify(t *testing.T) {
+	n := &plan.Notify{Title: "pm: api", Via: "osc9"}
+	p := plan.New()
+	p.Step(":env", []plan.Op{plan.OpRun{Line: "export GREETING=hi"}}, n)
+	p.Step(":test", []plan.Op{plan.OpRun{Line: `echo "$GREETING"`}, plan.OpRun{Line: "sh -c 'exit 3'"}, plan.OpRun{Line: "echo unreachable"}}, n)
+	p.Step(":never", []plan.Op{plan.OpRun{Line: "echo unreachable"}}, n)
+
+	for dialect, shell := range map[string]string{"bash": "bash", "sh": "sh"} {
+		if _, err := exec.LookPath(shell); err != nil {
+			continue
+		}
+		script, err := Render(p, dialect, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		cmd := exec.Command(shell, "-c", `set -e; eval "$1"; echo after`, "pm", script)
+		var stderr strings.Builder
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		var ee *exec.ExitError
+		if !errors.As(err, &ee) || ee.ExitCode() != 3 {
+			t.Errorf("%s: want exit status 3, got %v", dialect, err)
+		}
+		if got := string(out); got != "hi\n" {
+			t.Errorf("%s: output = %q, want the step's output up to the failure", dialect, got)
+		}
+		want := "\x1b]9;pm: api: :env: ok\a\x1b]9;pm: api: :test: failed (exit 3)\a"
+		if stderr.String() != want {
+			t.Errorf("%s: notifications = %q, want %q", dialect, stderr.String(), want)
+		}
+	}
+}
+
+// TestRender_StepOnFailure executes a failing step with failure hooks
+// under errexit: the hooks see the failure, and the script still ends
+// with the step's status.
+func TestRender_StepOnFailure(t *testing.T) {
+	p := plan.New()
+	p.Step(":build", []plan.Op{plan.OpRun{Line: "echo built"}}, nil)
+	p.Ops = append(p.Ops,
+		plan.OpStep{Name: ":test", Ops: []plan.Op{plan.OpRun{Line: "sh -c 'exit 3'"}, plan.OpRun{Line: "echo unreachable"}},
+			OnFailure: []plan.Op{plan.OpRun{Line: `echo "cleanup after $__pm_rc"`}}},
+		plan.OpStep{Name: ":never", Ops: []plan.Op{plan.OpRun{Line: "echo unreachable"}}},
+	)
+
+	for dialect, shell := range map[string]string{"bash": "bash", "sh": "sh"} {
+		if _, err := exec.LookPath(shell); err != nil {
+			continue
+		}
+		script, err := Render(p, dialect, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := exec.Command(shell, "-c", `set -e; eval "$1"; echo after`, "pm", script).Output()
+		var ee *exec.ExitError
+		if !errors.As(err, &ee) || ee.ExitCode() != 3 {
+			t.Errorf("%s: want exit status 3, got %v", dialect, err)
+		}
+		if got := string(out); got != "built\ncleanup after 3\n" {
+			t.Errorf("%s: output = %q, want the hook after the failure", dialect, got)
+		}
+	}
+}
+
+// TestRender_NuDoneFile checks that nu also writes the count of finished
+// steps to the file the wrapper reads it back from.
+func TestRender_NuDoneFile(t *testing.T) {
+	p := plan.New()
+	p.Env(cache.DoneVar, "2")
+	s, err := Render(p, "nu", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "$env.PM_STEPS_DONE = '2'\nif 'PM_STEPS_FILE' in $env { '2' | save -f $env.PM_STEPS_FILE }\n"
+	if !strings.Contains(s, want) {
+		t.Fatalf("want %q in script:\n%s", want, s)
+	}
+}
@@ -0,0 +1,69 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"pm/internal/plan"
+)
+
+// shRenderer emits POSIX sh. There is no pushd in POSIX, so the previous
+// working directories are saved newline-separated in __pm_dirs and restored
+// with cd.
+type shRenderer struct{}
+
+func (s shRenderer) Name() string { return "sh" }
+
+func (s shRenderer) Begin(root string) []string {
+	return append([]string{"# pm begin"}, shPushd(root)...)
+}
+func (s shRenderer) End() []string {
+	return append(shPopd(), "# pm end")
+}
+func (s shRenderer) RenderOp(op plan.Op) []string {
+	switch v := op.(type) {
+	case plan.OpPushd:
+		return shPushd(v.Dir)
+	case plan.OpPopd:
+		return shPopd()
+	case plan.OpEcho:
+		return []string{fmt.Sprintf("printf '%%s\\n' %s", posixQuote(v.Line))}
+	case plan.OpEnv:
+		return []string{fmt.Sprintf("%s=%s; export %s", v.Name, posixQuote(v.Value), v.Name)}
+	case plan.OpRun:
+		return []string{v.Line}
+	case plan.OpBlock:
+		return posixBlock(s, v, posixQuote(v.Name))
+	case plan.OpSummary:
+		return posixSummary(`printf '%s\n'`)
+	case plan.OpStep:
+		return posixStep(s, v, posixErrexit)
+	case plan.OpTimings:
+		return posixTimings()
+	default:
+		return nil
+	}
+}
+
+func shPushd(dir string) []string {
+	return []string{
+		`__pm_dirs="$PWD
+${__pm_dirs:-}"`,
+		fmt.Sprintf("cd %s", posixQuote(dir)),
+	}
+}
+
+func shPopd() []string {
+	return []string{
+		`cd "${__pm_dirs%%
+*}"`,
+		`__pm_dirs="${__pm_dirs#*
+}"`,
+	}
+}
+
+// posixQuote always single-quotes s, closing and reopening the quotes
+// around embedded single quotes.
+func posixQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,79 @@
+package render
+
+import (
+	"fmt"
+
+	"pm/internal/plan"
+)
+
+// zshRenderer emits zsh code. The script runs inside an anonymous function so
+// that `emulate -L zsh` and the setopts stay local, and the options are
+// chosen to keep user command lines behaving like they do under bash:
+// unquoted variables are word-split and unmatched globs are passed through.
+// A failing command returns from the function; the always block restores
+// the caller's working directory.
+type zshRenderer struct{}
+
+func (z zshRenderer) Name() string { return "zsh" }
+
+func (z zshRenderer) Begin(root string) []string {
+	return []string{
+		"# pm begin",
+		"() {",
+		"emulate -L zsh",
+		"setopt err_return local_options pushd_silent sh_word_split no_nomatch",
+		"local __pm_start=$PWD",
+		"{",
+		fmt.Sprintf("pushd -q %s", sh(root)),
+	}
+}
+func (z zshRenderer) End() []string {
+	return []string{
+		"popd -q",
+		"} always {",
+		`[[ $PWD == "$__pm_start" ]] || cd -q -- "$__pm_start"`,
+		"}",
+		"}",
+		"# pm end",
+	}
+}
+func (z zshRenderer) RenderOp(op plan.Op) []string {
+	switch v := op.(type) {
+	case plan.OpPushd:
+		return []string{fmt.Sprintf("pushd -q %s", sh(v.Dir))}
+	case plan.OpPopd:
+		return []string{"popd -q"}
+	case plan.OpEcho:
+		return []string{fmt.Sprintf("print -r -- %s", sh(v.Line))}
+	case plan.OpEnv:
+		return []string{fmt.Sprintf("export %s=%s", v.Name, sh(v.Value))}
+	case plan.OpRun:
+		return []string{v.Line}
+	case plan.OpBlock:
+		// err_return would leave the function on the subshell's failure
+		out := []string{"setopt no_err_return", "(", "setopt err_exit"}
+		out = append(out, renderOps(z, v.Ops)...)
+		out = append(out, ")", "__pm_rc=$?")
+		if len(v.OnFailure) > 0 {
+			out = append(out, "if (( __pm_rc != 0 )); then", "(")
+			out = append(out, renderOps(z, v.OnFailure)...)
+			out = append(out, ")", "fi")
+		}
+		name := posixQuote(v.Name)
+		return append(out,
+			"setopt err_return",
+			fmt.Sprintf(`if (( __pm_rc == 0 )); then __pm_ok+=" "%s; else __pm_failed+=" "%s; fi`, name, name),
+		)
+	case plan.OpSummary:
+		return posixSummary("print -r --")
+	case plan.OpStep:
+		// EPOCHREALTIME comes with the zsh/datetime module; err_return is
+		// always on in the script, so a failing step always stops it
+		return append([]string{"zmodload -F zsh/datetime p:EPOCHREALTIME 2>/dev/null || true"},
+			posixStep(z, v, [3]string{"setopt no_err_return", "setopt err_return", "%s"})...)
+	case plan.OpTimings:
+		return posixTimings()
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,50 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"pm/internal/plan"
+)
+
+// notify sends a step's notification when it ran long enough, the way
+// the rendered scripts do.
+func (r *Runner) notify(n *plan.Notify, s StepResult) {
+	if n == nil || s.Duration < n.After {
+		return
+	}
+	msg := s.Step + ": ok"
+	if s.Status != 0 {
+		msg = fmt.Sprintf("%s: failed (exit %d)", s.Step, s.Status)
+	}
+	switch n.Via {
+	case "bell":
+		fmt.Fprint(r.Stderr, "\a")
+	case "osc9":
+		fmt.Fprintf(r.Stderr, "\x1b]9;%s: %s\a", n.Title, msg)
+	default:
+		if desktopNotify(n.Title, msg) != nil {
+			fmt.Fprint(r.Stderr, "\a")
+		}
+	}
+}
+
+// desktopNotify shows a notification with BurntToast on Windows,
+// notify-send or osascript elsewhere.
+func desktopNotify(title, msg string) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("powershell", "-NoProfile", "-Command", "New-BurntToastNotification -Text $env:PM_TITLE, $env:PM_MESSAGE")
+		cmd.Env = append(os.Environ(), "PM_TITLE="+title, "PM_MESSAGE="+msg)
+	} else if _, err := exec.LookPath("notify-send"); err == nil {
+		cmd = exec.Command("notify-send", title, msg)
+	} else if _, err := exec.LookPath("osascript"); err == nil {
+		cmd = exec.Command("osascript", "-e", "on run a", "-e", "display notification (item 2 of a) with title (item 1 of a)", "-e", "end run", title, msg)
+	} else {
+		return errors.New("no notifier")
+	}
+	return cmd.Run()
+}
@@ -0,0 +1,272 @@
+// Package runner executes a plan in pm-bin itself instead of printing a
+// script for the shell to eval: each step is rendered as one script of the
+// system shell and started in the tracked directory and environment.
+// `pm run` uses it where no wrapper is around (CI, editors, other tools).
+package runner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"pm/internal/plan"
+)
+
+// Runner executes plans; the zero value runs with the process's stdio.
+type Runner struct {
+	Stdin          io.Reader
+	Stdout, Stderr io.Writer
+	// Project names the steps in the log outside of blocks, which are
+	// named after their project.
+	Project string
+	// Log is a JSONL file every finished step is appended to, if set.
+	Log string
+	// Dialect is the shell the steps run in, one of Dialects; sh (pwsh
+	// on Windows) if empty.
+	Dialect string
+
+	dirs  []string
+	env   map[string]string
+	steps []StepResult
+	// names of the blocks that passed and failed, for OpSummary
+	ok, failed []string
+}
+
+// StepResult is one finished step, as printed in the table and logged.
+type StepResult struct {
+	Time     time.Time     `json:"time"`
+	Project  string        `json:"project,omitempty"`
+	Step     string        `json:"step"`
+	Duration time.Duration `json:"-"`
+	Millis   int64         `json:"duration_ms"`
+	Status   int           `json:"status"`
+}
+
+// exitError stops the plan, or the block it happened in, with a status.
+type exitError struct{ status int }
+
+func (e *exitError) Error() string { return fmt.Sprintf("exit status %d", e.status) }
+
+// Run executes pl and returns its exit status: the status of the first
+// failing line outside a block, 1 when a block failed, 0 otherwise. The
+// table of steps is printed even when a step failed.
+func (r *Runner) Run(pl *plan.Plan) int {
+	if r.Stdin == nil {
+		r.Stdin = os.Stdin
+	}
+	if r.Stdout == nil {
+		r.Stdout = os.Stdout
+	}
+	if r.Stderr == nil {
+		r.Stderr = os.Stderr
+	}
+	cwd, _ := os.Getwd()
+	r.dirs, r.env, r.steps, r.ok, r.failed = []string{cwd}, map[string]string{}, nil, nil, nil
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok && k != "" {
+			r.env[k] = v
+		}
+	}
+
+	err := r.ops(pl.Ops, r.Project)
+	var ee *exitError
+	if !errors.As(err, &ee) {
+		return 0
+	}
+	for _, op := range pl.Ops {
+		if _, ok := op.(plan.OpTimings); ok {
+			r.timings()
+			break
+		}
+	}
+	return ee.status
+}
+
+// Steps returns the steps of the last Run.
+func (r *Runner) Steps() []StepResult { return r.steps }
+
+// Getenv returns name in the environment the last Run's plan left.
+func (r *Runner) Getenv(name string) string { return r.env[name] }
+
+func (r *Runner) ops(ops []plan.Op, project string) error {
+	for _, op := range ops {
+		if err := r.op(op, project); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Runner) op(op plan.Op, project string) error {
+	switch v := op.(type) {
+	case plan.OpPushd:
+		dir := v.Dir
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(r.dir(), dir)
+		}
+		if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+			fmt.Fprintf(r.Stderr, "# pm: no such directory: %s\n", v.Dir)
+			return &exitError{1}
+		}
+		r.dirs = append(r.dirs, dir)
+	case plan.OpPopd:
+		if len(r.dirs) > 1 {
+			r.dirs = r.dirs[:len(r.dirs)-1]
+		}
+	case plan.OpEcho:
+		fmt.Fprintln(r.Stdout, v.Line)
+	case plan.OpEnv:
+		r.env[v.Name] = v.Value
+	case plan.OpRun:
+		return r.run([]plan.Op{v})
+	case plan.OpBlock:
+		// a failure ends the block only; the directory is restored, after
+		// the failure hooks
+		dir := r.dir()
+		err := r.run(v.Ops)
+		if err != nil {
+			_ = r.run(v.OnFailure)
+		}
+		r.dirs[len(r.dirs)-1] = dir
+		if err != nil {
+			r.failed = append(r.failed, v.Name)
+		} else {
+			r.ok = append(r.ok, v.Name)
+		}
+	case plan.OpSummary:
+		if len(r.ok) > 0 {
+			fmt.Fprintln(r.Stdout, "# pm: ok: "+strings.Join(r.ok, " "))
+		}
+		failed := r.failed
+		r.ok, r.failed = nil, nil
+		if len(failed) > 0 {
+			fmt.Fprintln(r.Stdout, "# pm: failed: "+strings.Join(failed, " "))
+			return &exitError{1}
+		}
+	case plan.OpStep:
+		start := time.Now()
+		err := r.run(v.Ops)
+		status := 0
+		var ee *exitError
+		if errors.As(err, &ee) {
+			status = ee.status
+		}
+		s := StepResult{Time: start, Project: project, Step: v.Name, Duration: time.Since(start), Status: status}
+		r.record(s)
+		r.notify(v.Notify, s)
+		if err != nil {
+			// the step's status stands whatever the hooks do
+			_ = r.run(v.OnFailure)
+		}
+		return err
+	case plan.OpTimings:
+		r.timings()
+		r.steps = nil
+	}
+	return nil
+}
+
+func (r *Runner) dir() string { return r.dirs[len(r.dirs)-1] }
+
+// run runs ops as one script of the system shell in the tracked
+// directory and environment, so that a cd or an export carries to the
+// lines after it as in the wrapper's script. What the script exported and
+// the directory it ended in are kept for the ops that follow.
+func (r *Runner) run(ops []plan.Op) error {
+	if len(ops) == 0 {
+		return nil
+	}
+	sh, err := shellFor(r.Dialect)
+	if err != nil {
+		fmt.Fprintf(r.Stderr, "# pm: %v\n", err)
+		return &exitError{2}
+	}
+	script, err := sh.script(ops)
+	if err != nil {
+		fmt.Fprintf(r.Stderr, "# pm: %v\n", err)
+		return &exitError{127}
+	}
+	f, err := os.CreateTemp("", "pm-env-*")
+	if err != nil {
+		fmt.Fprintf(r.Stderr, "# pm: %v\n", err)
+		return &exitError{127}
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	cmd := sh.command(script)
+	cmd.Dir = r.dir()
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = r.Stdin, r.Stdout, r.Stderr
+	cmd.Env = []string{envFile + "=" + f.Name()}
+	for k, v := range r.env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	// the shell keeps PWD up to date on cd
+	cmd.Env = append(cmd.Env, "PWD="+cmd.Dir)
+	err = cmd.Run()
+	if env, rerr := readEnv(f.Name()); rerr != nil {
+		fmt.Fprintf(r.Stderr, "# pm: %v\n", rerr)
+	} else if env != nil {
+		r.env = env
+		if fi, err := os.Stat(env["PWD"]); err == nil && fi.IsDir() && filepath.IsAbs(env["PWD"]) {
+			r.dirs[len(r.dirs)-1] = env["PWD"]
+		}
+	}
+	var ee *exec.ExitError
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &ee):
+		return &exitError{max(ee.ExitCode(), 1)}
+	default:
+		fmt.Fprintf(r.Stderr, "# pm: %v\n", err)
+		return &exitError{127}
+	}
+}
+
+func (r *Runner) record(s StepResult) {
+	s.Millis = s.Duration.Milliseconds()
+	r.steps = append(r.steps, s)
+	if r.Log == "" {
+		return
+	}
+	if err := appendJSON(r.Log, s); err != nil {
+		fmt.Fprintf(r.Stderr, "# pm: step log: %v\n", err)
+	}
+}
+
+func appendJSON(path string, v any) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// timings prints the steps like the rendered scripts do.
+func (r *Runner) timings() {
+	for _, s := range r.steps {
+		status := "ok"
+		if s.Status != 0 {
+			status = fmt.Sprintf("exit %d", s.Status)
+		}
+		fmt.Fprintf(r.Stdout, "# pm: %-28s %7.1fs  %s\n", s.Step, s.Duration.Seconds(), status)
+	}
+}
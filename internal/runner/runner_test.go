@@ -0,0 +1,162 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"pm/internal/plan"
+)
+
+func newRunner(t *testing.T) (*Runner, *bytes.Buffer) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("run lines are POSIX shell")
+	}
+	var out bytes.Buffer
+	return &Runner{Stdin: strings.NewReader(""), Stdout: &out, Stderr: &out}, &out
+}
+
+func TestRun_StepsAndLog(t *testing.T) {
+	r, out := newRunner(t)
+	dir := t.TempDir()
+	r.Project, r.Log = "api", filepath.Join(dir, "logs", "steps.jsonl")
+
+	pl := plan.New()
+	pl.Pushd(dir)
+	pl.Env("GREETING", "it's me")
+	pl.Step(":build", []plan.Op{plan.OpRun{Line: `echo "$GREETING in $(pwd)"`}}, nil)
+	pl.Step(":test", []plan.Op{plan.OpRun{Line: "exit 3"}}, &plan.Notify{Title: "pm: api", Via: "osc9"})
+	pl.Step(":never", []plan.Op{plan.OpRun{Line: "echo unreachable"}}, nil)
+	pl.Timings()
+	pl.Popd()
+
+	if got := r.Run(pl); got != 3 {
+		t.Fatalf("status = %d, want 3\n%s", got, out)
+	}
+	for _, want := range []string{"it's me in " + dir, "# pm: :build ", "s  ok\n", "# pm: :test ", "s  exit 3\n", "\x1b]9;pm: api: :test: failed (exit 3)\a"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("want %q in output:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out.String(), "unreachable") || strings.Contains(out.String(), ":never") {
+		t.Errorf("kept running after a failed step:\n%s", out)
+	}
+
+	f, err := os.Open(r.Log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var logged []StepResult
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var s StepResult
+		if err := json.Unmarshal(sc.Bytes(), &s); err != nil {
+			t.Fatalf("bad log line %q: %v", sc.Text(), err)
+		}
+		logged = append(logged, s)
+	}
+	if len(logged) != 2 || logged[0].Step != ":build" || logged[1].Status != 3 || logged[1].Project != "api" {
+		t.Fatalf("log = %+v", logged)
+	}
+}
+
+// TestRun_Blocks: a failing block stops itself and runs its failure hooks
+// but does not stop the next one, and the summary fails the plan.
+func TestRun_Blocks(t *testing.T) {
+	r, out := newRunner(t)
+	dir := t.TempDir()
+
+	pl := plan.New()
+	pl.Ops = append(pl.Ops, plan.OpBlock{Name: "bad",
+		Ops:       []plan.Op{plan.OpPushd{Dir: dir}, plan.OpRun{Line: "false"}, plan.OpRun{Line: "echo unreachable"}, plan.OpPopd{}},
+		OnFailure: []plan.Op{plan.OpRun{Line: "echo cleanup bad"}}})
+	pl.Block("missing", []plan.Op{plan.OpPushd{Dir: filepath.Join(dir, "nope")}, plan.OpPopd{}})
+	pl.Block("good", []plan.Op{plan.OpPushd{Dir: dir}, plan.OpRun{Line: "echo reached"}, plan.OpPopd{}})
+	pl.Summary()
+
+	if got := r.Run(pl); got != 1 {
+		t.Fatalf("status = %d, want 1\n%s", got, out)
+	}
+	for _, want := range []string{"cleanup bad", "reached", "# pm: ok: good", "# pm: failed: bad missing"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("want %q in output:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out.String(), "unreachable") {
+		t.Errorf("failed block kept running:\n%s", out)
+	}
+}
+
+// TestRun_OnFailure: the failure hooks of a step run only when it fails,
+// and the step's status stands.
+func TestRun_OnFailure(t *testing.T) {
+	r, out := newRunner(t)
+	hook := []plan.Op{plan.OpRun{Line: "echo cleanup; exit 7"}}
+
+	pl := plan.New()
+	pl.Ops = append(pl.Ops,
+		plan.OpStep{Name: ":build", Ops: []plan.Op{plan.OpRun{Line: "echo built"}}, OnFailure: hook},
+		plan.OpStep{Name: ":test", Ops: []plan.Op{plan.OpRun{Line: "exit 3"}}, OnFailure: hook},
+	)
+	if got := r.Run(pl); got != 3 {
+		t.Fatalf("status = %d, want 3\n%s", got, out)
+	}
+	if got := out.String(); got != "built\ncleanup\n" {
+		t.Fatalf("output = %q, want the hook once", got)
+	}
+}
+
+// TestRun_OneShellPerStep: a cd or an export carries to the step's next
+// lines and to the steps after it, as in the wrapper's script.
+func TestRun_OneShellPerStep(t *testing.T) {
+	r, out := newRunner(t)
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	pl := plan.New()
+	pl.Pushd(dir)
+	pl.Run("export JAVA_HOME=/opt/jdk-21")
+	pl.Step(":build", []plan.Op{plan.OpRun{Line: "cd sub && export STAGE=built"}, plan.OpRun{Line: `echo "$STAGE in $(basename "$PWD")"`}}, nil)
+	pl.Step(":test", []plan.Op{plan.OpRun{Line: `echo "$STAGE with $JAVA_HOME in $(basename "$PWD")"`}}, nil)
+	pl.Popd()
+
+	if got := r.Run(pl); got != 0 {
+		t.Fatalf("status = %d\n%s", got, out)
+	}
+	want := "built in sub\nbuilt with /opt/jdk-21 in sub\n"
+	if out.String() != want {
+		t.Fatalf("output = %q, want %q", out, want)
+	}
+	if got := r.Getenv("STAGE"); got != "built" {
+		t.Fatalf("Getenv(STAGE) = %q", got)
+	}
+}
+
+// TestRun_Dialect: the steps run in the project's shell.
+func TestRun_Dialect(t *testing.T) {
+	r, out := newRunner(t)
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash is required")
+	}
+	r.Dialect = "bash"
+	pl := plan.New()
+	pl.Step(":build", []plan.Op{plan.OpRun{Line: `[[ -n $BASH_VERSION ]] && echo bash`}}, nil)
+	if got := r.Run(pl); got != 0 || out.String() != "bash\n" {
+		t.Fatalf("status = %d, output = %q", got, out)
+	}
+
+	r.Dialect = "fish"
+	if got := r.Run(pl); got != 2 || !strings.Contains(out.String(), "cannot run fish scripts") {
+		t.Fatalf("status = %d, output = %q", got, out)
+	}
+}
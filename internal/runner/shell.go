@@ -0,0 +1,110 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+
+	"pm/internal/plan"
+	"pm/internal/render"
+)
+
+// envFile names the variable holding the file a script writes its
+// environment to before it exits.
+const envFile = "__pm_env"
+
+// Dialects are the shells a Runner can run its scripts in.
+var Dialects = []string{"sh", "bash", "zsh", "pwsh"}
+
+// shell is the shell the runner hands its scripts to.
+type shell struct {
+	// the dialect the ops are rendered in
+	dialect string
+	// wrapped around each op, so that it runs only while the script has
+	// not failed, with the status in __pm_rc
+	guard [2]string
+	// the script's first and last lines: they start __pm_rc, write the
+	// environment with PWD to $__pm_env and exit with __pm_rc
+	begin, end []string
+	command    func(script string) *exec.Cmd
+}
+
+// posixShell runs scripts in the sh, bash or zsh called name.
+func posixShell(name string) shell {
+	return shell{
+		dialect: name,
+		guard:   [2]string{`if [ "$__pm_rc" -eq 0 ]; then`, "__pm_rc=$?\nfi"},
+		begin:   []string{`trap 'env -0 >"$` + envFile + `"' EXIT`, "__pm_rc=0"},
+		end:     []string{`exit "$__pm_rc"`},
+		command: func(script string) *exec.Cmd { return exec.Command(name, "-c", script) },
+	}
+}
+
+var pwshShell = shell{
+	dialect: "pwsh",
+	guard:   [2]string{"if ($__pm_rc -eq 0) {", "if (-not $?) { $__pm_rc = if ($LASTEXITCODE) { $LASTEXITCODE } else { 1 } }\n}"},
+	begin:   []string{"$__pm_rc = 0"},
+	end: []string{
+		"$env:PWD = (Get-Location).Path",
+		`[Environment]::GetEnvironmentVariables().GetEnumerator() | ForEach-Object { "$($_.Key)=$($_.Value)" + [char]0 } | Set-Content -NoNewline -Encoding utf8 -LiteralPath $env:` + envFile,
+		"exit $__pm_rc",
+	},
+	command: func(script string) *exec.Cmd {
+		exe := "pwsh"
+		if _, err := exec.LookPath(exe); err != nil {
+			exe = "powershell"
+		}
+		return exec.Command(exe, "-NoProfile", "-NonInteractive", "-Command", script)
+	},
+}
+
+// shellFor returns the shell of dialect, one of Dialects; "" is sh, pwsh
+// on Windows.
+func shellFor(dialect string) (shell, error) {
+	switch {
+	case dialect == "" && runtime.GOOS == "windows", dialect == "pwsh":
+		return pwshShell, nil
+	case dialect == "":
+		return posixShell("sh"), nil
+	case slices.Contains(Dialects, dialect):
+		return posixShell(dialect), nil
+	}
+	return shell{}, fmt.Errorf("pm run cannot run %s scripts, only %s", dialect, strings.Join(Dialects, ", "))
+}
+
+// script renders ops as one script that stops at the first op that
+// fails, like a step of the wrapper's script, and exits with its status.
+func (s shell) script(ops []plan.Op) (string, error) {
+	lines := append([]string{}, s.begin...)
+	for _, op := range ops {
+		body, err := render.Ops([]plan.Op{op}, s.dialect)
+		if err != nil {
+			return "", err
+		}
+		lines = append(lines, s.guard[0])
+		lines = append(lines, body...)
+		lines = append(lines, s.guard[1])
+	}
+	lines = append(lines, s.end...)
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+// readEnv reads the NUL-separated NAME=value pairs a script left in path;
+// nil when it exited before writing them.
+func readEnv(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil || len(data) == 0 {
+		return nil, err
+	}
+	env := map[string]string{}
+	for _, kv := range strings.Split(strings.TrimSuffix(string(data), "\x00"), "\x00") {
+		if k, v, ok := strings.Cut(kv, "="); ok && k != "" {
+			env[k] = v
+		}
+	}
+	delete(env, envFile)
+	return env, nil
+}
@@ -0,0 +1,222 @@
+package scaffold
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+
+	"pm/internal/config"
+	"pm/internal/importer"
+)
+
+//go:embed templates/*.yml
+var builtin embed.FS
+
+// Type is a detected project type; each has a template named <type>.yml.
+type Type string
+
+// detectors run in order; the first template to define a command wins.
+var detectors = []struct {
+	Type  Type
+	Match func(dir string) bool
+}{
+	{"go", has("go.mod")},
+	{"gradle", has("build.gradle", "build.gradle.kts", "settings.gradle", "settings.gradle.kts")},
+	{"maven", has("pom.xml")},
+	{"pnpm", all(has("package.json"), has("pnpm-lock.yaml"))},
+	{"yarn", all(has("package.json"), has("yarn.lock"))},
+	{"npm", all(has("package.json"), not(has("pnpm-lock.yaml", "yarn.lock")))},
+	{"poetry", func(dir string) bool {
+		return has("poetry.lock")(dir) || fileContains(dir, "pyproject.toml", "[tool.poetry]")
+	}},
+	{"python", all(has("pyproject.toml", "requirements.txt", "setup.py"), not(has("poetry.lock")), func(dir string) bool {
+		return !fileContains(dir, "pyproject.toml", "[tool.poetry]")
+	})},
+	{"rust", has("Cargo.toml")},
+	{"compose", has("compose.yaml", "compose.yml", "docker-compose.yml", "docker-compose.yaml")},
+}
+
+// Types lists every type with a built-in template.
+func Types() []Type {
+	out := make([]Type, len(detectors))
+	for i, d := range detectors {
+		out[i] = d.Type
+	}
+	return out
+}
+
+// Detect returns the project types found in dir.
+func Detect(dir string) []Type {
+	var out []Type
+	for _, d := range detectors {
+		if d.Match(dir) {
+			out = append(out, d.Type)
+		}
+	}
+	return out
+}
+
+// templateData is available to templates as {{.Name}}, {{.Gradle}}, ...
+// The values are escaped for a double-quoted YAML string, where the
+// templates put them; {{quote .Name}} is a quoted scalar of its own.
+type templateData struct {
+	Name        string
+	Root        string
+	Gradle      string
+	Maven       string
+	ComposeFile string
+}
+
+// Generate builds a meta for dir from the templates of types. Templates in
+// templatesDir (<type>.yml) override the built-in ones.
+func Generate(dir string, types []Type, templatesDir string) (*config.ProjectMeta, error) {
+	meta, err := importer.NewMeta(dir)
+	if err != nil {
+		return nil, err
+	}
+	dir, _ = filepath.Abs(dir)
+	data := templateData{
+		Name:   yamlEscape(meta.Info.Name),
+		Root:   yamlEscape(meta.Info.Root),
+		Gradle: "gradle",
+		Maven:  "mvn",
+	}
+	if has("gradlew")(dir) {
+		data.Gradle = "./gradlew"
+	}
+	if has("mvnw")(dir) {
+		data.Maven = "./mvnw"
+	}
+	var docker config.DockerDef
+	for _, t := range types {
+		if t == "compose" {
+			if docker, _, err = importer.Compose(dir); err != nil {
+				return nil, err
+			}
+			data.ComposeFile = yamlEscape(docker.ComposeFile)
+		}
+	}
+
+	var descs []string
+	for _, t := range types {
+		tm, err := load(t, templatesDir, data)
+		if err != nil {
+			return nil, err
+		}
+		if d := strings.TrimSpace(tm.Info.Description); d != "" {
+			descs = append(descs, d)
+		}
+		for name, c := range tm.Commands {
+			if _, ok := meta.Commands[name]; !ok {
+				meta.Commands[name] = c
+			}
+		}
+		for name, f := range tm.Func {
+			if meta.Func == nil {
+				meta.Func = map[string]config.FuncDef{}
+			}
+			if _, ok := meta.Func[name]; !ok {
+				meta.Func[name] = f
+			}
+		}
+		if tm.Docker.ComposeFile != "" && docker.ComposeFile == "" {
+			docker.ComposeFile = tm.Docker.ComposeFile
+		}
+		for g, svcs := range tm.Docker.Groups {
+			if docker.Groups == nil {
+				docker.Groups = map[string][]string{}
+			}
+			if _, ok := docker.Groups[g]; !ok {
+				docker.Groups[g] = svcs
+			}
+		}
+	}
+	meta.Info.Description = strings.Join(descs, " + ")
+	meta.Docker = docker
+	return meta, nil
+}
+
+// load reads the template for t, preferring templatesDir over the built-in.
+func load(t Type, templatesDir string, data templateData) (*config.ProjectMeta, error) {
+	name := string(t) + ".yml"
+	var src []byte
+	var err error
+	if templatesDir != "" {
+		src, err = os.ReadFile(filepath.Join(templatesDir, name))
+	}
+	if templatesDir == "" || errors.Is(err, os.ErrNotExist) {
+		src, err = builtin.ReadFile("templates/" + name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("template %s: %w", t, err)
+	}
+	funcs := template.FuncMap{"quote": func(s string) string { return `"` + s + `"` }}
+	tpl, err := template.New(name).Option("missingkey=error").Funcs(funcs).Parse(string(src))
+	if err != nil {
+		return nil, fmt.Errorf("template %s: %w", t, err)
+	}
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("template %s: %w", t, err)
+	}
+	var m config.ProjectMeta
+	if err := yaml.Unmarshal(buf.Bytes(), &m); err != nil {
+		return nil, fmt.Errorf("template %s: %w", t, err)
+	}
+	return &m, nil
+}
+
+// yamlEscape escapes s for the inside of a double-quoted YAML string.
+func yamlEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '"':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\x%02x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func has(names ...string) func(string) bool {
+	return func(dir string) bool {
+		for _, n := range names {
+			if _, err := os.Stat(filepath.Join(dir, n)); err == nil {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func all(fs ...func(string) bool) func(string) bool {
+	return func(dir string) bool {
+		for _, f := range fs {
+			if !f(dir) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func not(f func(string) bool) func(string) bool {
+	return func(dir string) bool { return !f(dir) }
+}
+
+func fileContains(dir, name, sub string) bool {
+	b, err := os.ReadFile(filepath.Join(dir, name))
+	return err == nil && bytes.Contains(b, []byte(sub))
+}
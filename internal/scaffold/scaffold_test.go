@@ -0,0 +1,119 @@
+package scaffold
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func touch(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	cases := []struct {
+		files map[string]string
+		want  []Type
+	}{
+		{map[string]string{"go.mod": "", "compose.yaml": ""}, []Type{"go", "compose"}},
+		{map[string]string{"package.json": "{}", "yarn.lock": ""}, []Type{"yarn"}},
+		{map[string]string{"package.json": "{}"}, []Type{"npm"}},
+		{map[string]string{"pyproject.toml": "[tool.poetry]\nname='x'"}, []Type{"poetry"}},
+		{map[string]string{"requirements.txt": ""}, []Type{"python"}},
+		{map[string]string{"build.gradle.kts": "", "gradlew": ""}, []Type{"gradle"}},
+		{map[string]string{"pom.xml": ""}, []Type{"maven"}},
+		{map[string]string{"Cargo.toml": ""}, []Type{"rust"}},
+		{map[string]string{"README.md": ""}, nil},
+	}
+	for _, c := range cases {
+		dir := t.TempDir()
+		touch(t, dir, c.files)
+		if got := Detect(dir); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Detect(%v) = %v, want %v", c.files, got, c.want)
+		}
+	}
+}
+
+func TestGenerate_BuiltinTemplates(t *testing.T) {
+	dir := t.TempDir()
+	touch(t, dir, map[string]string{
+		"build.gradle": "",
+		"gradlew":      "",
+		"compose.yml":  "services:\n  db:\n    image: postgres\n  api:\n    build: .\n",
+	})
+	meta, err := Generate(dir, Detect(dir), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := meta.Commands["build"].Cmd; got != "./gradlew build @{args}" {
+		t.Fatalf("build = %v", got)
+	}
+	if got := meta.Commands["down"].Cmd; got != "docker compose -f compose.yml down @{args}" {
+		t.Fatalf("down = %v", got)
+	}
+	if meta.Info.Description != "Gradle project + Docker Compose stack" {
+		t.Fatalf("description = %q", meta.Info.Description)
+	}
+	if g := meta.Docker.Groups; len(g["base"]) != 1 || len(g["app"]) != 1 {
+		t.Fatalf("groups = %v", g)
+	}
+}
+
+func TestGenerate_UserTemplateOverrides(t *testing.T) {
+	dir := t.TempDir()
+	touch(t, dir, map[string]string{"go.mod": ""})
+	tplDir := t.TempDir()
+	touch(t, tplDir, map[string]string{
+		"go.yml":     "commands:\n  build:\n    description: custom\n    cmd: \"task build # {{.Name}}\"\n",
+		"django.yml": "commands:\n  migrate:\n    cmd: \"python manage.py migrate\"\n",
+	})
+
+	meta, err := Generate(dir, []Type{"go", "django"}, tplDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := meta.Commands["build"].Cmd; got != "task build # "+filepath.Base(dir) {
+		t.Fatalf("build = %v", got)
+	}
+	if _, ok := meta.Commands["tidy"]; ok {
+		t.Fatal("user template should replace the built-in one, not merge with it")
+	}
+	if _, ok := meta.Commands["migrate"]; !ok {
+		t.Fatal("custom type from templates dir not applied")
+	}
+
+	if _, err := Generate(dir, []Type{"cobol"}, tplDir); err == nil {
+		t.Fatal("expected error for unknown type")
+	}
+}
+
+// TestGenerate_EscapesValues: a root whose directory name is YAML syntax
+// stays a plain value, in a quoted string and quoted on its own.
+func TestGenerate_EscapesValues(t *testing.T) {
+	for _, name := range []string{"a: b", "#x", `say "hi" \o`} {
+		dir := filepath.Join(t.TempDir(), name)
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		touch(t, dir, map[string]string{"go.mod": ""})
+		tplDir := t.TempDir()
+		touch(t, tplDir, map[string]string{
+			"go.yml": "info:\n  description: {{quote .Root}}\ncommands:\n  build:\n    cmd: \"cd {{.Root}}\"\n",
+		})
+		meta, err := Generate(dir, []Type{"go"}, tplDir)
+		if err != nil {
+			t.Fatalf("%q: %v", name, err)
+		}
+		root := meta.Info.Root
+		if !strings.HasSuffix(root, name) || meta.Info.Description != root || meta.Commands["build"].Cmd != "cd "+root {
+			t.Fatalf("%q: root %q, description %q, cmd %v", name, root, meta.Info.Description, meta.Commands["build"].Cmd)
+		}
+	}
+}
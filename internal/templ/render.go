@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"slices"
 	"strings"
 
 	"pm/internal/config"
@@ -18,15 +19,29 @@ var (
 	funcRe  = regexp.MustCompile(`_\{([A-Za-z0-9_.-]+)\((.*?)\)\}`)
 )
 
+// Options tweak substitution for callers that don't produce a script for
+// immediate execution.
+type Options struct {
+	// KeepEnv leaves ${ENV} references in place for the target shell.
+	KeepEnv bool
+}
+
 func RenderString(text string, params map[string]string, proj *config.ProjectMeta, global *config.GlobalConfig, ctx []map[string]any) (string, error) {
+	return RenderStringOpts(text, params, proj, global, ctx, Options{})
+}
+
+// RenderStringOpts is RenderString with Options.
+func RenderStringOpts(text string, params map[string]string, proj *config.ProjectMeta, global *config.GlobalConfig, ctx []map[string]any, opts Options) (string, error) {
 	// ${ENV}
-	text = envRe.ReplaceAllStringFunc(text, func(s string) string {
-		m := envRe.FindStringSubmatch(s)
-		if len(m) == 2 {
-			return getenv(m[1])
-		}
-		return ""
-	})
+	if !opts.KeepEnv {
+		text = envRe.ReplaceAllStringFunc(text, func(s string) string {
+			m := envRe.FindStringSubmatch(s)
+			if len(m) == 2 {
+				return getenv(m[1])
+			}
+			return ""
+		})
+	}
 	// @{param}
 	text = paramRe.ReplaceAllStringFunc(text, func(s string) string {
 		m := paramRe.FindStringSubmatch(s)
@@ -111,9 +126,9 @@ func RenderString(text string, params map[string]string, proj *config.ProjectMet
 		lines := funcToLines(node.Script)
 		var rendered []string
 		for _, raw := range lines {
-			r, err := RenderString(raw, nodeParams, proj, global, append(ctx, map[string]any{
+			r, err := RenderStringOpts(raw, nodeParams, proj, global, append(ctx, map[string]any{
 				"func": node,
-			}))
+			}), opts)
 			if err != nil {
 				continue
 			}
@@ -165,6 +180,23 @@ func parseKwargs(s string) map[string]string {
 	return out
 }
 
+// SplitArgs splits a comma-separated argument list such as the one in
+// _{pm(api, up, "@base")}, honouring quotes, and unquotes every argument.
+func SplitArgs(s string) []string {
+	var out []string
+	for _, tok := range splitByCommaRespectingQuotes(s) {
+		tok = strings.TrimSpace(tok)
+		if tok == "" {
+			continue
+		}
+		if unq, ok := unquote(tok); ok {
+			tok = unq
+		}
+		out = append(out, tok)
+	}
+	return out
+}
+
 func splitByCommaRespectingQuotes(s string) []string {
 	var res []string
 	var b strings.Builder
@@ -272,3 +304,14 @@ func getenv(k string) string {
 	}
 	return ""
 }
+
+// Params returns the distinct @{param} names used in text, in order.
+func Params(text string) []string {
+	var out []string
+	for _, m := range paramRe.FindAllStringSubmatch(text, -1) {
+		if !slices.Contains(out, m[1]) {
+			out = append(out, m[1])
+		}
+	}
+	return out
+}
@@ -8,11 +8,7 @@ import (
 
 func metaForTest() *config.ProjectMeta {
 	return &config.ProjectMeta{
-		Info: struct {
-			Name        string "yaml:\"name\""
-			Description string "yaml:\"description\""
-			Root        string "yaml:\"root\""
-		}{
+		Info: config.ProjectInfo{
 			Name: "subzero", Description: "desc", Root: "/proj",
 		},
 		Func: map[string]config.FuncDef{
@@ -91,3 +87,10 @@ outer:
 	}
 	return -1
 }
+
+func TestParams(t *testing.T) {
+	got := Params("mvn @{goal} -Dx=@{x} @{goal} #{info.name} @{args}")
+	if len(got) != 3 || got[0] != "goal" || got[1] != "x" || got[2] != "args" {
+		t.Fatalf("Params = %v", got)
+	}
+}
@@ -0,0 +1,80 @@
+package tui
+
+import "unicode/utf8"
+
+type keyKind int
+
+const (
+	keyRune keyKind = iota
+	keyEnter
+	keyBackspace
+	keyTab
+	keyEsc
+	keyCtrlC
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+)
+
+type key struct {
+	kind keyKind
+	r    rune
+}
+
+// arrows are the escape sequences of the cursor keys, in normal and
+// application mode.
+var arrows = map[string]keyKind{
+	"\x1b[A": keyUp, "\x1b[B": keyDown, "\x1b[C": keyRight, "\x1b[D": keyLeft,
+	"\x1bOA": keyUp, "\x1bOB": keyDown, "\x1bOC": keyRight, "\x1bOD": keyLeft,
+}
+
+// parseKeys splits what one read from a raw terminal returned into keys.
+// A lone ESC is the escape key; other escape sequences are dropped.
+func parseKeys(b []byte) []key {
+	var out []key
+	for len(b) > 0 {
+		switch c := b[0]; {
+		case c == 0x1b:
+			if len(b) == 1 {
+				out = append(out, key{kind: keyEsc})
+				return out
+			}
+			if len(b) >= 3 {
+				if k, ok := arrows[string(b[:3])]; ok {
+					out = append(out, key{kind: k})
+					b = b[3:]
+					continue
+				}
+			}
+			// an unknown sequence: skip up to its final letter
+			i := 1
+			for i < len(b) && !(b[i] >= 'A' && b[i] <= 'Z' || b[i] >= 'a' && b[i] <= 'z' || b[i] == '~') {
+				i++
+			}
+			b = b[min(i+1, len(b)):]
+			continue
+		case c == '\r' || c == '\n':
+			out = append(out, key{kind: keyEnter})
+		case c == 0x7f || c == 0x08:
+			out = append(out, key{kind: keyBackspace})
+		case c == '\t':
+			out = append(out, key{kind: keyTab})
+		case c == 0x03 || c == 0x04:
+			out = append(out, key{kind: keyCtrlC})
+		case c == 0x10: // ctrl-p
+			out = append(out, key{kind: keyUp})
+		case c == 0x0e: // ctrl-n
+			out = append(out, key{kind: keyDown})
+		case c < 0x20:
+			// other control keys do nothing
+		default:
+			r, n := utf8.DecodeRune(b)
+			out = append(out, key{kind: keyRune, r: r})
+			b = b[n:]
+			continue
+		}
+		b = b[1:]
+	}
+	return out
+}
@@ -0,0 +1,383 @@
+// Package tui is the interactive picker behind `pm` without arguments:
+// fuzzy search over recent invocations and the registered projects, then
+// their commands, most used first, a form
+// for the declared params and a preview of the rendered script.
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/shlex"
+
+	"pm/internal/builder"
+	"pm/internal/config"
+	"pm/internal/match"
+)
+
+// Picker holds what the picker shows; the callbacks keep it free of the
+// build and render pipeline.
+type Picker struct {
+	Projects []config.RegProject
+	// History puts recent invocations ahead of the projects and orders
+	// each project's commands by use.
+	History config.History
+	// Describe loads a project's commands.
+	Describe func(config.RegProject) (builder.Description, error)
+	// Preview renders the script pm-bin would print for args.
+	Preview func(args []string) string
+}
+
+// Run shows the picker on the terminal and returns the pm-bin arguments
+// chosen, e.g. [api :deploy --env=prod], or nil when it was cancelled.
+func (p *Picker) Run() ([]string, error) {
+	tty, restore, err := openTerminal()
+	if err != nil {
+		return nil, err
+	}
+	defer restore()
+	// alternate screen, hidden cursor
+	fmt.Fprint(tty, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(tty, "\x1b[?25h\x1b[?1049l")
+
+	m := newModel(p)
+	buf := make([]byte, 256)
+	for !m.done {
+		rows, cols := terminalSize(tty)
+		lines := m.view(rows, cols)
+		fmt.Fprint(tty, "\x1b[H\x1b[2J"+strings.Join(lines, "\r\n"))
+		n, err := tty.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range parseKeys(buf[:n]) {
+			if m.handle(k); m.done {
+				break
+			}
+		}
+	}
+	return m.result, nil
+}
+
+type stage int
+
+const (
+	stageProjects stage = iota
+	stageCommands
+	stageParams
+)
+
+// field is one input of the param form; the last one, without a param,
+// holds the extra arguments.
+type field struct {
+	param builder.ParamInfo
+	value string
+}
+
+type model struct {
+	p      *Picker
+	stage  stage
+	query  string
+	cursor int
+	// the project and command picked so far
+	project config.RegProject
+	desc    builder.Description
+	command builder.CommandInfo
+	fields  []field
+	err     string
+
+	done   bool
+	result []string
+}
+
+func newModel(p *Picker) *model {
+	return &model{p: p}
+}
+
+// recentLimit is how many recent invocations the first list offers.
+const recentLimit = 5
+
+// row is an entry of the first list: a recent invocation or a project.
+type row struct {
+	recent  *config.HistoryEntry
+	project config.RegProject
+}
+
+// rows returns the recent invocations and the projects matching the
+// query, best first.
+func (m *model) rows() []row {
+	var all []row
+	var texts []string
+	for _, e := range m.p.History.Recent("", recentLimit) {
+		all = append(all, row{recent: &e})
+		texts = append(texts, e.String())
+	}
+	for _, p := range m.p.Projects {
+		all = append(all, row{project: p})
+		texts = append(texts, strings.Join(append(append([]string{p.Name}, p.Aliases...), p.Tags...), " ")+" "+p.Description)
+	}
+	var out []row
+	for _, i := range match.Rank(m.query, texts) {
+		out = append(out, all[i])
+	}
+	return out
+}
+
+// commands returns the project's commands matching the query, best first.
+func (m *model) commands() []builder.CommandInfo {
+	texts := make([]string, len(m.desc.Commands))
+	for i, c := range m.desc.Commands {
+		texts[i] = strings.Join(append([]string{":" + c.Name}, c.Aliases...), " ") + " " + c.Group + " " + c.Description
+	}
+	var out []builder.CommandInfo
+	for _, i := range match.Rank(m.query, texts) {
+		out = append(out, m.desc.Commands[i])
+	}
+	return out
+}
+
+func (m *model) length() int {
+	switch m.stage {
+	case stageProjects:
+		return len(m.rows())
+	case stageCommands:
+		return len(m.commands())
+	}
+	return len(m.fields)
+}
+
+func (m *model) handle(k key) {
+	m.err = ""
+	switch k.kind {
+	case keyCtrlC:
+		m.finish(nil)
+		return
+	case keyUp:
+		m.cursor = max(m.cursor-1, 0)
+		return
+	case keyDown, keyTab:
+		m.cursor = min(m.cursor+1, max(m.length()-1, 0))
+		return
+	case keyEsc:
+		m.back()
+		return
+	case keyEnter:
+		m.enter()
+		return
+	}
+	if m.stage == stageParams {
+		m.edit(k)
+		return
+	}
+	switch k.kind {
+	case keyRune:
+		m.query += string(k.r)
+	case keyBackspace:
+		if m.query != "" {
+			r := []rune(m.query)
+			m.query = string(r[:len(r)-1])
+		}
+	}
+	m.cursor = 0
+}
+
+// edit changes the focused field of the param form: typing, backspace,
+// and left/right to step through a param's allowed values.
+func (m *model) edit(k key) {
+	f := &m.fields[m.cursor]
+	switch k.kind {
+	case keyRune:
+		f.value += string(k.r)
+	case keyBackspace:
+		if f.value != "" {
+			r := []rune(f.value)
+			f.value = string(r[:len(r)-1])
+		}
+	case keyLeft, keyRight:
+		enum := f.param.Enum
+		if len(enum) == 0 {
+			return
+		}
+		i := slices.Index(enum, f.value)
+		switch {
+		case k.kind == keyRight:
+			i = (i + 1) % len(enum)
+		case i <= 0:
+			i = len(enum) - 1
+		default:
+			i--
+		}
+		f.value = enum[i]
+	}
+}
+
+func (m *model) enter() {
+	switch m.stage {
+	case stageProjects:
+		rs := m.rows()
+		if len(rs) == 0 {
+			return
+		}
+		if r := rs[m.cursor]; r.recent != nil {
+			m.finish(r.recent.Command())
+			return
+		}
+		p := rs[m.cursor].project
+		d, err := m.p.Describe(p)
+		if err != nil {
+			m.err = err.Error()
+			return
+		}
+		d.SortByUse(m.p.History.Commands(p.Name))
+		m.project, m.desc = p, d
+		m.stage, m.query, m.cursor = stageCommands, "", 0
+	case stageCommands:
+		cs := m.commands()
+		if len(cs) == 0 {
+			return
+		}
+		m.command = cs[m.cursor]
+		if len(m.command.Params) == 0 {
+			m.finish(m.args())
+			return
+		}
+		m.fields = nil
+		for _, p := range m.command.Params {
+			m.fields = append(m.fields, field{param: p, value: p.Default})
+		}
+		m.fields = append(m.fields, field{})
+		m.stage, m.cursor = stageParams, 0
+	case stageParams:
+		for i, f := range m.fields {
+			if f.param.Required && f.value == "" {
+				m.err = "--" + f.param.Name + " is required"
+				m.cursor = i
+				return
+			}
+		}
+		m.finish(m.args())
+	}
+}
+
+// back leaves the current stage; leaving the project list cancels.
+func (m *model) back() {
+	switch m.stage {
+	case stageProjects:
+		m.finish(nil)
+	case stageCommands:
+		m.stage, m.query, m.cursor = stageProjects, "", 0
+	case stageParams:
+		m.stage, m.cursor = stageCommands, 0
+	}
+}
+
+func (m *model) finish(result []string) {
+	m.done, m.result = true, result
+}
+
+// args returns the pm-bin arguments for the highlighted command, or the
+// form's values once it is open.
+func (m *model) args() []string {
+	cmd := m.command
+	if m.stage == stageCommands {
+		cs := m.commands()
+		if len(cs) == 0 {
+			return nil
+		}
+		cmd = cs[m.cursor]
+	}
+	args := []string{m.project.Name, ":" + cmd.Name}
+	if m.stage != stageParams {
+		return args
+	}
+	for _, f := range m.fields {
+		switch {
+		case f.param.Name != "" && f.value != "":
+			args = append(args, "--"+f.param.Name+"="+f.value)
+		case f.param.Name == "":
+			extra, err := shlex.Split(f.value)
+			if err != nil {
+				extra = strings.Fields(f.value)
+			}
+			args = append(args, extra...)
+		}
+	}
+	return args
+}
+
+// view draws the picker into at most rows lines of cols columns: the
+// prompt, the list or form, the preview and a key hint.
+func (m *model) view(rows, cols int) []string {
+	var head, body []string
+	hint := "↑↓ move  enter select  esc back  ctrl-c quit"
+	switch m.stage {
+	case stageProjects:
+		head = append(head, "pm › "+m.query+"▏")
+		for _, r := range m.rows() {
+			if r.recent != nil {
+				body = append(body, "↺ "+r.recent.String())
+				continue
+			}
+			p := r.project
+			line := p.Name
+			if len(p.Tags) > 0 {
+				line += "  @" + strings.Join(p.Tags, " @")
+			}
+			body = append(body, line+"  "+p.Description)
+		}
+		hint = "type to filter  ↑↓ move  enter commands  esc quit"
+	case stageCommands:
+		head = append(head, "pm "+m.project.Name+" › :"+m.query+"▏")
+		for _, c := range m.commands() {
+			line := ":" + c.Name
+			if len(c.Aliases) > 0 {
+				line += " (" + strings.Join(c.Aliases, ", ") + ")"
+			}
+			if c.Group != "" {
+				line += " [" + c.Group + "]"
+			}
+			body = append(body, line+"  "+c.Description)
+		}
+	case stageParams:
+		head = append(head, "pm "+m.project.Name+" :"+m.command.Name+"  "+m.command.Description)
+		for _, f := range m.fields {
+			label := "args"
+			if f.param.Name != "" {
+				label = "--" + f.param.String()
+			}
+			body = append(body, label+": "+f.value)
+		}
+		hint = "tab/↑↓ field  ←→ allowed values  enter run  esc back"
+	}
+	if m.err != "" {
+		head = append(head, "! "+m.err)
+	}
+
+	// the list gets half the screen, scrolled to keep the cursor visible
+	listRows := max(min(len(body), (rows-len(head)-2)/2), 1)
+	first := max(m.cursor-listRows+1, 0)
+	var lines []string
+	lines = append(lines, head...)
+	for i := first; i < len(body) && i < first+listRows; i++ {
+		marker := "  "
+		if i == m.cursor {
+			marker = "> "
+		}
+		lines = append(lines, marker+body[i])
+	}
+	if m.stage != stageProjects && m.p.Preview != nil {
+		if args := m.args(); args != nil {
+			lines = append(lines, "── "+strings.Join(args, " "))
+			preview := strings.Split(strings.TrimRight(m.p.Preview(args), "\n"), "\n")
+			lines = append(lines, preview[:min(len(preview), max(rows-len(lines)-1, 0))]...)
+		}
+	}
+	lines = append(lines[:min(len(lines), max(rows-1, 1))], hint)
+	for i, l := range lines {
+		if r := []rune(l); cols > 0 && len(r) > cols {
+			lines[i] = string(r[:cols])
+		}
+	}
+	return lines
+}
@@ -0,0 +1,120 @@
+package tui
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"pm/internal/builder"
+	"pm/internal/config"
+)
+
+func testPicker() *Picker {
+	return &Picker{
+		Projects: []config.RegProject{
+			{Name: "web", Description: "Frontend"},
+			{Name: "api", Description: "Backend", Tags: []string{"backend"}},
+		},
+		Describe: func(p config.RegProject) (builder.Description, error) {
+			return builder.Description{Name: p.Name, Commands: []builder.CommandInfo{
+				{Name: "build", Description: "Build"},
+				{Name: "deploy", Description: "Deploy", Params: []builder.ParamInfo{
+					{Name: "env", Required: true, Enum: []string{"dev", "prod"}},
+					{Name: "replicas", Default: "1"},
+				}},
+			}}, nil
+		},
+		Preview: func(args []string) string { return "# " + strings.Join(args, " ") },
+	}
+}
+
+func feed(m *model, input string) {
+	for _, k := range parseKeys([]byte(input)) {
+		m.handle(k)
+	}
+}
+
+func TestPicker_Command(t *testing.T) {
+	m := newModel(testPicker())
+	feed(m, "ap\r")
+	if m.stage != stageCommands || m.project.Name != "api" {
+		t.Fatalf("expected api's commands, got stage %d project %q", m.stage, m.project.Name)
+	}
+	feed(m, "bu")
+	if got := m.view(24, 80); !slices.Contains(got, "── api :build") || !slices.Contains(got, "# api :build") {
+		t.Errorf("expected a preview of :build, got\n%s", strings.Join(got, "\n"))
+	}
+	feed(m, "\r")
+	if !m.done || !reflect.DeepEqual(m.result, []string{"api", ":build"}) {
+		t.Errorf("result = %v (done %v)", m.result, m.done)
+	}
+}
+
+func TestPicker_Params(t *testing.T) {
+	m := newModel(testPicker())
+	feed(m, "api\rdep\r")
+	if m.stage != stageParams || len(m.fields) != 3 {
+		t.Fatalf("expected the param form, got stage %d, %d fields", m.stage, len(m.fields))
+	}
+	// env is required
+	feed(m, "\r")
+	if m.done || m.err != "--env is required" {
+		t.Fatalf("expected a required error, got %q (done %v)", m.err, m.done)
+	}
+	// → picks the first allowed value, ← wraps around to the last
+	feed(m, "\x1b[C\x1b[C\x1b[D\x1b[D")
+	if m.fields[0].value != "prod" {
+		t.Errorf("env = %q, want prod", m.fields[0].value)
+	}
+	feed(m, "\t\x7f3\t--dry-run 'a b'\r")
+	want := []string{"api", ":deploy", "--env=prod", "--replicas=3", "--dry-run", "a b"}
+	if !m.done || !reflect.DeepEqual(m.result, want) {
+		t.Errorf("result = %q, want %q", m.result, want)
+	}
+}
+
+func TestPicker_Back(t *testing.T) {
+	m := newModel(testPicker())
+	feed(m, "backend\r")
+	feed(m, "\x1b")
+	if m.stage != stageProjects || m.done {
+		t.Fatalf("esc should go back to the projects, got stage %d", m.stage)
+	}
+	feed(m, "\x1b")
+	if !m.done || m.result != nil {
+		t.Errorf("esc on the projects should cancel, got %v", m.result)
+	}
+}
+
+func TestPicker_Recent(t *testing.T) {
+	p := testPicker()
+	p.History = config.History{
+		{Project: "api", Ref: "api", Args: []string{":build"}},
+		{Project: "api", Ref: "api", Args: []string{":deploy", "--env=dev"}},
+	}
+	m := newModel(p)
+	// the latest invocations come first and run as they were
+	if got := m.view(24, 80)[1]; got != "> ↺ api :deploy --env=dev" {
+		t.Errorf("first row = %q", got)
+	}
+	feed(m, "\x1b[B\r")
+	if want := []string{"api", ":build"}; !m.done || !reflect.DeepEqual(m.result, want) {
+		t.Errorf("result = %q, want %q", m.result, want)
+	}
+
+	// commands used before come first
+	m = newModel(p)
+	feed(m, "backend\r")
+	if cs := m.commands(); cs[0].Name != "deploy" || cs[1].Name != "build" {
+		t.Errorf("commands not ordered by use: %v", cs)
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	got := parseKeys([]byte("a\x1b[Bé\x7f\r\x1b[5~\x03"))
+	want := []key{{keyRune, 'a'}, {kind: keyDown}, {keyRune, 'é'}, {kind: keyBackspace}, {kind: keyEnter}, {kind: keyCtrlC}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseKeys = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,51 @@
+//go:build !windows
+
+package tui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// openTerminal opens the controlling terminal in raw mode; stdout stays
+// free for the script the wrapper evals. restore puts the terminal back.
+func openTerminal() (*os.File, func(), error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("the picker needs a terminal: %w", err)
+	}
+	saved, err := stty(tty, "-g")
+	if err != nil {
+		tty.Close()
+		return nil, nil, fmt.Errorf("the picker needs stty: %w", err)
+	}
+	if _, err := stty(tty, "raw", "-echo"); err != nil {
+		tty.Close()
+		return nil, nil, err
+	}
+	restore := func() {
+		_, _ = stty(tty, strings.TrimSpace(saved))
+		tty.Close()
+	}
+	return tty, restore, nil
+}
+
+// terminalSize returns the terminal's rows and columns, 24x80 when stty
+// cannot tell.
+func terminalSize(tty *os.File) (int, int) {
+	var rows, cols int
+	out, err := stty(tty, "size")
+	if _, scanErr := fmt.Sscan(out, &rows, &cols); err != nil || scanErr != nil || rows == 0 {
+		return 24, 80
+	}
+	return rows, cols
+}
+
+func stty(tty *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = tty
+	out, err := cmd.Output()
+	return string(out), err
+}
@@ -0,0 +1,16 @@
+//go:build windows
+
+package tui
+
+import (
+	"errors"
+	"os"
+)
+
+func openTerminal() (*os.File, func(), error) {
+	return nil, nil, errors.New("the picker is not supported on Windows yet; run pm PROJECT :COMMAND")
+}
+
+func terminalSize(*os.File) (int, int) {
+	return 24, 80
+}
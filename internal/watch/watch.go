@@ -0,0 +1,134 @@
+// Package watch runs a plan again whenever the files it depends on
+// change. The files are polled, which works the same on every platform
+// and file system; a run starts once they have been quiet for the
+// debounce time.
+package watch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"pm/internal/fileset"
+)
+
+// Watcher describes what to watch and how to report it.
+type Watcher struct {
+	Root string
+	// Patterns are globs relative to Root; none watches every file. Files
+	// excluded by .gitignore are never watched.
+	Patterns []string
+	// Debounce is how long the files must stay unchanged before a run.
+	Debounce time.Duration
+	// Interval is how often the files are polled.
+	Interval time.Duration
+	// Clear clears the screen before every run.
+	Clear bool
+	// Out receives the watcher's messages.
+	Out io.Writer
+}
+
+// fileState is what a change of a file is told by.
+type fileState struct {
+	mod  time.Time
+	size int64
+}
+
+type snapshot map[string]fileState
+
+// Run calls run, then again after every change, until ctx is done, and
+// returns the status of the last run. Changes are told against the files
+// as they were when the last run started, so a save during a run is not
+// lost.
+func (w *Watcher) Run(ctx context.Context, run func() int) int {
+	patterns := w.Patterns
+	if len(patterns) == 0 {
+		patterns = []string{"**"}
+	}
+	last := w.snapshot(patterns)
+	status := w.run(run, nil)
+	w.waiting(patterns)
+	tick := time.NewTicker(w.Interval)
+	defer tick.Stop()
+	var (
+		changed []string
+		quiet   time.Time
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return status
+		case <-tick.C:
+		}
+		cur := w.snapshot(patterns)
+		if diff := last.diff(cur); len(diff) > 0 {
+			changed = append(changed, diff...)
+			quiet = time.Now().Add(w.Debounce)
+			last = cur
+			continue
+		}
+		if len(changed) == 0 || time.Now().Before(quiet) {
+			continue
+		}
+		// files saved while the run was going start the next one
+		last = w.snapshot(patterns)
+		status = w.run(run, changed)
+		changed = nil
+		w.waiting(patterns)
+	}
+}
+
+func (w *Watcher) run(run func() int, changed []string) int {
+	if w.Clear {
+		fmt.Fprint(w.Out, "\x1b[H\x1b[2J\x1b[3J")
+	}
+	if len(changed) > 0 {
+		slices.Sort(changed)
+		changed = slices.Compact(changed)
+		msg := changed[0]
+		if len(changed) > 1 {
+			msg += fmt.Sprintf(" and %d more", len(changed)-1)
+		}
+		fmt.Fprintf(w.Out, "# pm: changed: %s\n", msg)
+	}
+	return run()
+}
+
+func (w *Watcher) waiting(patterns []string) {
+	fmt.Fprintf(w.Out, "# pm: watching %s (Ctrl-C to stop)\n", strings.Join(patterns, " "))
+}
+
+// snapshot returns the state of the watched files; files that cannot be
+// read are left out, so they count as changed once they can be.
+func (w *Watcher) snapshot(patterns []string) snapshot {
+	files, _ := fileset.Tracked(w.Root, patterns)
+	s := make(snapshot, len(files))
+	for _, f := range files {
+		fi, err := os.Stat(filepath.Join(w.Root, filepath.FromSlash(f)))
+		if err == nil {
+			s[f] = fileState{fi.ModTime(), fi.Size()}
+		}
+	}
+	return s
+}
+
+// diff returns the files created, changed or removed since s.
+func (s snapshot) diff(cur snapshot) []string {
+	var out []string
+	for f, st := range cur {
+		if old, ok := s[f]; !ok || old != st {
+			out = append(out, f)
+		}
+	}
+	for f := range s {
+		if _, ok := cur[f]; !ok {
+			out = append(out, f)
+		}
+	}
+	return out
+}
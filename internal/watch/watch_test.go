@@ -0,0 +1,140 @@
+package watch
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is written by the watcher and read by the test.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.String()
+}
+
+// TestWatcher: a burst of changes to watched files gives one run, files
+// outside the patterns or ignored by git none, and the status of the last
+// run is returned.
+func TestWatcher(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(".gitignore", "out/\n")
+	write("src/a.go", "a")
+
+	var out syncBuffer
+	w := &Watcher{Root: root, Patterns: []string{"src/**", "out/**"}, Debounce: 50 * time.Millisecond, Interval: 5 * time.Millisecond, Out: &out}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	runs := make(chan int, 10)
+	n := 0
+	done := make(chan int)
+	go func() {
+		done <- w.Run(ctx, func() int {
+			n++
+			runs <- n
+			return n
+		})
+	}()
+
+	wait := func(want int) {
+		t.Helper()
+		select {
+		case got := <-runs:
+			if got != want {
+				t.Fatalf("run %d, want %d", got, want)
+			}
+		case <-ctx.Done():
+			t.Fatalf("no run %d:\n%s", want, out.String())
+		}
+	}
+	wait(1)
+	write("out/ignored.go", "x")
+	write("notes.txt", "x")
+	for _, c := range []string{"b", "bb", "bbb"} {
+		write("src/a.go", c)
+		time.Sleep(10 * time.Millisecond)
+	}
+	write("src/new.go", "n")
+	wait(2)
+	select {
+	case got := <-runs:
+		t.Fatalf("extra run %d:\n%s", got, out.String())
+	case <-time.After(200 * time.Millisecond):
+	}
+	cancel()
+	if status := <-done; status != 2 {
+		t.Fatalf("status = %d, want 2", status)
+	}
+	if got := out.String(); !strings.Contains(got, "# pm: changed: src/a.go and 1 more\n") || !strings.Contains(got, "# pm: watching src/** out/**") {
+		t.Fatalf("output:\n%s", got)
+	}
+}
+
+// TestWatcher_ChangeDuringRun: a file saved while a run started by a
+// change is going starts another run once it is over.
+func TestWatcher_ChangeDuringRun(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "a.go")
+	if err := os.WriteFile(src, []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out syncBuffer
+	w := &Watcher{Root: root, Debounce: 20 * time.Millisecond, Interval: 5 * time.Millisecond, Out: &out}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	runs := make(chan int, 10)
+	n := 0
+	go w.Run(ctx, func() int {
+		n++
+		if n == 2 {
+			if err := os.WriteFile(src, []byte("saved during the run"), 0o644); err != nil {
+				t.Error(err)
+			}
+		}
+		runs <- n
+		return 0
+	})
+
+	for want := 1; want <= 3; want++ {
+		if want == 2 {
+			if err := os.WriteFile(src, []byte("b"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		select {
+		case got := <-runs:
+			if got != want {
+				t.Fatalf("run %d, want %d", got, want)
+			}
+		case <-ctx.Done():
+			t.Fatalf("no run %d:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,55 @@
+@echo off
+rem pm - cmd.exe wrapper script for project manager
+rem Builds commands via pm-bin and executes them
+
+setlocal
+set "PM_ENGINE=%~dp0..\dist\pm-engine.exe"
+if defined PM_BIN set "PM_ENGINE=%PM_BIN%"
+
+if "%~1"=="" goto direct
+if /i "%~1"=="ls" goto direct
+if /i "%~1"=="add" goto direct
+if /i "%~1"=="rm" goto direct
+if /i "%~1"=="plugins" goto direct
+if /i "%~1"=="init" goto direct
+if /i "%~1"=="scan" goto direct
+if /i "%~1"=="prune" goto direct
+if /i "%~1"=="workspaces" goto direct
+if /i "%~1"=="registry" goto direct
+if /i "%~1"=="set" goto direct
+if /i "%~1"=="show" goto direct
+if /i "%~1"=="which" goto direct
+if /i "%~1"=="export" goto direct
+if /i "%~1"=="completion" goto direct
+if /i "%~1"=="__complete" goto direct
+if /i "%~1"=="history" goto direct
+if /i "%~1"=="recent" goto direct
+if /i "%~1"=="__status" goto direct
+if /i "%~1"=="run" goto direct
+if /i "%~1"=="-h" goto direct
+if /i "%~1"=="--help" goto direct
+
+set "PM_DIALECT_ARG=%PM_DIALECT%"
+if not defined PM_DIALECT_ARG set "PM_DIALECT_ARG=cmd"
+
+set "PM_SCRIPT=%TEMP%\pm-%RANDOM%%RANDOM%.cmd"
+"%PM_ENGINE%" --dialect %PM_DIALECT_ARG% %* > "%PM_SCRIPT%"
+set "PM_RC=%ERRORLEVEL%"
+if not "%PM_RC%"=="0" (
+    del "%PM_SCRIPT%" >nul 2>&1
+    exit /b %PM_RC%
+)
+
+endlocal & set "PM_SCRIPT=%PM_SCRIPT%" & set "PM_ENGINE=%PM_ENGINE%"
+call "%PM_SCRIPT%"
+set "PM_RC=%ERRORLEVEL%"
+del "%PM_SCRIPT%" >nul 2>&1
+rem report the status to the history
+"%PM_ENGINE%" __status %PM_RC% >nul 2>&1
+set "PM_SCRIPT="
+set "PM_ENGINE="
+exit /b %PM_RC%
+
+:direct
+"%PM_ENGINE%" %*
+exit /b %ERRORLEVEL%
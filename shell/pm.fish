@@ -0,0 +1,41 @@
+# pm - fish wrapper function for project manager
+# Builds commands via pm-bin and executes them in the current shell.
+# Copy to ~/.config/fish/functions/pm.fish
+
+function pm --description 'project manager'
+    # Detect pm-bin location (PM_BIN, next to this file or in PATH)
+    set -l pm_bin $PM_BIN
+    if test -z "$pm_bin"; or not test -x "$pm_bin"
+        set pm_bin (path resolve (status dirname)/../pm-bin)
+    end
+    if not test -x "$pm_bin"
+        set pm_bin (command -v pm-bin)
+    end
+    if test -z "$pm_bin"; or not test -x "$pm_bin"
+        echo "Error: pm-bin not found. Build it first: go build -o pm-bin ./cmd/pm-bin" >&2
+        return 1
+    end
+
+    # Special commands print info, call pm-bin directly; without args pm-bin
+    # opens the picker and its script is evaled below
+    if contains -- $argv[1] ls add rm plugins init scan prune workspaces registry set show which export completion __complete history recent __status run -h --help
+        $pm_bin $argv
+        return $status
+    end
+
+    set -l dialect fish
+    if set -q PM_DIALECT
+        set dialect $PM_DIALECT
+    end
+
+    set -l script ($pm_bin --dialect $dialect $argv | string collect)
+    or return $status
+
+    # Execute generated script if not empty, then report its status to the history
+    if test -n "$script"
+        eval $script
+        set -l rc $status
+        $pm_bin __status $rc >/dev/null 2>&1
+        return $rc
+    end
+end
@@ -6,7 +6,7 @@ param(
 $enginePath = Join-Path -Path $PSScriptRoot -ChildPath '..\dist\pm-engine.exe'
 $enginePath = Resolve-Path -LiteralPath $enginePath | Select-Object -ExpandProperty Path
 
-if ($Args.Count -eq 0 -or $Args[0] -in @('ls','add','rm','-h','--help')) {
+if ($Args.Count -eq 0 -or $Args[0] -in @('ls','add','rm','plugins','init','scan','prune','workspaces','registry','set','show','which','export','completion','__complete','history','recent','__status','run','-h','--help')) {
     & $enginePath @Args
     return
 }
@@ -23,4 +23,8 @@ if ($LASTEXITCODE -ne 0) {
 if ($null -ne $script -and $script -ne "") {
     $cmd = ($script -join "`n")
     Invoke-Expression -Command $cmd
+    $ok = $?
+    # report the status to the history
+    $rc = if ($LASTEXITCODE) { $LASTEXITCODE } elseif ($ok) { 0 } else { 1 }
+    & $enginePath __status $rc | Out-Null
 }
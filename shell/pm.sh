@@ -0,0 +1,34 @@
+#!/bin/sh
+# pm - POSIX sh wrapper script for project manager
+# Builds commands via pm-bin and executes them
+
+set -eu
+
+# Detect pm-bin location (next to this script or in PATH)
+SCRIPT_DIR="$(cd "$(dirname "$0")" && pwd)"
+PM_BIN="${PM_BIN:-$SCRIPT_DIR/../pm-bin}"
+
+if [ ! -x "$PM_BIN" ]; then
+    PM_BIN="$(command -v pm-bin 2>/dev/null || true)"
+fi
+
+if [ -z "$PM_BIN" ] || [ ! -x "$PM_BIN" ]; then
+    echo "Error: pm-bin not found. Build it first: go build -o pm-bin ./cmd/pm-bin" >&2
+    exit 1
+fi
+
+# Special commands print info, call pm-bin directly; without args pm-bin
+# opens the picker and its script is evaled below
+case "${1:-}" in
+    ls|add|rm|plugins|init|scan|prune|workspaces|registry|set|show|which|export|completion|__complete|history|recent|__status|run|-h|--help) exec "$PM_BIN" "$@" ;;
+esac
+
+# Generate script with sh dialect
+dialect="${PM_DIALECT:-sh}"
+script=$("$PM_BIN" --dialect "$dialect" "$@")
+
+# Execute generated script if not empty, then report its status to the history
+if [ -n "$script" ]; then
+    trap '"$PM_BIN" __status "$?" >/dev/null 2>&1 || true' EXIT
+    eval "$script"
+fi